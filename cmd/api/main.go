@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"mafia/config"
 	"mafia/docs"
 	httpadapter "mafia/internal/adapters/http"
+	"mafia/internal/adapters/memory"
 	"mafia/internal/adapters/postgres"
+	"mafia/internal/adapters/rabbitmq"
+	redisadapter "mafia/internal/adapters/redis"
 	"mafia/internal/adapters/webrtc"
+	"mafia/internal/adapters/ws"
+	"mafia/internal/core/domain"
 	"mafia/internal/core/services"
 	"mafia/internal/ports"
 	cachepkg "mafia/pkg/cache"
@@ -14,10 +20,14 @@ import (
 	"mafia/pkg/notifications"
 	"mafia/pkg/payment"
 	"mafia/pkg/queue"
+	"mafia/pkg/scheduler"
+	"mafia/pkg/voice"
+	"mafia/pkg/websocket"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -36,37 +46,160 @@ func main() {
 	docs.SwaggerInfo.BasePath = "/"
 
 	db := postgres.New(cfg.Database.URL)
-	inMemoryCache := cachepkg.NewInMemoryCache()
-	taskQueue := queue.NewBackgroundQueue(128)
-	eventBus := events.NewSimpleBus(taskQueue.Enqueue)
+	buffer := cfg.Queue.Buffer
+	if buffer <= 0 {
+		buffer = 128
+	}
+	taskQueue := queue.NewBackgroundQueue(queue.Options{
+		Workers: cfg.Queue.Workers,
+		Buffer:  buffer,
+		OnPanic: func(recovered interface{}, stack []byte) {
+			logrus.WithFields(logrus.Fields{"panic": recovered, "stack": string(stack)}).Error("background task panicked")
+		},
+	})
+
+	node := domain.Node{ID: cfg.Cluster.NodeID, Address: cfg.Cluster.AdvertiseURL}
+	if node.ID == "" {
+		node.ID, _ = os.Hostname()
+	}
+	if node.Address == "" {
+		node.Address = "http://localhost:" + cfg.Server.Port
+	}
+	var cache ports.Cache = cachepkg.NewInMemoryCache()
+	var broker events.Broker = events.NewMemoryBroker()
+	presence := memory.NewPresenceStore()
+	if cfg.Redis.Enabled {
+		client, err := redisadapter.New(cfg.Redis.Addr)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to connect to redis")
+		}
+		defer client.Close()
+		cache = redisadapter.NewCache(client)
+		broker = redisadapter.NewBroker(client)
+		presence = redisadapter.NewPresenceStore(client)
+	}
+	// Event handlers run one at a time so websocket clients see events in the order they
+	// were published, and broadcast sequence numbers follow that order.
+	eventQueue := queue.NewBackgroundQueue(queue.Options{
+		Workers: 1,
+		Buffer:  buffer,
+		OnPanic: func(recovered interface{}, stack []byte) {
+			logrus.WithFields(logrus.Fields{"panic": recovered, "stack": string(stack)}).Error("event handler panicked")
+		},
+	})
+	dropped := func(topic string, err error) {
+		logrus.WithError(err).WithField("topic", topic).Error("dropped event")
+	}
+	eventBus, err := events.NewClusterBus(context.Background(), events.NewSimpleBus(eventQueue.Enqueue, dropped), broker, node.ID)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to connect the event bus")
+	}
+	for _, event := range domain.BroadcastEvents {
+		eventBus.Register(event)
+	}
+	var jobs ports.Queue = queue.NewMemoryJobQueue(taskQueue.Enqueue, queue.DefaultRetryPolicy)
+	if cfg.RabbitMQ.Enabled {
+		jobs, err = rabbitmq.New(cfg.RabbitMQ.URL, rabbitmq.Options{Workers: cfg.RabbitMQ.Workers, Retry: queue.DefaultRetryPolicy})
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to connect to rabbitmq")
+		}
+	}
 	notifier := notifications.NewLogSender()
 	paymentProvider := payment.NewZarinpalProvider(cfg.Payment.Zarinpal)
-	sfu := webrtc.NewSFU()
-
-	repos := ports.Repositories{
-		User:      postgres.NewUserRepository(db),
-		Room:      postgres.NewRoomRepository(db),
-		Group:     postgres.NewGroupRepository(db),
-		Wallet:    postgres.NewWalletRepository(db),
-		Challenge: postgres.NewChallengeRepository(db),
-		Role:      postgres.NewRoleRepository(db),
-		Shop:      postgres.NewShopRepository(db),
-		Rule:      postgres.NewRuleRepository(db),
-		Scenario:  postgres.NewScenarioRepository(db),
+	iceServers := make([]domain.ICEServer, 0, len(cfg.WebRTC.ICEServers))
+	for _, server := range cfg.WebRTC.ICEServers {
+		iceServers = append(iceServers, domain.ICEServer{URLs: server.URLs, Username: server.Username, Credential: server.Credential})
 	}
+	sfu, err := webrtc.NewSFU(iceServers, voice.NewRouter())
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to set up the voice server")
+	}
+
+	repos := postgres.NewRepositories(db)
 
 	infra := ports.Infrastructure{
-		Cache:         inMemoryCache,
-		Queue:         taskQueue,
+		Cache:         cache,
+		Queue:         jobs,
 		Events:        eventBus,
 		Notifications: notifier,
 		Payments:      paymentProvider,
+		Presence:      presence,
+		Leases:        postgres.NewLeaseRegistry(db),
+	}
+
+	phaseDurations := make(map[string]domain.PhaseDurations, len(cfg.Game.PhaseDurations))
+	for roomType, durations := range cfg.Game.PhaseDurations {
+		phaseDurations[roomType] = durations
+	}
+	grace := cfg.Game.DisconnectGrace
+	if grace <= 0 {
+		grace = 30 * time.Second
+	}
+	snapshots := cfg.Game.SnapshotInterval
+	if snapshots <= 0 {
+		snapshots = 10 * time.Second
 	}
+	leaseTTL := cfg.Cluster.LeaseTTL
+	if leaseTTL <= 0 {
+		leaseTTL = 15 * time.Second
+	}
+	chat := ports.ChatOptions{RateLimit: cfg.Chat.RateLimit, RateWindow: cfg.Chat.RateWindow}
+	if chat.RateLimit <= 0 {
+		chat.RateLimit = 5
+	}
+	if chat.RateWindow <= 0 {
+		chat.RateWindow = 10 * time.Second
+	}
+	options := ports.GameOptions{PhaseDurations: phaseDurations, DisconnectGrace: grace, AbsencePolicy: cfg.Game.AbsencePolicy, SnapshotInterval: snapshots, Node: node, LeaseTTL: leaseTTL, Chat: chat}
+	moderation := ports.ModerationOptions{Actions: map[string]string{domain.ContentChat: domain.ModerationMask, domain.ContentName: domain.ModerationReject, domain.ContentGroup: domain.ModerationReject}, Words: cfg.Moderation.Words, ReportThreshold: cfg.Moderation.ReportThreshold, ReportWindow: cfg.Moderation.ReportWindow}
+	for kind, action := range cfg.Moderation.Actions {
+		moderation.Actions[kind] = action
+	}
+	if moderation.ReportThreshold <= 0 {
+		moderation.ReportThreshold = 3
+	}
+	if moderation.ReportWindow <= 0 {
+		moderation.ReportWindow = time.Hour
+	}
+	services := services.NewServices(repos, infra, options, moderation, sfu)
+	if restored, err := services.Game.Rehydrate(); err != nil {
+		logrus.WithError(err).Warn("failed to restore some running games")
+	} else if restored > 0 {
+		logrus.WithField("rooms", restored).Info("restored running games")
+	}
+
+	resumeWindow := cfg.Game.ResumeWindow
+	if resumeWindow <= 0 {
+		resumeWindow = 5 * time.Minute
+	}
+	gateway := ws.NewGateway(websocket.NewHub(), services.User, services.Game, services.Chat, eventBus, sfu, resumeWindow)
 
-	services := services.NewServices(repos, infra, sfu)
+	interval := cfg.Game.SchedulerInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	phaseTimer := scheduler.NewTicker(interval, func(now time.Time) {
+		if _, err := services.Game.AdvanceExpiredPhases(now); err != nil {
+			logrus.WithError(err).Warn("failed to advance expired phases")
+		}
+		if _, err := services.Game.AdvanceSpeakingTurns(now); err != nil {
+			logrus.WithError(err).Warn("failed to advance speaking turns")
+		}
+		if _, err := services.Game.ExpireAbsences(now); err != nil {
+			logrus.WithError(err).Warn("failed to handle absent players")
+		}
+		if _, err := services.Game.SnapshotRooms(now); err != nil {
+			logrus.WithError(err).Warn("failed to snapshot game rooms")
+		}
+		if _, err := services.Outbox.Relay(); err != nil {
+			logrus.WithError(err).Warn("failed to relay outbox events")
+		}
+		gateway.ExpireSessions(now)
+	})
+	phaseTimer.Start()
 
 	r := gin.Default()
-	httpadapter.SetupRoutes(r, services, sfu)
+	httpadapter.SetupRoutes(r, services, sfu, gateway, taskQueue, node.ID, cfg.Cluster.Secret)
 
 	srv := &http.Server{Addr: ":" + cfg.Server.Port, Handler: r}
 	go srv.ListenAndServe()
@@ -76,5 +209,26 @@ func main() {
 	<-quit
 	logrus.Info("Shutting down server...")
 
-	taskQueue.Close()
+	drainTimeout := cfg.Queue.ShutdownTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Warn("failed to finish in-flight requests")
+	}
+
+	sfu.Close()
+	phaseTimer.Stop()
+	if err := services.Game.Shutdown(); err != nil {
+		logrus.WithError(err).Warn("failed to snapshot game rooms on shutdown")
+	}
+	jobs.Close()
+	if err := eventQueue.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Warn("event handlers did not finish before shutdown")
+	}
+	if err := taskQueue.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Warn("background tasks did not finish before shutdown")
+	}
 }
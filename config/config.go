@@ -3,41 +3,59 @@ package config
 import (
 	"github.com/spf13/viper"
 	"log"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Server   Server
-	Database Database
-	Redis    Redis
-	RabbitMQ RabbitMQ
-	Payment  Payment
-	WebRTC   WebRTC
-	Logging  Logging
+	Server     Server
+	Database   Database
+	Redis      Redis
+	RabbitMQ   RabbitMQ
+	Queue      Queue
+	Payment    Payment
+	WebRTC     WebRTC
+	Logging    Logging
+	Game       Game
+	Chat       Chat
+	Moderation Moderation
+	Cluster    Cluster
 }
 
 type Server struct{ Port string; Debug bool }
 type Database struct{ URL string }
-type Redis struct{ Addr string }
-type RabbitMQ struct{ URL string }
+type Redis struct{ Addr string; Enabled bool }
+type RabbitMQ struct{ URL string; Enabled bool; Workers int }
+type Queue struct{ Workers, Buffer int; ShutdownTimeout time.Duration }
 type Payment struct{ Zarinpal string }
 type WebRTC struct{ ICEServers []ICEServer }
-type ICEServer struct{ URLs []string }
+type ICEServer struct{ URLs []string; Username, Credential string }
 type Logging struct{ Level, Format string }
+type Chat struct{ RateLimit int; RateWindow time.Duration }
+type Moderation struct{ Actions map[string]string; Words []string; ReportThreshold int; ReportWindow time.Duration }
+type Cluster struct{ NodeID, AdvertiseURL, Secret string; LeaseTTL time.Duration }
+type Game struct{ SchedulerInterval time.Duration; PhaseDurations map[string]map[string]int; DisconnectGrace, ResumeWindow, SnapshotInterval time.Duration; AbsencePolicy string }
 
 func Load() *Config {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 	if err := viper.ReadInConfig(); err != nil { log.Println("Config file not found, using env") }
 	return &Config{
-		Server:   Server{Port: viper.GetString("server.port"), Debug: viper.GetBool("server.debug")},
-		Database: Database{URL: viper.GetString("database.url")},
-		Redis:    Redis{Addr: viper.GetString("redis.addr")},
-		RabbitMQ: RabbitMQ{URL: viper.GetString("rabbitmq.url")},
-		Payment:  Payment{Zarinpal: viper.GetString("payment.zarinpal")},
-		WebRTC:   WebRTC{ICEServers: parseICEServers()},
-		Logging:  Logging{Level: viper.GetString("logging.level"), Format: viper.GetString("logging.format")},
+		Server:     Server{Port: viper.GetString("server.port"), Debug: viper.GetBool("server.debug")},
+		Database:   Database{URL: viper.GetString("database.url")},
+		Redis:      Redis{Addr: viper.GetString("redis.addr"), Enabled: viper.GetBool("redis.enabled")},
+		RabbitMQ:   RabbitMQ{URL: viper.GetString("rabbitmq.url"), Enabled: viper.GetBool("rabbitmq.enabled"), Workers: viper.GetInt("rabbitmq.workers")},
+		Queue:      Queue{Workers: viper.GetInt("queue.workers"), Buffer: viper.GetInt("queue.buffer"), ShutdownTimeout: viper.GetDuration("queue.shutdown_timeout")},
+		Payment:    Payment{Zarinpal: viper.GetString("payment.zarinpal")},
+		WebRTC:     WebRTC{ICEServers: parseICEServers()},
+		Logging:    Logging{Level: viper.GetString("logging.level"), Format: viper.GetString("logging.format")},
+		Game:       Game{SchedulerInterval: viper.GetDuration("game.scheduler_interval"), PhaseDurations: parsePhaseDurations(), DisconnectGrace: viper.GetDuration("game.disconnect_grace"), ResumeWindow: viper.GetDuration("game.resume_window"), AbsencePolicy: viper.GetString("game.absence_policy"), SnapshotInterval: viper.GetDuration("game.snapshot_interval")},
+		Chat:       Chat{RateLimit: viper.GetInt("chat.rate_limit"), RateWindow: viper.GetDuration("chat.rate_window")},
+		Moderation: Moderation{Actions: viper.GetStringMapString("moderation.actions"), Words: viper.GetStringSlice("moderation.words"), ReportThreshold: viper.GetInt("moderation.report_threshold"), ReportWindow: viper.GetDuration("moderation.report_window")},
+		Cluster:    Cluster{NodeID: viper.GetString("cluster.node_id"), AdvertiseURL: viper.GetString("cluster.advertise_url"), Secret: viper.GetString("cluster.secret"), LeaseTTL: viper.GetDuration("cluster.lease_ttl")},
 	}
 }
 
@@ -46,3 +64,12 @@ func parseICEServers() []ICEServer {
 	viper.UnmarshalKey("webrtc.ice_servers", &servers)
 	return servers
 }
+
+func parsePhaseDurations() map[string]map[string]int {
+	durations := map[string]map[string]int{}
+	viper.UnmarshalKey("game.phase_durations", &durations)
+	if _, ok := durations["default"]; !ok {
+		durations["default"] = map[string]int{"night": 60, "day": 300, "defense": 60}
+	}
+	return durations
+}
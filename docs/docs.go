@@ -43,6 +43,49 @@ const docTemplate = `{
                 }
             }
         },
+        "/admin/queue/metrics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reports the worker pool size, queue depth, task counters including panics and rejections, and how long tasks waited and ran.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Background queue metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/queue.Metrics"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/admin/roles": {
             "get": {
                 "security": [
@@ -225,6 +268,67 @@ const docTemplate = `{
                 }
             }
         },
+        "/admin/rooms/{id}/state": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the full game state of a room, including every role. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Get the raw game state",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.GameState"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/admin/rules": {
             "post": {
                 "security": [
@@ -728,6 +832,49 @@ const docTemplate = `{
                 }
             }
         },
+        "/admin/ws/metrics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reports connected clients, topics and delivery counters, including messages dropped and clients disconnected for falling behind.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Websocket hub metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/websocket.Metrics"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/auth/login": {
             "post": {
                 "description": "Sends a one-time password to authenticate an existing user.",
@@ -1055,25 +1202,34 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/game/rooms/{id}/join": {
+        "/game/rooms/{id}/challenge": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Adds the authenticated user to a room by ID.",
+                "description": "Asks the current speaker for a challenge, a short turn taken right after theirs. Each living player may take one challenge a day.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Game"
                 ],
-                "summary": "Join a game room",
+                "summary": "Request a challenge",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1114,21 +1270,24 @@ const docTemplate = `{
                 }
             }
         },
-        "/game/rooms/{id}/leave": {
+        "/game/rooms/{id}/challenge/accept": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Removes the authenticated user from a room by ID.",
+                "description": "Lets the current speaker grant a requested challenge. The challenger speaks as soon as the turn ends and the other requests lapse.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Game"
                 ],
-                "summary": "Leave a game room",
+                "summary": "Accept a challenge",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1136,6 +1295,15 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Challenger",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.AcceptChallengeRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -1169,21 +1337,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/game/rooms/{id}/phase": {
-            "post": {
+        "/game/rooms/{id}/chat": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Advances the game phase for a room.",
+                "description": "Returns a page of a chat channel the caller may read, newest first, without the messages of users the caller blocked. Pass the ID of the oldest message received as before to get the page preceding it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Game"
                 ],
-                "summary": "Advance game phase",
+                "summary": "Page through chat history",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1191,13 +1359,36 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "room",
+                        "description": "Channel: room, team or dead",
+                        "name": "channel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only messages older than this ID",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.GameRoom"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.ChatMessage"
+                            }
                         }
                     },
                     "400": {
@@ -1217,25 +1408,35 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
-            }
-        },
-        "/game/rooms/{id}/start": {
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Starts the game for a given room ID.",
+                "description": "Posts to one of the room's chat channels: room (the default), team or dead. During the game the living post to the room by day unless silenced, the living mafia to team at night, and the dead and spectators to dead; narrators may post anywhere. Each sender may only post a few messages every few seconds. Messages are run through the content filter, which by default stars out blocked words.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Game"
                 ],
-                "summary": "Start a game",
+                "summary": "Post a chat message",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1243,16 +1444,22 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Chat payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.ChatRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/domain.ChatMessage"
                         }
                     },
                     "400": {
@@ -1272,18 +1479,36 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/game/rooms/{id}/vote": {
+        "/game/rooms/{id}/disarm": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Records a vote in an active game.",
+                "description": "Guesses the code of a bomb or dynamite planted on a player. A wrong guess sets it off.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1293,7 +1518,7 @@ const docTemplate = `{
                 "tags": [
                     "Game"
                 ],
-                "summary": "Submit a vote",
+                "summary": "Disarm an explosive",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1303,12 +1528,12 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Vote payload",
+                        "description": "Disarm payload",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.VoteRequest"
+                            "$ref": "#/definitions/domain.DisarmRequest"
                         }
                     }
                 ],
@@ -1318,7 +1543,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "boolean"
                             }
                         }
                     },
@@ -1339,37 +1564,55 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/shop/items": {
-            "get": {
+        "/game/rooms/{id}/join": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns all shop items available for purchase.",
+                "description": "Seats the authenticated user in a room that has not started yet. Members of the room, spectators included, cannot join again.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Shop"
+                    "Game"
+                ],
+                "summary": "Join a game room",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List shop items",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.ShopItem"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1377,8 +1620,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1389,14 +1632,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/shop/purchase": {
+        "/game/rooms/{id}/kick": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Purchases an item from the shop for the authenticated user.",
+                "description": "Removes a lower-ranked member from the room. Requires the host, a co-host or a moderator.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1404,17 +1647,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Shop"
+                    "Game"
                 ],
-                "summary": "Purchase a shop item",
+                "summary": "Kick a room member",
                 "parameters": [
                     {
-                        "description": "Purchase payload",
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Kick payload",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.PurchaseItemRequest"
+                            "$ref": "#/definitions/domain.KickRequest"
                         }
                     }
                 ],
@@ -1422,7 +1672,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.ShopItem"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -1442,31 +1695,60 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/user/dashboard": {
-            "get": {
+        "/game/rooms/{id}/leave": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves aggregated dashboard information for the authenticated user.",
+                "description": "Removes the authenticated user from a room by ID.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Game"
+                ],
+                "summary": "Leave a game room",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get user dashboard",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -1486,30 +1768,79 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/user/profile": {
-            "get": {
+        "/game/rooms/{id}/members/{userId}/role": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves the authenticated user's profile details.",
+                "description": "Promotes a member to co_host or moderator, or sets them back to player or spectator. Host only.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Game"
+                ],
+                "summary": "Change a member's room role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Member user ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.MemberRoleRequest"
+                        }
+                    }
                 ],
-                "summary": "Get user profile",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Profile"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "401": {
@@ -1521,8 +1852,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1531,40 +1871,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/game/rooms/{id}/phase": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates the authenticated user's profile information.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Advances the game phase for a room. Requires the host, a co-host or a moderator.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Game"
                 ],
-                "summary": "Update user profile",
+                "summary": "Advance game phase",
                 "parameters": [
                     {
-                        "description": "Profile update payload",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.UpdateProfileRequest"
-                        }
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Profile"
+                            "$ref": "#/definitions/domain.GameRoom"
                         }
                     },
                     "400": {
@@ -1584,47 +1921,57 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/user/purchase": {
-            "post": {
+        "/game/rooms/{id}/replay": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Initiates a purchase flow for the authenticated user.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the event timeline of a finished game together with the state rebuilt from it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Game"
                 ],
-                "summary": "Initiate wallet purchase",
+                "summary": "Replay a finished game",
                 "parameters": [
                     {
-                        "description": "Purchase payload",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.PurchaseRequest"
-                        }
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/domain.Replay"
                         }
                     },
                     "400": {
@@ -1648,26 +1995,56 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/wallet": {
-            "get": {
+        "/game/rooms/{id}/settings": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves the authenticated user's wallet balances.",
+                "description": "Replaces the settings of a waiting room. Requires the host or a co-host.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Game"
+                ],
+                "summary": "Update room settings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Room settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.RoomSettings"
+                        }
+                    }
                 ],
-                "summary": "Get wallet details",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Wallet"
+                            "$ref": "#/definitions/domain.GameRoom"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "401": {
@@ -1679,8 +2056,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1690,23 +2076,1168 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.AbilityRequest": {
-            "type": "object",
-            "properties": {
-                "ability": {
-                    "type": "string"
-                },
-                "target_id": {
-                    "type": "integer"
-                }
-            }
         },
-        "domain.CreateRoleRequest": {
-            "type": "object",
-            "required": [
+        "/game/rooms/{id}/spectate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds the authenticated user to a room as a spectator.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Game"
+                ],
+                "summary": "Spectate a game room",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/game/rooms/{id}/start": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts the game for a given room ID. Only the host or a co-host may start it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Game"
+                ],
+                "summary": "Start a game",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/game/rooms/{id}/turn": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lets the narrator hand the table to a living player for up to five minutes during the day. The other players are muted until the turn ends, is replaced or the phase changes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Game"
+                ],
+                "summary": "Give a player the floor",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Player and length of the turn",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.SpeakingTurnRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SpeakingTurn"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lets the narrator cut the current turn short. The next queued player gets the floor, or the table opens to every living player once the queue is done.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Game"
+                ],
+                "summary": "End the current speaking turn",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/game/rooms/{id}/turn/pass": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ends the caller's speaking turn early and hands the floor to the next queued player.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Game"
+                ],
+                "summary": "Pass the floor",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/game/rooms/{id}/view": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns what the authenticated member may see of the game: their own role, mafia teammates, public deaths, their investigation results and the vote history. Moderators and finished games include the full state.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Game"
+                ],
+                "summary": "Get the caller's game view",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PlayerView"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/game/rooms/{id}/vote": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records a vote in an active game, replacing the caller's earlier vote for the same ballot.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Game"
+                ],
+                "summary": "Submit a vote",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Room ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Vote payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.VoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/shop/items": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all shop items available for purchase.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shop"
+                ],
+                "summary": "List shop items",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.ShopItem"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/shop/purchase": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Purchases an item from the shop for the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shop"
+                ],
+                "summary": "Purchase a shop item",
+                "parameters": [
+                    {
+                        "description": "Purchase payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.PurchaseItemRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ShopItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/blocks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the IDs of the users whose chat the caller has hidden.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List blocked users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/blocks/{userId}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Hides the user's chat messages from the caller, both live and in history.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Block a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID to block",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Shows the user's chat messages to the caller again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Unblock a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID to unblock",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/dashboard": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves aggregated dashboard information for the authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get user dashboard",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/profile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the authenticated user's profile details.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get user profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Profile"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the authenticated user's profile information. Names are run through the content filter and, by default, rejected when they contain blocked words.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Update user profile",
+                "parameters": [
+                    {
+                        "description": "Profile update payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Profile"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/purchase": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Initiates a purchase flow for the authenticated user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Initiate wallet purchase",
+                "parameters": [
+                    {
+                        "description": "Purchase payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.PurchaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/wallet": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the authenticated user's wallet balances.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get wallet details",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Wallet"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ws": {
+            "get": {
+                "description": "Upgrades to a websocket authenticated with the bearer token from the Authorization header or the token query parameter. The first message is a session carrying a resume token. Clients send subscribe, unsubscribe, chat (room, team or dead channel, following the same rules as POST /game/rooms/{id}/chat) and resume messages and receive phase_changed, player_joined, player_left, player_connected, player_disconnected, player_absent, player_returned, vote_cast, player_died, game_finished and chat messages for the rooms they subscribed to, plus their own investigation_result messages. Every broadcast carries a seq; resume with the last seq seen replays newer messages. Chat only reaches members who may read its channel and have not blocked the sender; it carries no seq and is not replayed, so page through GET /game/rooms/{id}/chat after resuming. Voice: voice_join makes the server send a voice_offer, which the client answers with voice_answer; both sides trickle voice_candidate messages and every later voice_offer must be answered too. Forwarded audio tracks carry the stream ID user-\u003cid\u003e of their speaker. Voice is served by the replica running the room: open the socket with the room query parameter so it is relayed there, otherwise voice_join may fail with an error naming the serving node. voice_leave, leaving the room or closing the socket hangs up. The server only forwards voice the game allows: the dead are heard in the graveyard only, at night only the mafia hear each other, silenced players cannot speak for the day. During the day speaking_turn messages announce who holds the floor, until when, and the queue after them; challenge_requested messages tell the speaker who asked to interject.",
+                "tags": [
+                    "Game"
+                ],
+                "summary": "Open the game websocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT when the Authorization header cannot be set",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Room whose voice the socket will join; the socket is relayed to the replica serving it",
+                        "name": "room",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WSMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.AbilityAction": {
+            "type": "object",
+            "properties": {
+                "ability": {
+                    "type": "string"
+                },
+                "day": {
+                    "type": "integer"
+                },
+                "guess": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "resolved": {
+                    "type": "boolean"
+                },
+                "target_id": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.AbilityRequest": {
+            "type": "object",
+            "properties": {
+                "ability": {
+                    "type": "string"
+                },
+                "guess": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.AbilityUsage": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "integer"
+                },
+                "phase": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.AcceptChallengeRequest": {
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Ballot": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "round": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Bomb": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "deadline": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "planted_by": {
+                    "type": "integer"
+                },
+                "resolved": {
+                    "type": "boolean"
+                },
+                "target": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ChallengeRequest": {
+            "type": "object",
+            "properties": {
+                "speaker": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ChatMessage": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "room_id": {
+                    "type": "integer"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ChatRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.CreateRoleRequest": {
+            "type": "object",
+            "required": [
                 "name"
             ],
             "properties": {
@@ -1716,24 +3247,117 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "description": {
+                "description": {
+                    "type": "string"
+                },
+                "max_count": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "team": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.CreateRoomRequest": {
+            "type": "object",
+            "required": [
+                "scenario_id"
+            ],
+            "properties": {
+                "scenario_id": {
+                    "type": "integer"
+                },
+                "settings": {
+                    "$ref": "#/definitions/domain.RoomSettings"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DaySummary": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "day": {
+                    "type": "integer"
+                },
+                "eliminated": {
+                    "type": "integer"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "round": {
+                    "type": "integer"
+                },
+                "tally": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "domain.DeathRecord": {
+            "type": "object",
+            "properties": {
+                "by": {
+                    "type": "integer"
+                },
+                "cause": {
                     "type": "string"
                 },
-                "max_count": {
+                "day": {
                     "type": "integer"
                 },
-                "name": {
+                "phase": {
                     "type": "string"
                 },
-                "team": {
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.DisarmRequest": {
+            "type": "object",
+            "required": [
+                "guess",
+                "target_id"
+            ],
+            "properties": {
+                "guess": {
                     "type": "string"
+                },
+                "target_id": {
+                    "type": "integer"
                 }
             }
         },
-        "domain.CreateRoomRequest": {
+        "domain.GameResult": {
             "type": "object",
             "properties": {
-                "type": {
+                "day": {
+                    "type": "integer"
+                },
+                "players": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "winner": {
                     "type": "string"
                 }
             }
@@ -1741,6 +3365,12 @@ const docTemplate = `{
         "domain.GameRoom": {
             "type": "object",
             "properties": {
+                "co_host_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "code": {
                     "type": "string"
                 },
@@ -1759,17 +3389,35 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "moderator_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "phase": {
                     "type": "string"
                 },
+                "phase_ends_at": {
+                    "type": "string"
+                },
                 "players": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/domain.User"
                     }
                 },
-                "results": {
-                    "type": "string"
+                "scenario_id": {
+                    "type": "integer"
+                },
+                "settings": {
+                    "$ref": "#/definitions/domain.RoomSettings"
+                },
+                "spectator_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 },
                 "status": {
                     "type": "string"
@@ -1780,6 +3428,9 @@ const docTemplate = `{
                 "updated_at": {
                     "type": "string"
                 },
+                "version": {
+                    "type": "integer"
+                },
                 "winner": {
                     "type": "string"
                 }
@@ -1791,37 +3442,312 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
-                "deleted_at": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.GameState": {
+            "type": "object",
+            "properties": {
+                "abilities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.AbilityAction"
+                    }
+                },
+                "assignments": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/domain.PlayerAssignment"
+                    }
+                },
+                "ballot": {
+                    "$ref": "#/definitions/domain.Ballot"
+                },
+                "bombs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Bomb"
+                    }
+                },
+                "day_count": {
+                    "type": "integer"
+                },
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DaySummary"
+                    }
+                },
+                "deaths": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DeathRecord"
+                    }
+                },
+                "effects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.StatusEffect"
+                    }
+                },
+                "investigations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.InvestigationResult"
+                    }
+                },
+                "mafia_shots": {
+                    "type": "integer"
+                },
+                "nights": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.NightSummary"
+                    }
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "predictions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "result": {
+                    "$ref": "#/definitions/domain.GameResult"
+                },
+                "settings": {
+                    "$ref": "#/definitions/domain.RoomSettings"
+                },
+                "speaking": {
+                    "$ref": "#/definitions/domain.SpeakingQueue"
+                },
+                "turn": {
+                    "$ref": "#/definitions/domain.SpeakingTurn"
+                },
+                "votes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.VoteLog"
+                    }
+                }
+            }
+        },
+        "domain.InvestigationResult": {
+            "type": "object",
+            "properties": {
+                "ability": {
+                    "type": "string"
+                },
+                "day": {
+                    "type": "integer"
+                },
+                "investigator": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "integer"
+                },
+                "team": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.KickRequest": {
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.LoginRequest": {
+            "type": "object",
+            "required": [
+                "phone"
+            ],
+            "properties": {
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.MemberRoleRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.NightSummary": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "day": {
+                    "type": "integer"
+                },
+                "exposed": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "killed": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "saved": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "domain.PhaseDurations": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "integer"
+            }
+        },
+        "domain.PlayerAssignment": {
+            "type": "object",
+            "properties": {
+                "abilities": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "alive": {
+                    "type": "boolean"
+                },
+                "controller": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "team": {
+                    "type": "string"
+                },
+                "used_abilities": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/domain.AbilityUsage"
+                    }
+                }
+            }
+        },
+        "domain.PlayerView": {
+            "type": "object",
+            "properties": {
+                "alive": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "boolean"
+                    }
+                },
+                "assignment": {
+                    "$ref": "#/definitions/domain.PlayerAssignment"
+                },
+                "ballot": {
+                    "$ref": "#/definitions/domain.Ballot"
+                },
+                "controllers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "day_count": {
+                    "type": "integer"
+                },
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DaySummary"
+                    }
+                },
+                "deaths": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.PublicDeath"
+                    }
+                },
+                "investigations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.InvestigationResult"
+                    }
+                },
+                "phase": {
                     "type": "string"
                 },
-                "description": {
-                    "type": "string"
+                "queue": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SpeakingSlot"
+                    }
                 },
-                "enabled": {
-                    "type": "boolean"
+                "result": {
+                    "$ref": "#/definitions/domain.GameResult"
                 },
-                "id": {
-                    "type": "integer"
+                "state": {
+                    "$ref": "#/definitions/domain.GameState"
                 },
-                "name": {
-                    "type": "string"
+                "teammates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Teammate"
+                    }
                 },
-                "phase": {
-                    "type": "string"
+                "turn": {
+                    "$ref": "#/definitions/domain.SpeakingTurn"
                 },
-                "updated_at": {
-                    "type": "string"
-                }
-            }
-        },
-        "domain.LoginRequest": {
-            "type": "object",
-            "required": [
-                "phone"
-            ],
-            "properties": {
-                "phone": {
-                    "type": "string"
+                "votes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.VoteLog"
+                    }
                 }
             }
         },
@@ -1878,6 +3804,23 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.PublicDeath": {
+            "type": "object",
+            "properties": {
+                "cause": {
+                    "type": "string"
+                },
+                "day": {
+                    "type": "integer"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "domain.PurchaseItemRequest": {
             "type": "object",
             "required": [
@@ -1908,6 +3851,23 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.Replay": {
+            "type": "object",
+            "properties": {
+                "room_id": {
+                    "type": "integer"
+                },
+                "state": {
+                    "$ref": "#/definitions/domain.GameState"
+                },
+                "timeline": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TimelineEntry"
+                    }
+                }
+            }
+        },
         "domain.Role": {
             "type": "object",
             "properties": {
@@ -1943,6 +3903,26 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.RoomSettings": {
+            "type": "object",
+            "properties": {
+                "challenge_seconds": {
+                    "type": "integer"
+                },
+                "defense_seconds": {
+                    "type": "integer"
+                },
+                "phase_durations": {
+                    "$ref": "#/definitions/domain.PhaseDurations"
+                },
+                "tie_break": {
+                    "type": "string"
+                },
+                "turn_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
         "domain.RuleRequest": {
             "type": "object",
             "required": [
@@ -1966,6 +3946,12 @@ const docTemplate = `{
         "domain.Scenario": {
             "type": "object",
             "properties": {
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ScenarioBracket"
+                    }
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -1981,6 +3967,14 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "phase_durations": {
+                    "description": "PhaseDurations override the room type's phase lengths, in seconds, for rooms\nplaying the scenario. Room settings override them in turn.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.PhaseDurations"
+                        }
+                    ]
+                },
                 "roles": {
                     "type": "array",
                     "items": {
@@ -1998,18 +3992,44 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.ScenarioBracket": {
+            "type": "object",
+            "properties": {
+                "max_players": {
+                    "type": "integer"
+                },
+                "min_players": {
+                    "type": "integer"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "domain.ScenarioRequest": {
             "type": "object",
             "required": [
                 "name"
             ],
             "properties": {
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ScenarioBracket"
+                    }
+                },
                 "description": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
+                "phase_durations": {
+                    "$ref": "#/definitions/domain.PhaseDurations"
+                },
                 "roles": {
                     "type": "array",
                     "items": {
@@ -2060,6 +4080,123 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.SpeakingQueue": {
+            "type": "object",
+            "properties": {
+                "challenged": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "pending": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SpeakingSlot"
+                    }
+                },
+                "requests": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ChallengeRequest"
+                    }
+                }
+            }
+        },
+        "domain.SpeakingSlot": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SpeakingTurn": {
+            "type": "object",
+            "properties": {
+                "ends_at": {
+                    "type": "string"
+                },
+                "granted_by": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SpeakingTurnRequest": {
+            "type": "object",
+            "required": [
+                "seconds",
+                "user_id"
+            ],
+            "properties": {
+                "seconds": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.StatusEffect": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "day": {
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "integer"
+                },
+                "until": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Teammate": {
+            "type": "object",
+            "properties": {
+                "alive": {
+                    "type": "boolean"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.TimelineEntry": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "seq": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
         "domain.UpdateProfileRequest": {
             "type": "object",
             "properties": {
@@ -2139,6 +4276,29 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.VoteLog": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "integer"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "round": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "voter": {
+                    "type": "integer"
+                }
+            }
+        },
         "domain.VoteRequest": {
             "type": "object",
             "properties": {
@@ -2147,6 +4307,18 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.WSMessage": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "seq": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
         "domain.Wallet": {
             "type": "object",
             "properties": {
@@ -2172,6 +4344,67 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "queue.Metrics": {
+            "type": "object",
+            "properties": {
+                "avg_run_ns": {
+                    "type": "integer"
+                },
+                "avg_wait_ns": {
+                    "type": "integer"
+                },
+                "capacity": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "integer"
+                },
+                "depth": {
+                    "type": "integer"
+                },
+                "enqueued": {
+                    "type": "integer"
+                },
+                "max_run_ns": {
+                    "type": "integer"
+                },
+                "max_wait_ns": {
+                    "type": "integer"
+                },
+                "panicked": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "running": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "websocket.Metrics": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "disconnected": {
+                    "type": "integer"
+                },
+                "dropped": {
+                    "type": "integer"
+                },
+                "topics": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
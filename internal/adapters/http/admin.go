@@ -3,6 +3,7 @@ package http
 import (
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
+	"mafia/pkg/queue"
 	"net/http"
 	"strconv"
 
@@ -335,3 +336,19 @@ func DeleteScenarioHandler(srv ports.AdminService) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"message": "deleted"})
 	}
 }
+
+// QueueMetricsHandler godoc
+// @Summary Background queue metrics
+// @Description Reports the worker pool size, queue depth, task counters including panics and rejections, and how long tasks waited and ran.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} queue.Metrics
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/queue/metrics [get]
+func QueueMetricsHandler(tasks *queue.BackgroundQueue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, tasks.Metrics())
+	}
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PostChatHandler godoc
+// @Summary Post a chat message
+// @Description Posts to one of the room's chat channels: room (the default), team or dead. During the game the living post to the room by day unless silenced, the living mafia to team at night, and the dead and spectators to dead; narrators may post anywhere. Each sender may only post a few messages every few seconds. Messages are run through the content filter, which by default stars out blocked words.
+// @Tags Game
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param request body domain.ChatRequest true "Chat payload"
+// @Success 200 {object} domain.ChatMessage
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /game/rooms/{id}/chat [post]
+func PostChatHandler(srv ports.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		var req domain.ChatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		msg, err := srv.Post(uint(roomID), userID, req)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, msg)
+	}
+}
+
+// ChatHistoryHandler godoc
+// @Summary Page through chat history
+// @Description Returns a page of a chat channel the caller may read, newest first, without the messages of users the caller blocked. Pass the ID of the oldest message received as before to get the page preceding it.
+// @Tags Game
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param channel query string false "Channel: room, team or dead" default(room)
+// @Param before query int false "Only messages older than this ID"
+// @Param limit query int false "Page size, at most 100" default(50)
+// @Success 200 {array} domain.ChatMessage
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /game/rooms/{id}/chat [get]
+func ChatHistoryHandler(srv ports.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		before, _ := strconv.Atoi(c.Query("before"))
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		messages, err := srv.History(uint(roomID), userID, c.Query("channel"), uint(before), limit)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, messages)
+	}
+}
+
+// ListBlockedHandler godoc
+// @Summary List blocked users
+// @Description Returns the IDs of the users whose chat the caller has hidden.
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} int
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /user/blocks [get]
+func ListBlockedHandler(srv ports.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		blocked, err := srv.Blocked(userID)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, blocked)
+	}
+}
+
+// BlockUserHandler godoc
+// @Summary Block a user
+// @Description Hides the user's chat messages from the caller, both live and in history.
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param userId path int true "User ID to block"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /user/blocks/{userId} [post]
+func BlockUserHandler(srv ports.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		targetID, _ := strconv.Atoi(c.Param("userId"))
+		if err := srv.Block(userID, uint(targetID)); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "user blocked"})
+	}
+}
+
+// UnblockUserHandler godoc
+// @Summary Unblock a user
+// @Description Shows the user's chat messages to the caller again.
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param userId path int true "User ID to unblock"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /user/blocks/{userId} [delete]
+func UnblockUserHandler(srv ports.ChatService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		targetID, _ := strconv.Atoi(c.Param("userId"))
+		if err := srv.Unblock(userID, uint(targetID)); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "user unblocked"})
+	}
+}
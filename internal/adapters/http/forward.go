@@ -0,0 +1,99 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"mafia/internal/ports"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forwardedHeader marks a request relayed by another replica so it is never relayed again.
+// It carries the target node, the relay time and a signature made with the secret the
+// replicas share.
+const forwardedHeader = "X-Mafia-Forwarded-To"
+
+// relayMaxAge bounds how old a relay marker may be, so a captured one cannot be replayed
+// later.
+const relayMaxAge = 30 * time.Second
+
+// RoomOwnerMiddleware relays requests for a room to the replica that owns the room's live
+// state, so any replica behind the load balancer can take them. The room is taken from
+// the id path parameter, or from the room query parameter on routes without one such as
+// the websocket. When the owner cannot be reached the request fails with 502 Bad
+// Gateway. Relays are signed with secret for the target node; a relay marker that does
+// not verify, is stale or names another node, such as one sent by a client, is dropped.
+// Without a secret relays are not marked at all and only the leases keep them from
+// bouncing.
+func RoomOwnerMiddleware(srv ports.GameService, node, secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		relayed := verifyRelay(c.Request, node, secret, time.Now())
+		c.Request.Header.Del(forwardedHeader)
+		roomID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			roomID, err = strconv.Atoi(c.Query("room"))
+		}
+		if err != nil || relayed {
+			c.Next()
+			return
+		}
+		lease, err := srv.RoomOwner(uint(roomID))
+		if err != nil || lease == nil || lease.Address == "" {
+			c.Next()
+			return
+		}
+		target, err := url.Parse(lease.Address)
+		if err != nil {
+			c.Next()
+			return
+		}
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, _ error) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"room owner is unreachable"}`))
+		}
+		if secret != "" {
+			c.Request.Header.Set(forwardedHeader, relayMarker(secret, lease.Node, time.Now(), c.Request))
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
+
+// relayMarker marks the request as relayed to node at the given time.
+func relayMarker(secret, node string, at time.Time, r *http.Request) string {
+	stamp := strconv.FormatInt(at.Unix(), 10)
+	return node + ";" + stamp + ";" + relaySignature(secret, node, stamp, r)
+}
+
+// relaySignature authenticates the relay of the request to node at stamp.
+func relaySignature(secret, node, stamp string, r *http.Request) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(node + "\n" + stamp + "\n" + r.Method + "\n" + r.URL.RequestURI()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyRelay reports whether the request carries a relay marker signed with secret for
+// node within relayMaxAge of now.
+func verifyRelay(r *http.Request, node, secret string, now time.Time) bool {
+	parts := strings.Split(r.Header.Get(forwardedHeader), ";")
+	if len(parts) != 3 || secret == "" || parts[0] != node {
+		return false
+	}
+	unix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > relayMaxAge || age < -relayMaxAge {
+		return false
+	}
+	return hmac.Equal([]byte(parts[2]), []byte(relaySignature(secret, node, parts[1], r)))
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"io"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ownedRooms reports a fixed lease for every room.
+type ownedRooms struct {
+	ports.GameService
+	lease *domain.RoomLease
+}
+
+func (s ownedRooms) RoomOwner(uint) (*domain.RoomLease, error) {
+	return s.lease, nil
+}
+
+func TestRoomOwnerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "cluster-secret"
+
+	var relayedHeader string
+	owner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		relayedHeader = r.Header.Get(forwardedHeader)
+		w.Write([]byte("owner"))
+	}))
+	defer owner.Close()
+	lease := &domain.RoomLease{RoomID: 7, Node: "node-b", Address: owner.URL}
+
+	signed := func(node string, at time.Time) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set(forwardedHeader, relayMarker(secret, node, at, r))
+		}
+	}
+	tests := []struct {
+		name       string
+		lease      *domain.RoomLease
+		secret     string
+		target     string
+		prepare    func(r *http.Request)
+		want       string
+		wantMarker bool
+	}{
+		{name: "relays to the owner", lease: lease, secret: secret, target: "/rooms/7", want: "owner", wantMarker: true},
+		{name: "relays sockets by the room query", lease: lease, secret: secret, target: "/ws?room=7", want: "owner", wantMarker: true},
+		{name: "serves rooms it owns", secret: secret, target: "/rooms/7", want: "local"},
+		{name: "serves signed relays", lease: lease, secret: secret, target: "/rooms/7", prepare: signed("node-a", time.Now()), want: "local"},
+		{
+			name:   "ignores markers sent by clients",
+			lease:  lease,
+			secret: secret,
+			target: "/rooms/7",
+			prepare: func(r *http.Request) {
+				r.Header.Set(forwardedHeader, "node-a;"+strconv.FormatInt(time.Now().Unix(), 10)+";forged")
+			},
+			want:       "owner",
+			wantMarker: true,
+		},
+		{
+			name:   "ignores markers signed for another request",
+			lease:  lease,
+			secret: secret,
+			target: "/rooms/7",
+			prepare: func(r *http.Request) {
+				other := httptest.NewRequest(http.MethodGet, "/rooms/8", nil)
+				r.Header.Set(forwardedHeader, relayMarker(secret, "node-a", time.Now(), other))
+			},
+			want:       "owner",
+			wantMarker: true,
+		},
+		{
+			name:       "ignores stale markers",
+			lease:      lease,
+			secret:     secret,
+			target:     "/rooms/7",
+			prepare:    signed("node-a", time.Now().Add(-time.Minute)),
+			want:       "owner",
+			wantMarker: true,
+		},
+		{
+			name:       "ignores markers for another node",
+			lease:      lease,
+			secret:     secret,
+			target:     "/rooms/7",
+			prepare:    signed("node-c", time.Now()),
+			want:       "owner",
+			wantMarker: true,
+		},
+		{
+			name:    "trusts no marker without a secret",
+			lease:   lease,
+			target:  "/rooms/7",
+			prepare: signed("node-a", time.Now()),
+			want:    "owner",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relayedHeader = ""
+			var localHeader string
+			r := gin.New()
+			local := func(c *gin.Context) {
+				localHeader = c.GetHeader(forwardedHeader)
+				c.String(http.StatusOK, "local")
+			}
+			middleware := RoomOwnerMiddleware(ownedRooms{lease: tt.lease}, "node-a", tt.secret)
+			r.GET("/rooms/:id", middleware, local)
+			r.GET("/ws", middleware, local)
+
+			// The reverse proxy needs a real connection to watch for the client going away.
+			front := httptest.NewServer(r)
+			defer front.Close()
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.prepare != nil {
+				tt.prepare(req)
+			}
+			out, err := http.NewRequest(http.MethodGet, front.URL+tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.Header = req.Header
+			res, err := http.DefaultClient.Do(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+
+			if got := string(body); got != tt.want {
+				t.Fatalf("served by %q, want %q", got, tt.want)
+			}
+			if localHeader != "" {
+				t.Fatalf("handler saw the relay marker %q", localHeader)
+			}
+			if tt.want != "owner" {
+				return
+			}
+			if !tt.wantMarker {
+				if relayedHeader != "" {
+					t.Fatalf("relay marked without a secret: %q", relayedHeader)
+				}
+				return
+			}
+			check := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			check.Header.Set(forwardedHeader, relayedHeader)
+			if !verifyRelay(check, lease.Node, tt.secret, time.Now()) {
+				t.Fatalf("owner got an unsigned relay marker %q", relayedHeader)
+			}
+		})
+	}
+}
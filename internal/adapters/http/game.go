@@ -1,8 +1,10 @@
 package http
 
 import (
+	"errors"
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
 	"net/http"
 	"strconv"
 
@@ -29,7 +31,7 @@ func CreateRoomHandler(srv ports.GameService) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		room, err := srv.CreateRoom(userID, req.Type)
+		room, err := srv.CreateRoom(userID, req)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -61,7 +63,7 @@ func ListRoomsHandler(srv ports.GameService) gin.HandlerFunc {
 
 // JoinRoomHandler godoc
 // @Summary Join a game room
-// @Description Adds the authenticated user to a room by ID.
+// @Description Seats the authenticated user in a room that has not started yet. Members of the room, spectators included, cannot join again.
 // @Tags Game
 // @Produce json
 // @Security BearerAuth
@@ -75,7 +77,7 @@ func JoinRoomHandler(srv ports.GameService) gin.HandlerFunc {
 		roomID, _ := strconv.Atoi(c.Param("id"))
 		userID := c.GetUint("user_id")
 		if err := srv.JoinRoom(uint(roomID), userID); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "joined"})
@@ -92,22 +94,146 @@ func JoinRoomHandler(srv ports.GameService) gin.HandlerFunc {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /game/rooms/{id}/leave [post]
 func LeaveRoomHandler(srv ports.GameService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		roomID, _ := strconv.Atoi(c.Param("id"))
 		userID := c.GetUint("user_id")
 		if err := srv.LeaveRoom(uint(roomID), userID); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "left"})
 	}
 }
 
+// SpectateRoomHandler godoc
+// @Summary Spectate a game room
+// @Description Adds the authenticated user to a room as a spectator.
+// @Tags Game
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /game/rooms/{id}/spectate [post]
+func SpectateRoomHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		if err := srv.Spectate(uint(roomID), userID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "spectating"})
+	}
+}
+
+// KickPlayerHandler godoc
+// @Summary Kick a room member
+// @Description Removes a lower-ranked member from the room. Requires the host, a co-host or a moderator.
+// @Tags Game
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param request body domain.KickRequest true "Kick payload"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /game/rooms/{id}/kick [post]
+func KickPlayerHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		var req domain.KickRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := srv.KickPlayer(uint(roomID), userID, req.UserID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "player kicked"})
+	}
+}
+
+// UpdateRoomSettingsHandler godoc
+// @Summary Update room settings
+// @Description Replaces the settings of a waiting room. Requires the host or a co-host.
+// @Tags Game
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param request body domain.RoomSettings true "Room settings"
+// @Success 200 {object} domain.GameRoom
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /game/rooms/{id}/settings [put]
+func UpdateRoomSettingsHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		var req domain.RoomSettings
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		room, err := srv.UpdateSettings(uint(roomID), userID, req)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, room)
+	}
+}
+
+// SetMemberRoleHandler godoc
+// @Summary Change a member's room role
+// @Description Promotes a member to co_host or moderator, or sets them back to player or spectator. Host only.
+// @Tags Game
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param userId path int true "Member user ID"
+// @Param request body domain.MemberRoleRequest true "Role payload"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /game/rooms/{id}/members/{userId}/role [put]
+func SetMemberRoleHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		targetID, _ := strconv.Atoi(c.Param("userId"))
+		userID := c.GetUint("user_id")
+		var req domain.MemberRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := srv.SetMemberRole(uint(roomID), userID, uint(targetID), req.Role); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+	}
+}
+
 // StartGameHandler godoc
 // @Summary Start a game
-// @Description Starts the game for a given room ID.
+// @Description Starts the game for a given room ID. Only the host or a co-host may start it.
 // @Tags Game
 // @Produce json
 // @Security BearerAuth
@@ -115,12 +241,15 @@ func LeaveRoomHandler(srv ports.GameService) gin.HandlerFunc {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /game/rooms/{id}/start [post]
 func StartGameHandler(srv ports.GameService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		roomID, _ := strconv.Atoi(c.Param("id"))
-		if err := srv.StartGame(uint(roomID)); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		userID := c.GetUint("user_id")
+		if err := srv.StartGame(uint(roomID), userID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "game started"})
@@ -129,7 +258,7 @@ func StartGameHandler(srv ports.GameService) gin.HandlerFunc {
 
 // AdvancePhaseHandler godoc
 // @Summary Advance game phase
-// @Description Advances the game phase for a room.
+// @Description Advances the game phase for a room. Requires the host, a co-host or a moderator.
 // @Tags Game
 // @Produce json
 // @Security BearerAuth
@@ -137,13 +266,16 @@ func StartGameHandler(srv ports.GameService) gin.HandlerFunc {
 // @Success 200 {object} domain.GameRoom
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /game/rooms/{id}/phase [post]
 func AdvancePhaseHandler(srv ports.GameService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		roomID, _ := strconv.Atoi(c.Param("id"))
-		room, err := srv.AdvancePhase(uint(roomID))
+		userID := c.GetUint("user_id")
+		room, err := srv.AdvancePhase(uint(roomID), userID)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, room)
@@ -152,7 +284,7 @@ func AdvancePhaseHandler(srv ports.GameService) gin.HandlerFunc {
 
 // VoteHandler godoc
 // @Summary Submit a vote
-// @Description Records a vote in an active game.
+// @Description Records a vote in an active game, replacing the caller's earlier vote for the same ballot.
 // @Tags Game
 // @Accept json
 // @Produce json
@@ -162,6 +294,7 @@ func AdvancePhaseHandler(srv ports.GameService) gin.HandlerFunc {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /game/rooms/{id}/vote [post]
 func VoteHandler(srv ports.GameService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -173,7 +306,7 @@ func VoteHandler(srv ports.GameService) gin.HandlerFunc {
 			return
 		}
 		if err := srv.Vote(uint(roomID), userID, req.TargetID); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "vote recorded"})
@@ -192,6 +325,7 @@ func VoteHandler(srv ports.GameService) gin.HandlerFunc {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /game/rooms/{id}/ability [post]
 func AbilityHandler(srv ports.GameService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -202,10 +336,264 @@ func AbilityHandler(srv ports.GameService) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if err := srv.UseAbility(uint(roomID), userID, req.Ability, req.TargetID); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err := srv.UseAbility(uint(roomID), userID, req); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "ability used"})
 	}
 }
+
+// DisarmHandler godoc
+// @Summary Disarm an explosive
+// @Description Guesses the code of a bomb or dynamite planted on a player. A wrong guess sets it off.
+// @Tags Game
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param request body domain.DisarmRequest true "Disarm payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /game/rooms/{id}/disarm [post]
+func DisarmHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		var req domain.DisarmRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		disarmed, err := srv.Disarm(uint(roomID), userID, req)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"disarmed": disarmed})
+	}
+}
+
+// GiveSpeakingTurnHandler godoc
+// @Summary Give a player the floor
+// @Description Lets the narrator hand the table to a living player for up to five minutes during the day. The other players are muted until the turn ends, is replaced or the phase changes.
+// @Tags Game
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param request body domain.SpeakingTurnRequest true "Player and length of the turn"
+// @Success 200 {object} domain.SpeakingTurn
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /game/rooms/{id}/turn [post]
+func GiveSpeakingTurnHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		var req domain.SpeakingTurnRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		turn, err := srv.GiveSpeakingTurn(uint(roomID), userID, req)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, turn)
+	}
+}
+
+// EndSpeakingTurnHandler godoc
+// @Summary End the current speaking turn
+// @Description Lets the narrator cut the current turn short. The next queued player gets the floor, or the table opens to every living player once the queue is done.
+// @Tags Game
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /game/rooms/{id}/turn [delete]
+func EndSpeakingTurnHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		if err := srv.EndSpeakingTurn(uint(roomID), userID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "turn ended"})
+	}
+}
+
+// PassTurnHandler godoc
+// @Summary Pass the floor
+// @Description Ends the caller's speaking turn early and hands the floor to the next queued player.
+// @Tags Game
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /game/rooms/{id}/turn/pass [post]
+func PassTurnHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		if err := srv.PassTurn(uint(roomID), userID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "passed"})
+	}
+}
+
+// RequestChallengeHandler godoc
+// @Summary Request a challenge
+// @Description Asks the current speaker for a challenge, a short turn taken right after theirs. Each living player may take one challenge a day.
+// @Tags Game
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /game/rooms/{id}/challenge [post]
+func RequestChallengeHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		if err := srv.RequestChallenge(uint(roomID), userID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "challenge requested"})
+	}
+}
+
+// AcceptChallengeHandler godoc
+// @Summary Accept a challenge
+// @Description Lets the current speaker grant a requested challenge. The challenger speaks as soon as the turn ends and the other requests lapse.
+// @Tags Game
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Param request body domain.AcceptChallengeRequest true "Challenger"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /game/rooms/{id}/challenge/accept [post]
+func AcceptChallengeHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		var req domain.AcceptChallengeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := srv.AcceptChallenge(uint(roomID), userID, req.UserID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "challenge accepted"})
+	}
+}
+
+// GameViewHandler godoc
+// @Summary Get the caller's game view
+// @Description Returns what the authenticated member may see of the game: their own role, mafia teammates, public deaths, their investigation results and the vote history. Moderators and finished games include the full state.
+// @Tags Game
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Success 200 {object} domain.PlayerView
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /game/rooms/{id}/view [get]
+func GameViewHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		userID := c.GetUint("user_id")
+		view, err := srv.View(uint(roomID), userID)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, view)
+	}
+}
+
+// ReplayHandler godoc
+// @Summary Replay a finished game
+// @Description Returns the event timeline of a finished game together with the state rebuilt from it.
+// @Tags Game
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Success 200 {object} domain.Replay
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /game/rooms/{id}/replay [get]
+func ReplayHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		replay, err := srv.Replay(uint(roomID))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, replay)
+	}
+}
+
+// AdminGameStateHandler godoc
+// @Summary Get the raw game state
+// @Description Returns the full game state of a room, including every role. Admin only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Room ID"
+// @Success 200 {object} domain.GameState
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/rooms/{id}/state [get]
+func AdminGameStateHandler(srv ports.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID, _ := strconv.Atoi(c.Param("id"))
+		state, err := srv.GameState(uint(roomID))
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+// errorStatus maps service errors to HTTP status codes, defaulting to 400.
+func errorStatus(err error) int {
+	if errors.Is(err, apperrors.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, apperrors.ErrConflict) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, apperrors.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+	var owned *domain.RoomOwnedError
+	if errors.As(err, &owned) {
+		return http.StatusMisdirectedRequest
+	}
+	return http.StatusBadRequest
+}
@@ -1,14 +1,20 @@
 package http
 
 import (
+	"mafia/internal/adapters/ws"
 	"mafia/internal/ports"
+	"mafia/pkg/queue"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, s ports.Services, _ ports.SFU) {
+func SetupRoutes(r *gin.Engine, s ports.Services, _ ports.SFU, gateway *ws.Gateway, tasks *queue.BackgroundQueue, node, clusterSecret string) {
 	registerSwaggerRoutes(r)
 
+	// Voice is served by the SFU of the replica owning the room, so sockets opened for a
+	// room are relayed there.
+	r.GET("/ws", RoomOwnerMiddleware(s.Game, node, clusterSecret), gateway.Handle)
+
 	auth := r.Group("/auth")
 	{
 		auth.POST("/register", RegisterHandler(s.User))
@@ -23,6 +29,9 @@ func SetupRoutes(r *gin.Engine, s ports.Services, _ ports.SFU) {
 		user.GET("/dashboard", DashboardHandler(s.User))
 		user.GET("/wallet", GetWalletHandler(s.Wallet))
 		user.POST("/purchase", PurchaseHandler(s.Wallet))
+		user.GET("/blocks", ListBlockedHandler(s.Chat))
+		user.POST("/blocks/:userId", BlockUserHandler(s.Chat))
+		user.DELETE("/blocks/:userId", UnblockUserHandler(s.Chat))
 	}
 
 	shop := r.Group("/shop").Use(AuthMiddleware(s.User))
@@ -31,16 +40,30 @@ func SetupRoutes(r *gin.Engine, s ports.Services, _ ports.SFU) {
 		shop.POST("/purchase", PurchaseItemHandler(s.Shop))
 	}
 
-	game := r.Group("/game").Use(AuthMiddleware(s.User))
+	game := r.Group("/game").Use(AuthMiddleware(s.User), RoomOwnerMiddleware(s.Game, node, clusterSecret))
 	{
 		game.POST("/rooms", CreateRoomHandler(s.Game))
 		game.GET("/rooms", ListRoomsHandler(s.Game))
 		game.POST("/rooms/:id/join", JoinRoomHandler(s.Game))
 		game.POST("/rooms/:id/leave", LeaveRoomHandler(s.Game))
+		game.POST("/rooms/:id/spectate", SpectateRoomHandler(s.Game))
+		game.POST("/rooms/:id/kick", KickPlayerHandler(s.Game))
+		game.PUT("/rooms/:id/settings", UpdateRoomSettingsHandler(s.Game))
+		game.PUT("/rooms/:id/members/:userId/role", SetMemberRoleHandler(s.Game))
 		game.POST("/rooms/:id/start", StartGameHandler(s.Game))
 		game.POST("/rooms/:id/phase", AdvancePhaseHandler(s.Game))
 		game.POST("/rooms/:id/vote", VoteHandler(s.Game))
 		game.POST("/rooms/:id/ability", AbilityHandler(s.Game))
+		game.POST("/rooms/:id/disarm", DisarmHandler(s.Game))
+		game.POST("/rooms/:id/turn", GiveSpeakingTurnHandler(s.Game))
+		game.DELETE("/rooms/:id/turn", EndSpeakingTurnHandler(s.Game))
+		game.POST("/rooms/:id/turn/pass", PassTurnHandler(s.Game))
+		game.POST("/rooms/:id/challenge", RequestChallengeHandler(s.Game))
+		game.POST("/rooms/:id/challenge/accept", AcceptChallengeHandler(s.Game))
+		game.POST("/rooms/:id/chat", PostChatHandler(s.Chat))
+		game.GET("/rooms/:id/chat", ChatHistoryHandler(s.Chat))
+		game.GET("/rooms/:id/view", GameViewHandler(s.Game))
+		game.GET("/rooms/:id/replay", ReplayHandler(s.Game))
 	}
 
 	admin := r.Group("/admin")
@@ -52,5 +75,8 @@ func SetupRoutes(r *gin.Engine, s ports.Services, _ ports.SFU) {
 		admin.POST("/shop/items", CreateShopItemHandler(s.Shop))
 		admin.PUT("/shop/items/:id", UpdateShopItemHandler(s.Shop))
 		admin.DELETE("/shop/items/:id", DeleteShopItemHandler(s.Shop))
+		admin.GET("/rooms/:id/state", RoomOwnerMiddleware(s.Game, node, clusterSecret), AdminGameStateHandler(s.Game))
+		admin.GET("/ws/metrics", gateway.MetricsHandler)
+		admin.GET("/queue/metrics", QueueMetricsHandler(tasks))
 	}
 }
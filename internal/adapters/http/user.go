@@ -33,7 +33,7 @@ func GetProfileHandler(srv ports.UserService) gin.HandlerFunc {
 
 // UpdateProfileHandler godoc
 // @Summary Update user profile
-// @Description Updates the authenticated user's profile information.
+// @Description Updates the authenticated user's profile information. Names are run through the content filter and, by default, rejected when they contain blocked words.
 // @Tags User
 // @Accept json
 // @Produce json
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"sync"
+	"time"
+)
+
+type leaseRegistry struct {
+	mu     sync.Mutex
+	leases map[uint]domain.RoomLease
+}
+
+// NewLeaseRegistry keeps room leases in process memory. Services sharing one registry
+// behave like replicas sharing a database, which makes ownership testable in-process.
+func NewLeaseRegistry() ports.LeaseRegistry {
+	return &leaseRegistry{leases: make(map[uint]domain.RoomLease)}
+}
+
+func (r *leaseRegistry) Acquire(roomID uint, node domain.Node, ttl time.Duration) (*domain.RoomLease, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	current, ok := r.leases[roomID]
+	if ok && current.Node != node.ID && current.ExpiresAt.After(now) {
+		return &current, false, nil
+	}
+	lease := domain.RoomLease{RoomID: roomID, Node: node.ID, Address: node.Address, ExpiresAt: now.Add(ttl)}
+	r.leases[roomID] = lease
+	return &lease, true, nil
+}
+
+func (r *leaseRegistry) Release(roomID uint, nodeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.leases[roomID]; ok && current.Node == nodeID {
+		delete(r.leases, roomID)
+	}
+	return nil
+}
+
+func (r *leaseRegistry) Owner(roomID uint) (*domain.RoomLease, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.leases[roomID]
+	if !ok || !current.ExpiresAt.After(time.Now()) {
+		return nil, false, nil
+	}
+	return &current, true, nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"mafia/internal/core/domain"
+	"testing"
+	"time"
+)
+
+func TestLeaseHandoff(t *testing.T) {
+	a := domain.Node{ID: "node-a", Address: "http://a:8080"}
+	b := domain.Node{ID: "node-b", Address: "http://b:8080"}
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		handoff func(r *leaseRegistry)
+		taken   bool
+	}{
+		{name: "held lease is refused", ttl: time.Minute, handoff: func(*leaseRegistry) {}},
+		{name: "released lease is taken over", ttl: time.Minute, handoff: func(r *leaseRegistry) { r.Release(7, a.ID) }, taken: true},
+		{name: "only the holder can release", ttl: time.Minute, handoff: func(r *leaseRegistry) { r.Release(7, b.ID) }},
+		{name: "expired lease is taken over", ttl: 20 * time.Millisecond, handoff: func(*leaseRegistry) { time.Sleep(40 * time.Millisecond) }, taken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewLeaseRegistry().(*leaseRegistry)
+			if _, ok, err := r.Acquire(7, a, tt.ttl); err != nil || !ok {
+				t.Fatalf("first acquire: ok %v, err %v", ok, err)
+			}
+			tt.handoff(r)
+
+			lease, ok, err := r.Acquire(7, b, time.Minute)
+			if err != nil {
+				t.Fatalf("acquire: %v", err)
+			}
+			if ok != tt.taken {
+				t.Fatalf("taken over %v, want %v", ok, tt.taken)
+			}
+			want := a
+			if tt.taken {
+				want = b
+			}
+			if lease.Node != want.ID || lease.Address != want.Address {
+				t.Fatalf("lease held by %s at %s, want %s", lease.Node, lease.Address, want.ID)
+			}
+			owner, found, err := r.Owner(7)
+			if err != nil || !found || owner.Node != want.ID {
+				t.Fatalf("owner %+v, found %v, err %v; want %s", owner, found, err, want.ID)
+			}
+		})
+	}
+}
+
+func TestLeaseRenewal(t *testing.T) {
+	r := NewLeaseRegistry()
+	a := domain.Node{ID: "node-a"}
+	first, _, _ := r.Acquire(7, a, 20*time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	renewed, ok, err := r.Acquire(7, a, time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("renew: ok %v, err %v", ok, err)
+	}
+	if !renewed.ExpiresAt.After(first.ExpiresAt) {
+		t.Fatal("renewal did not extend the lease")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok, _ := r.Acquire(7, domain.Node{ID: "node-b"}, time.Minute); ok {
+		t.Fatal("renewed lease was taken over once the first term ran out")
+	}
+}
+
+func TestLeaseOwnerForgetsExpiredLeases(t *testing.T) {
+	r := NewLeaseRegistry()
+	if _, found, _ := r.Owner(7); found {
+		t.Fatal("unleased room has an owner")
+	}
+	r.Acquire(7, domain.Node{ID: "node-a"}, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if _, found, _ := r.Owner(7); found {
+		t.Fatal("expired lease still owns the room")
+	}
+}
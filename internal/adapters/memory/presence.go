@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"sort"
+	"sync"
+	"time"
+)
+
+type presenceKey struct {
+	room uint
+	user uint
+}
+
+type presenceStore struct {
+	mu      sync.RWMutex
+	entries map[presenceKey]domain.Presence
+}
+
+// NewPresenceStore keeps presence in process memory. It suits a single API node.
+func NewPresenceStore() ports.PresenceStore {
+	return &presenceStore{entries: make(map[presenceKey]domain.Presence)}
+}
+
+func (s *presenceStore) Online(roomID, userID uint, at time.Time) error {
+	s.set(roomID, userID, domain.PresenceOnline, at)
+	return nil
+}
+
+func (s *presenceStore) Offline(roomID, userID uint, at time.Time) error {
+	s.set(roomID, userID, domain.PresenceOffline, at)
+	return nil
+}
+
+func (s *presenceStore) Get(roomID, userID uint) (*domain.Presence, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[presenceKey{roomID, userID}]
+	if !ok {
+		return nil, false, nil
+	}
+	return &entry, true, nil
+}
+
+func (s *presenceStore) List(roomID uint) ([]domain.Presence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []domain.Presence
+	for key, entry := range s.entries {
+		if key.room == roomID {
+			list = append(list, entry)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].UserID < list[j].UserID })
+	return list, nil
+}
+
+func (s *presenceStore) Expired(cutoff time.Time) ([]domain.Presence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []domain.Presence
+	for _, entry := range s.entries {
+		if entry.Status == domain.PresenceOffline && !entry.Handled && entry.Since.Before(cutoff) {
+			list = append(list, entry)
+		}
+	}
+	return list, nil
+}
+
+func (s *presenceStore) MarkHandled(roomID, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := presenceKey{roomID, userID}
+	if entry, ok := s.entries[key]; ok {
+		entry.Handled = true
+		s.entries[key] = entry
+	}
+	return nil
+}
+
+func (s *presenceStore) Remove(roomID, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, presenceKey{roomID, userID})
+	return nil
+}
+
+func (s *presenceStore) set(roomID, userID uint, status string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[presenceKey{roomID, userID}] = domain.Presence{RoomID: roomID, UserID: userID, Status: status, Since: at}
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type chatRepository struct {
+	db *gorm.DB
+}
+
+func NewChatRepository(db *gorm.DB) ports.ChatRepository {
+	return &chatRepository{db}
+}
+
+func (r *chatRepository) Create(msg *domain.ChatMessage) error {
+	return r.db.Create(msg).Error
+}
+
+func (r *chatRepository) List(query domain.ChatQuery) ([]domain.ChatMessage, error) {
+	q := r.db.Where("room_id = ? AND channel IN ?", query.RoomID, query.Channels)
+	if query.Before > 0 {
+		q = q.Where("id < ?", query.Before)
+	}
+	if len(query.Hidden) > 0 {
+		q = q.Where("user_id NOT IN ?", query.Hidden)
+	}
+	var messages []domain.ChatMessage
+	err := q.Order("id DESC").Limit(query.Limit).Find(&messages).Error
+	return messages, err
+}
+
+type blockRepository struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) ports.BlockRepository {
+	return &blockRepository{db}
+}
+
+func (r *blockRepository) Block(userID, targetID uint) error {
+	block := domain.UserBlock{UserID: userID, BlockedID: targetID}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error
+}
+
+func (r *blockRepository) Unblock(userID, targetID uint) error {
+	return r.db.Where("user_id = ? AND blocked_id = ?", userID, targetID).Delete(&domain.UserBlock{}).Error
+}
+
+func (r *blockRepository) ListBlocked(userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.UserBlock{}).Where("user_id = ?", userID).Order("created_at").Pluck("blocked_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+
+	"gorm.io/gorm"
+)
+
+type gameEventRepository struct {
+	db *gorm.DB
+}
+
+func NewGameEventRepository(db *gorm.DB) ports.GameEventRepository {
+	return &gameEventRepository{db}
+}
+
+func (r *gameEventRepository) Append(roomID uint, events []domain.GameEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&domain.GameEvent{}).Where("room_id = ?", roomID).Select("COALESCE(MAX(seq), 0)").Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			events[i].RoomID = roomID
+			events[i].Seq = last + i + 1
+		}
+		return tx.Create(&events).Error
+	})
+}
+
+func (r *gameEventRepository) ListByRoom(roomID uint) ([]domain.GameEvent, error) {
+	var events []domain.GameEvent
+	err := r.db.Where("room_id = ?", roomID).Order("seq").Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"errors"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type leaseRegistry struct {
+	db *gorm.DB
+}
+
+// NewLeaseRegistry keeps room leases in the room_leases table so every replica sharing
+// the database agrees on who owns a room.
+func NewLeaseRegistry(db *gorm.DB) ports.LeaseRegistry {
+	return &leaseRegistry{db}
+}
+
+func (r *leaseRegistry) Acquire(roomID uint, node domain.Node, ttl time.Duration) (*domain.RoomLease, bool, error) {
+	now := time.Now()
+	lease := domain.RoomLease{RoomID: roomID, Node: node.ID, Address: node.Address, ExpiresAt: now.Add(ttl)}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "room_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"node", "address", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "room_leases.node = ? OR room_leases.expires_at < ?", Vars: []interface{}{node.ID, now}},
+		}},
+	}).Create(&lease).Error
+	if err != nil {
+		return nil, false, err
+	}
+	var current domain.RoomLease
+	if err := r.db.First(&current, roomID).Error; err != nil {
+		return nil, false, err
+	}
+	return &current, current.Node == node.ID, nil
+}
+
+func (r *leaseRegistry) Release(roomID uint, nodeID string) error {
+	return r.db.Where("room_id = ? AND node = ?", roomID, nodeID).Delete(&domain.RoomLease{}).Error
+}
+
+func (r *leaseRegistry) Owner(roomID uint) (*domain.RoomLease, bool, error) {
+	var lease domain.RoomLease
+	err := r.db.Where("room_id = ? AND expires_at > ?", roomID, time.Now()).First(&lease).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &lease, true, nil
+}
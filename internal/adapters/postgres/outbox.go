@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"mafia/pkg/events"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) ports.OutboxRepository {
+	return &outboxRepository{db}
+}
+
+func (r *outboxRepository) Add(list ...domain.Event) error {
+	if len(list) == 0 {
+		return nil
+	}
+	messages := make([]domain.OutboxMessage, 0, len(list))
+	for _, event := range list {
+		env, err := events.Seal(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, domain.OutboxMessage{
+			EventID:    env.ID,
+			Topic:      env.Topic,
+			Version:    env.Version,
+			Payload:    string(env.Payload),
+			OccurredAt: env.OccurredAt,
+		})
+	}
+	return r.db.Create(&messages).Error
+}
+
+func (r *outboxRepository) Pending(limit int) ([]domain.OutboxMessage, error) {
+	var messages []domain.OutboxMessage
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("retry_at IS NULL OR retry_at <= ?", time.Now()).
+		Order("id").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (r *outboxRepository) Defer(id uint, until time.Time, reason string) error {
+	return r.db.Model(&domain.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"retry_at":   until,
+		"last_error": reason,
+	}).Error
+}
+
+func (r *outboxRepository) Delete(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Delete(&domain.OutboxMessage{}, ids).Error
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+
+	"gorm.io/gorm"
+)
+
+type reportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) ports.ReportRepository {
+	return &reportRepository{db}
+}
+
+func (r *reportRepository) Create(report *domain.Report) error {
+	return r.db.Create(report).Error
+}
@@ -24,6 +24,8 @@ func NewPostgresRepository(dsn string) *gorm.DB {
 		&domain.User{}, &domain.Profile{}, &domain.Role{}, &domain.GameRoom{},
 		&domain.Group{}, &domain.Wallet{}, &domain.Transaction{}, &domain.Challenge{},
 		&domain.Report{}, &domain.Term{}, &domain.ShopItem{}, &domain.GameRule{}, &domain.Scenario{},
+		&domain.GameEvent{}, &domain.RoomLease{}, &domain.OutboxMessage{},
+		&domain.ChatMessage{}, &domain.UserBlock{},
 	)
 	return db
 }
@@ -3,8 +3,10 @@ package postgres
 import (
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type roomRepository struct {
@@ -34,8 +36,22 @@ func (r *roomRepository) ListWaiting() ([]domain.GameRoom, error) {
 	return rooms, err
 }
 
+// Update saves the room only if nobody else updated it since it was loaded, bumping its
+// version. Players are managed through AddPlayer and RemovePlayer and are left untouched.
 func (r *roomRepository) Update(room *domain.GameRoom) error {
-	return r.db.Save(room).Error
+	expected := room.Version
+	room.Version++
+	res := r.db.Model(room).Where("version = ?", expected).
+		Select("*").Omit("id", "created_at", clause.Associations).Updates(room)
+	if res.Error != nil {
+		room.Version = expected
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		room.Version = expected
+		return &domain.VersionConflictError{RoomID: room.ID, Version: expected}
+	}
+	return nil
 }
 
 func (r *roomRepository) AddPlayer(roomID, userID uint) error {
@@ -45,3 +61,15 @@ func (r *roomRepository) AddPlayer(roomID, userID uint) error {
 func (r *roomRepository) RemovePlayer(roomID, userID uint) error {
 	return r.db.Exec("DELETE FROM room_players WHERE game_room_id = ? AND user_id = ?", roomID, userID).Error
 }
+
+func (r *roomRepository) ListExpired(now time.Time) ([]domain.GameRoom, error) {
+	var rooms []domain.GameRoom
+	err := r.db.Where("status = ? AND phase_ends_at <= ?", "playing", now).Find(&rooms).Error
+	return rooms, err
+}
+
+func (r *roomRepository) ListActive() ([]domain.GameRoom, error) {
+	var rooms []domain.GameRoom
+	err := r.db.Where("status = ?", "playing").Find(&rooms).Error
+	return rooms, err
+}
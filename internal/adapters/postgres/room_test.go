@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"mafia/internal/core/domain"
+	apperrors "mafia/pkg/errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// rowsDriver stands in for the database: every statement reports touching rows rows and
+// the last one is kept for inspection.
+type rowsDriver struct {
+	rows  int64
+	query string
+	args  []driver.NamedValue
+}
+
+func (d *rowsDriver) Connect(context.Context) (driver.Conn, error) { return rowsConn{d}, nil }
+func (d *rowsDriver) Driver() driver.Driver                        { return nil }
+
+type rowsConn struct{ d *rowsDriver }
+
+func (c rowsConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c rowsConn) Close() error                        { return nil }
+func (c rowsConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c rowsConn) Commit() error                       { return nil }
+func (c rowsConn) Rollback() error                     { return nil }
+
+func (c rowsConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.query, c.d.args = query, args
+	return driver.RowsAffected(c.d.rows), nil
+}
+
+func openRows(t *testing.T, d *rowsDriver) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(d)}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRoomUpdateChecksVersion(t *testing.T) {
+	d := &rowsDriver{rows: 1}
+	repo := NewRoomRepository(openRows(t, d))
+
+	room := &domain.GameRoom{ID: 7, Version: 3, Status: "playing"}
+	if err := repo.Update(room); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if room.Version != 4 {
+		t.Fatalf("version %d after an update, want 4", room.Version)
+	}
+	if !strings.Contains(d.query, "version = $") || !hasArg(d.args, int64(3)) {
+		t.Fatalf("update %q with %v does not check the loaded version", d.query, d.args)
+	}
+
+	d.rows = 0
+	err := repo.Update(room)
+	var conflict *domain.VersionConflictError
+	if !errors.As(err, &conflict) || conflict.RoomID != 7 || conflict.Version != 4 || !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("got %v, want a version conflict on version 4", err)
+	}
+	if room.Version != 4 {
+		t.Fatalf("version %d after a conflict, want it left at 4", room.Version)
+	}
+}
+
+func hasArg(args []driver.NamedValue, want int64) bool {
+	for _, arg := range args {
+		if n, ok := arg.Value.(int64); ok && n == want {
+			return true
+		}
+	}
+	return false
+}
@@ -19,6 +19,15 @@ func (r *scenarioRepository) Create(s *domain.Scenario) error {
 	return r.db.Create(s).Error
 }
 
+func (r *scenarioRepository) FindByID(id uint) (*domain.Scenario, error) {
+	var scenario domain.Scenario
+	err := r.db.First(&scenario, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
 func (r *scenarioRepository) List() ([]domain.Scenario, error) {
 	var scenarios []domain.Scenario
 	err := r.db.Find(&scenarios).Error
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"mafia/internal/ports"
+
+	"gorm.io/gorm"
+)
+
+// NewRepositories builds every repository on the connection. Repositories built on a
+// transaction share it.
+func NewRepositories(db *gorm.DB) ports.Repositories {
+	return ports.Repositories{
+		User:      NewUserRepository(db),
+		Room:      NewRoomRepository(db),
+		Group:     NewGroupRepository(db),
+		Wallet:    NewWalletRepository(db),
+		Challenge: NewChallengeRepository(db),
+		Role:      NewRoleRepository(db),
+		Shop:      NewShopRepository(db),
+		Rule:      NewRuleRepository(db),
+		Scenario:  NewScenarioRepository(db),
+		GameEvent: NewGameEventRepository(db),
+		Outbox:    NewOutboxRepository(db),
+		Chat:      NewChatRepository(db),
+		Block:     NewBlockRepository(db),
+		Report:    NewReportRepository(db),
+		Tx:        NewTransactor(db),
+	}
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor runs changes in database transactions. Nested calls use savepoints.
+func NewTransactor(db *gorm.DB) ports.Transactor {
+	return &transactor{db}
+}
+
+func (t *transactor) Transact(fn func(tx ports.Repositories) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepositories(tx))
+	})
+}
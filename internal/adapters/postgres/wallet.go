@@ -1,8 +1,10 @@
 package postgres
 
 import (
+	"errors"
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
 
 	"gorm.io/gorm"
 )
@@ -22,6 +24,9 @@ func (r *walletRepository) Create(w *domain.Wallet) error {
 func (r *walletRepository) FindByUserID(id uint) (*domain.Wallet, error) {
 	var w domain.Wallet
 	err := r.db.Where("user_id = ?", id).First(&w).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,327 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mafia/pkg/queue"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// exchange routes jobs to their queue by job name.
+const exchange = "mafia.jobs"
+
+// Message headers carried by every job.
+const (
+	headerAttempt   = "x-attempt"
+	headerLastError = "x-last-error"
+)
+
+// Options tunes how jobs are consumed and retried.
+type Options struct {
+	// Workers is how many jobs of each name run concurrently on this replica.
+	Workers int
+	Retry   queue.RetryPolicy
+}
+
+// Queue is a durable job queue on RabbitMQ. Each job name gets a durable queue, one
+// delay queue per retry whose expired messages flow back into it, and a dead-letter
+// queue for jobs that ran out of attempts. Jobs are acknowledged only after they ran or
+// were moved on, so a job in flight when a replica dies is delivered again.
+type Queue struct {
+	url     string
+	options Options
+	done    chan struct{}
+	workers sync.WaitGroup
+
+	mu        sync.Mutex
+	conn      *amqp.Connection
+	publisher *amqp.Channel
+	consumers []consumer
+	declared  map[string]bool
+	handlers  map[string]queue.Handler
+	closed    bool
+}
+
+type consumer struct {
+	ch  *amqp.Channel
+	tag string
+}
+
+// New connects to the broker and keeps reconnecting whenever the connection drops.
+func New(url string, options Options) (*Queue, error) {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.Retry.MaxAttempts <= 0 {
+		options.Retry = queue.DefaultRetryPolicy
+	}
+	q := &Queue{url: url, options: options, done: make(chan struct{}), handlers: make(map[string]queue.Handler)}
+	closed, err := q.connect()
+	if err != nil {
+		return nil, err
+	}
+	go q.supervise(closed)
+	return q, nil
+}
+
+// Register consumes jobs of the given name with the handler.
+func (q *Queue) Register(name string, handler func(ctx context.Context, payload []byte) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[name] = handler
+	if q.conn == nil || q.closed {
+		return
+	}
+	if err := q.consume(name, handler); err != nil {
+		// The supervisor sets every consumer up again on the next connection.
+		q.conn.Close()
+	}
+}
+
+// Enqueue publishes the job and waits for the broker to confirm it was stored.
+func (q *Queue) Enqueue(ctx context.Context, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return q.publish(ctx, name, name, amqp.Publishing{
+		Type:    name,
+		Body:    data,
+		Headers: amqp.Table{headerAttempt: int32(1)},
+	}, exchange)
+}
+
+// Close stops consuming, waits for running jobs and disconnects.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.done)
+	for _, c := range q.consumers {
+		_ = c.ch.Cancel(c.tag, false)
+	}
+	q.mu.Unlock()
+
+	// Cancelling lets the workers finish and acknowledge the jobs they already hold.
+	q.workers.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.conn != nil {
+		q.conn.Close()
+	}
+}
+
+// connect dials the broker and sets up every registered consumer.
+func (q *Queue) connect() (chan *amqp.Error, error) {
+	conn, err := amqp.Dial(q.url)
+	if err != nil {
+		return nil, err
+	}
+	publisher, err := conn.Channel()
+	if err == nil {
+		err = publisher.Confirm(false)
+	}
+	if err == nil {
+		err = publisher.ExchangeDeclare(exchange, amqp.ExchangeDirect, true, false, false, false, nil)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		conn.Close()
+		return nil, queue.ErrStopped
+	}
+	q.conn, q.publisher, q.consumers, q.declared = conn, publisher, nil, make(map[string]bool)
+	for name, handler := range q.handlers {
+		if err := q.consume(name, handler); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn.NotifyClose(make(chan *amqp.Error, 1)), nil
+}
+
+// supervise reconnects with growing delays after the connection is lost.
+func (q *Queue) supervise(closed chan *amqp.Error) {
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-closed:
+		}
+		for delay := time.Second; ; delay = min(2*delay, 30*time.Second) {
+			select {
+			case <-q.done:
+				return
+			case <-time.After(delay):
+			}
+			var err error
+			if closed, err = q.connect(); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// declare sets up the queues of a job name. Callers must hold q.mu.
+func (q *Queue) declare(name string) error {
+	if q.declared[name] {
+		return nil
+	}
+	ch := q.publisher
+	if _, err := ch.QueueDeclare(workQueue(name), true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(workQueue(name), name, exchange, false, nil); err != nil {
+		return err
+	}
+	for attempt := 2; attempt <= q.options.Retry.MaxAttempts; attempt++ {
+		delay := q.options.Retry.Backoff(attempt)
+		_, err := ch.QueueDeclare(retryQueue(name, delay), true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    exchange,
+			"x-dead-letter-routing-key": name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := ch.QueueDeclare(deadQueue(name), true, false, false, false, nil); err != nil {
+		return err
+	}
+	q.declared[name] = true
+	return nil
+}
+
+// consume starts the workers of a job name on their own channel. Callers must hold q.mu.
+func (q *Queue) consume(name string, handler queue.Handler) error {
+	if err := q.declare(name); err != nil {
+		return err
+	}
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := ch.Qos(q.options.Workers, 0, false); err != nil {
+		ch.Close()
+		return err
+	}
+	tag := fmt.Sprintf("%s-%d", name, len(q.consumers))
+	deliveries, err := ch.Consume(workQueue(name), tag, false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return err
+	}
+	q.consumers = append(q.consumers, consumer{ch: ch, tag: tag})
+	for i := 0; i < q.options.Workers; i++ {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			for delivery := range deliveries {
+				q.handle(name, handler, delivery)
+			}
+		}()
+	}
+	return nil
+}
+
+// handle runs one job. A failed job is republished to the delay queue of its next
+// attempt, or to the dead-letter queue once it has no attempts left.
+func (q *Queue) handle(name string, handler queue.Handler, delivery amqp.Delivery) {
+	ctx := context.Background()
+	err := queue.Run(ctx, handler, delivery.Body)
+	if err == nil {
+		_ = delivery.Ack(false)
+		return
+	}
+	attempt := attemptOf(delivery) + 1
+	target := deadQueue(name)
+	if attempt <= q.options.Retry.MaxAttempts {
+		target = retryQueue(name, q.options.Retry.Backoff(attempt))
+	}
+	err = q.publish(ctx, name, target, amqp.Publishing{
+		Type:    name,
+		Body:    delivery.Body,
+		Headers: amqp.Table{headerAttempt: int32(attempt), headerLastError: err.Error()},
+	}, "")
+	if err != nil {
+		_ = delivery.Nack(false, true)
+		return
+	}
+	_ = delivery.Ack(false)
+}
+
+// publish sends a persistent message after making sure the job's queues exist.
+func (q *Queue) publish(ctx context.Context, name, key string, msg amqp.Publishing, via string) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return queue.ErrStopped
+	}
+	if q.publisher == nil || q.publisher.IsClosed() {
+		if q.conn != nil {
+			// A channel error leaves the connection up; dropping it makes the supervisor
+			// start over with fresh channels.
+			q.conn.Close()
+		}
+		q.mu.Unlock()
+		return errors.New("job queue is not connected")
+	}
+	if err := q.declare(name); err != nil {
+		q.mu.Unlock()
+		return err
+	}
+	publisher := q.publisher
+	q.mu.Unlock()
+
+	msg.ContentType = "application/json"
+	msg.DeliveryMode = amqp.Persistent
+	msg.Timestamp = time.Now()
+	confirm, err := publisher.PublishWithDeferredConfirmWithContext(ctx, via, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return fmt.Errorf("broker rejected job %q", name)
+	}
+	return nil
+}
+
+func attemptOf(delivery amqp.Delivery) int {
+	switch attempt := delivery.Headers[headerAttempt].(type) {
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	}
+	return 1
+}
+
+func workQueue(name string) string {
+	return exchange + "." + name
+}
+
+func retryQueue(name string, delay time.Duration) string {
+	return fmt.Sprintf("%s.%s.retry.%s", exchange, name, delay)
+}
+
+func deadQueue(name string) string {
+	return exchange + "." + name + ".dead"
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Broker relays cluster events between replicas over Redis pub/sub. Delivery is at most
+// once: replicas that are disconnected while an event is published miss it.
+type Broker struct {
+	client *goredis.Client
+}
+
+// NewBroker creates a pub/sub broker for events.ClusterBus.
+func NewBroker(client *goredis.Client) *Broker {
+	return &Broker{client: client}
+}
+
+// Publish sends the message to every replica subscribed to the channel.
+func (b *Broker) Publish(ctx context.Context, channel string, data []byte) error {
+	return b.client.Publish(ctx, keyPrefix+channel, data).Err()
+}
+
+// Subscribe delivers the channel's messages to the handler until ctx is cancelled. It
+// returns once the subscription is confirmed, so no later message is missed.
+func (b *Broker) Subscribe(ctx context.Context, channel string, handler func(data []byte)) error {
+	sub := b.client.Subscribe(ctx, keyPrefix+channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return err
+	}
+	messages := sub.Channel()
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				handler([]byte(msg.Payload))
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBrokerDeliversToEverySubscriber(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two brokers on one server stand in for two replicas.
+	first, second := NewBroker(client), NewBroker(client)
+	received := make(chan string, 4)
+	for _, b := range []*Broker{first, second} {
+		if err := b.Subscribe(ctx, "events", func(data []byte) { received <- string(data) }); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+	}
+
+	if err := first.Publish(ctx, "events", []byte("phase_changed")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-received:
+			if got != "phase_changed" {
+				t.Fatalf("received %q", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of 2 subscribers got the message", i)
+		}
+	}
+}
+
+func TestBrokerKeepsChannelsApart(t *testing.T) {
+	server, client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewBroker(client)
+	received := make(chan string, 2)
+	if err := b.Subscribe(ctx, "events", func(data []byte) { received <- string(data) }); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if n := server.PubSubNumSub(keyPrefix + "events"); n[keyPrefix+"events"] != 1 {
+		t.Fatalf("subscription not under the key prefix: %v", n)
+	}
+
+	if err := b.Publish(ctx, "other", []byte("ignored")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := b.Publish(ctx, "events", []byte("wanted")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	select {
+	case got := <-received:
+		if got != "wanted" {
+			t.Fatalf("received %q from another channel", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not delivered")
+	}
+}
+
+func TestBrokerStopsWhenCancelled(t *testing.T) {
+	server, client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := NewBroker(client)
+	if err := b.Subscribe(ctx, "events", func([]byte) {}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.PubSubNumSub(keyPrefix + "events")[keyPrefix+"events"] != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription outlived its context")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mafia/internal/ports"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type cache struct {
+	client *goredis.Client
+}
+
+// NewCache stores cache entries in Redis as JSON, so every replica sees the same values.
+func NewCache(client *goredis.Client) ports.Cache {
+	return &cache{client: client}
+}
+
+func (c *cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, keyPrefix+"cache:"+key, data, ttl).Err()
+}
+
+func (c *cache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	data, err := c.client.Get(ctx, keyPrefix+"cache:"+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *cache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, keyPrefix+"cache:"+key).Err()
+}
@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type cachedProfile struct {
+	Name  string `json:"name"`
+	Level int    `json:"level"`
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	server, client := newTestClient(t)
+	c := NewCache(client)
+	ctx := context.Background()
+
+	want := cachedProfile{Name: "Sara", Level: 4}
+	if err := c.Set(ctx, "profile:1", want, time.Minute); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if !server.Exists(keyPrefix + "cache:profile:1") {
+		t.Fatal("entry not stored under the cache prefix")
+	}
+
+	var got cachedProfile
+	found, err := c.Get(ctx, "profile:1", &got)
+	if err != nil || !found {
+		t.Fatalf("get: found %v, err %v", found, err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheMisses(t *testing.T) {
+	server, client := newTestClient(t)
+	c := NewCache(client)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		setup func()
+	}{
+		{name: "unknown key", setup: func() {}},
+		{name: "expired entry", setup: func() {
+			if err := c.Set(ctx, "profile:1", cachedProfile{Name: "Sara"}, time.Second); err != nil {
+				t.Fatalf("set: %v", err)
+			}
+			server.FastForward(2 * time.Second)
+		}},
+		{name: "deleted entry", setup: func() {
+			if err := c.Set(ctx, "profile:1", cachedProfile{Name: "Sara"}, time.Minute); err != nil {
+				t.Fatalf("set: %v", err)
+			}
+			if err := c.Delete(ctx, "profile:1"); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.FlushAll()
+			tt.setup()
+			var got cachedProfile
+			found, err := c.Get(ctx, "profile:1", &got)
+			if err != nil || found {
+				t.Fatalf("found %v, err %v; want a miss", found, err)
+			}
+		})
+	}
+}
+
+func TestCacheRejectsCorruptEntries(t *testing.T) {
+	server, client := newTestClient(t)
+	c := NewCache(client)
+	server.Set(keyPrefix+"cache:profile:1", "{not json")
+
+	var got cachedProfile
+	if _, err := c.Get(context.Background(), "profile:1", &got); err == nil {
+		t.Fatal("corrupt entry decoded without an error")
+	}
+}
@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"mafia/pkg/utils"
+	"sort"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// offlineKey is a sorted set of unhandled offline members scored by when they left, so
+// Expired is a range query instead of a scan over every room.
+const offlineKey = keyPrefix + "presence:offline"
+
+type presenceStore struct {
+	client *goredis.Client
+}
+
+// NewPresenceStore keeps presence in Redis, shared by every API replica. Each room is a
+// hash of its members' presence.
+func NewPresenceStore(client *goredis.Client) ports.PresenceStore {
+	return &presenceStore{client: client}
+}
+
+func roomPresenceKey(roomID uint) string {
+	return fmt.Sprintf("%spresence:room:%d", keyPrefix, roomID)
+}
+
+func offlineMember(roomID, userID uint) string {
+	return fmt.Sprintf("%d:%d", roomID, userID)
+}
+
+func (s *presenceStore) Online(roomID, userID uint, at time.Time) error {
+	return s.set(domain.Presence{RoomID: roomID, UserID: userID, Status: domain.PresenceOnline, Since: at})
+}
+
+func (s *presenceStore) Offline(roomID, userID uint, at time.Time) error {
+	return s.set(domain.Presence{RoomID: roomID, UserID: userID, Status: domain.PresenceOffline, Since: at})
+}
+
+func (s *presenceStore) Get(roomID, userID uint) (*domain.Presence, bool, error) {
+	data, err := s.client.HGet(context.Background(), roomPresenceKey(roomID), utils.Key(userID)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var entry domain.Presence
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, err
+	}
+	return &entry, true, nil
+}
+
+func (s *presenceStore) List(roomID uint) ([]domain.Presence, error) {
+	values, err := s.client.HVals(context.Background(), roomPresenceKey(roomID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	var list []domain.Presence
+	for _, value := range values {
+		var entry domain.Presence
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, err
+		}
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].UserID < list[j].UserID })
+	return list, nil
+}
+
+func (s *presenceStore) Expired(cutoff time.Time) ([]domain.Presence, error) {
+	members, err := s.client.ZRangeByScore(context.Background(), offlineKey, &goredis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(cutoff.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	var list []domain.Presence
+	for _, member := range members {
+		var roomID, userID uint
+		if _, err := fmt.Sscanf(member, "%d:%d", &roomID, &userID); err != nil {
+			continue
+		}
+		entry, ok, err := s.Get(roomID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if ok && entry.Status == domain.PresenceOffline && !entry.Handled && entry.Since.Before(cutoff) {
+			list = append(list, *entry)
+		}
+	}
+	return list, nil
+}
+
+// MarkHandled flags the entry unless the member came back or left again meanwhile.
+func (s *presenceStore) MarkHandled(roomID, userID uint) error {
+	ctx := context.Background()
+	key := roomPresenceKey(roomID)
+	field := utils.Key(userID)
+	return s.client.Watch(ctx, func(tx *goredis.Tx) error {
+		data, err := tx.HGet(ctx, key, field).Bytes()
+		if errors.Is(err, goredis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var entry domain.Presence
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		entry.Handled = true
+		if data, err = json.Marshal(entry); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.HSet(ctx, key, field, data)
+			pipe.ZRem(ctx, offlineKey, offlineMember(roomID, userID))
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (s *presenceStore) Remove(roomID, userID uint) error {
+	ctx := context.Background()
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HDel(ctx, roomPresenceKey(roomID), utils.Key(userID))
+		pipe.ZRem(ctx, offlineKey, offlineMember(roomID, userID))
+		return nil
+	})
+	return err
+}
+
+func (s *presenceStore) set(entry domain.Presence) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	member := offlineMember(entry.RoomID, entry.UserID)
+	_, err = s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, roomPresenceKey(entry.RoomID), utils.Key(entry.UserID), data)
+		if entry.Status == domain.PresenceOffline {
+			pipe.ZAdd(ctx, offlineKey, goredis.Z{Score: float64(entry.Since.UnixMilli()), Member: member})
+		} else {
+			pipe.ZRem(ctx, offlineKey, member)
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"mafia/internal/core/domain"
+	"testing"
+	"time"
+)
+
+func TestPresenceTracksMembers(t *testing.T) {
+	_, client := newTestClient(t)
+	s := NewPresenceStore(client)
+	now := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+
+	if err := s.Online(1, 7, now); err != nil {
+		t.Fatalf("online: %v", err)
+	}
+	if err := s.Offline(1, 3, now.Add(time.Second)); err != nil {
+		t.Fatalf("offline: %v", err)
+	}
+	if err := s.Online(2, 7, now); err != nil {
+		t.Fatalf("online: %v", err)
+	}
+
+	entry, ok, err := s.Get(1, 7)
+	if err != nil || !ok {
+		t.Fatalf("get: ok %v, err %v", ok, err)
+	}
+	if entry.Status != domain.PresenceOnline || !entry.Since.Equal(now) {
+		t.Fatalf("got %+v", entry)
+	}
+	if _, ok, err := s.Get(1, 9); err != nil || ok {
+		t.Fatalf("unknown member: ok %v, err %v", ok, err)
+	}
+
+	list, err := s.List(1)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 || list[0].UserID != 3 || list[1].UserID != 7 {
+		t.Fatalf("list %+v, want members 3 and 7 of room 1 in order", list)
+	}
+}
+
+func TestPresenceExpired(t *testing.T) {
+	now := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	cutoff := now.Add(-time.Minute)
+	tests := []struct {
+		name  string
+		setup func(s *presenceStore) error
+		want  []uint
+	}{
+		{
+			name:  "offline before the cutoff",
+			setup: func(s *presenceStore) error { return s.Offline(1, 7, cutoff.Add(-time.Second)) },
+			want:  []uint{7},
+		},
+		{
+			name:  "offline since the cutoff",
+			setup: func(s *presenceStore) error { return s.Offline(1, 7, cutoff.Add(time.Second)) },
+		},
+		{
+			name: "came back online",
+			setup: func(s *presenceStore) error {
+				if err := s.Offline(1, 7, cutoff.Add(-time.Minute)); err != nil {
+					return err
+				}
+				return s.Online(1, 7, cutoff.Add(-time.Second))
+			},
+		},
+		{
+			name: "already handled",
+			setup: func(s *presenceStore) error {
+				if err := s.Offline(1, 7, cutoff.Add(-time.Second)); err != nil {
+					return err
+				}
+				return s.MarkHandled(1, 7)
+			},
+		},
+		{
+			name: "removed from the room",
+			setup: func(s *presenceStore) error {
+				if err := s.Offline(1, 7, cutoff.Add(-time.Second)); err != nil {
+					return err
+				}
+				return s.Remove(1, 7)
+			},
+		},
+		{
+			name: "in the order they left",
+			setup: func(s *presenceStore) error {
+				if err := s.Offline(2, 9, cutoff.Add(-time.Second)); err != nil {
+					return err
+				}
+				return s.Offline(1, 7, cutoff.Add(-time.Minute))
+			},
+			want: []uint{7, 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := newTestClient(t)
+			s := &presenceStore{client: client}
+			if err := tt.setup(s); err != nil {
+				t.Fatalf("setup: %v", err)
+			}
+			expired, err := s.Expired(cutoff)
+			if err != nil {
+				t.Fatalf("expired: %v", err)
+			}
+			var got []uint
+			for _, entry := range expired {
+				got = append(got, entry.UserID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expired %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expired %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPresenceMarkHandled(t *testing.T) {
+	server, client := newTestClient(t)
+	s := NewPresenceStore(client)
+	left := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+
+	if err := s.Offline(1, 7, left); err != nil {
+		t.Fatalf("offline: %v", err)
+	}
+	if err := s.MarkHandled(1, 7); err != nil {
+		t.Fatalf("mark handled: %v", err)
+	}
+	entry, ok, err := s.Get(1, 7)
+	if err != nil || !ok || !entry.Handled || entry.Status != domain.PresenceOffline {
+		t.Fatalf("got %+v, ok %v, err %v; want a handled offline entry", entry, ok, err)
+	}
+	if members, _ := server.ZMembers(offlineKey); len(members) != 0 {
+		t.Fatalf("handled member still indexed: %v", members)
+	}
+
+	// Members who are not tracked are left alone.
+	if err := s.MarkHandled(1, 9); err != nil {
+		t.Fatalf("mark unknown member: %v", err)
+	}
+	if _, ok, _ := s.Get(1, 9); ok {
+		t.Fatal("marking an unknown member created an entry")
+	}
+}
@@ -1,9 +1,23 @@
 package redis
 
-type Client struct {
-	Addr string
-}
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces every key the adapters write.
+const keyPrefix = "mafia:"
 
-func New(addr string) *Client {
-	return &Client{Addr: addr}
+// New connects to the Redis server at addr and checks that it answers.
+func New(addr string) (*goredis.Client, error) {
+	client := goredis.NewClient(&goredis.Options{Addr: addr})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
 }
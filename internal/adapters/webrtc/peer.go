@@ -0,0 +1,167 @@
+package webrtc
+
+import (
+	"errors"
+	"mafia/internal/core/domain"
+	"sync"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// peer is one member's connection to the SFU. Offers are serialized: a change made
+// while an offer is waiting for its answer is folded into the next offer.
+type peer struct {
+	clientID   string
+	roomID     uint
+	userID     uint
+	pc         *webrtc.PeerConnection
+	signal     func(domain.WSMessage)
+	iceServers []domain.ICEServer
+
+	mu          sync.Mutex
+	senders     map[uint]*webrtc.RTPSender
+	negotiating bool
+	pending     bool
+	candidates  []webrtc.ICECandidateInit
+	closed      bool
+}
+
+func newPeer(clientID string, roomID, userID uint, pc *webrtc.PeerConnection, signal func(domain.WSMessage), iceServers []domain.ICEServer) *peer {
+	return &peer{
+		clientID:   clientID,
+		roomID:     roomID,
+		userID:     userID,
+		pc:         pc,
+		signal:     signal,
+		iceServers: iceServers,
+		senders:    make(map[uint]*webrtc.RTPSender),
+	}
+}
+
+// negotiate sends the client an offer for the current set of tracks.
+func (p *peer) negotiate() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	if p.negotiating {
+		p.pending = true
+		return nil
+	}
+	offer, err := p.pc.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	if err := p.pc.SetLocalDescription(offer); err != nil {
+		return err
+	}
+	p.negotiating = true
+	p.signal(domain.WSMessage{Type: domain.WSVoiceOffer, Data: domain.VoiceDescription{
+		RoomID:     p.roomID,
+		SDP:        offer.SDP,
+		ICEServers: p.iceServers,
+	}})
+	return nil
+}
+
+// answer completes the outstanding offer and sends the next one if tracks changed in
+// the meantime.
+func (p *peer) answer(sdp string) error {
+	p.mu.Lock()
+	if !p.negotiating {
+		p.mu.Unlock()
+		return errors.New("no voice offer is waiting for an answer")
+	}
+	if err := p.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: sdp}); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.negotiating = false
+	for _, candidate := range p.candidates {
+		_ = p.pc.AddICECandidate(candidate)
+	}
+	p.candidates = nil
+	again := p.pending
+	p.pending = false
+	p.mu.Unlock()
+
+	if again {
+		return p.negotiate()
+	}
+	return nil
+}
+
+// addCandidate adds a client candidate, holding it back until the client's first
+// answer has been applied.
+func (p *peer) addCandidate(candidate webrtc.ICECandidateInit) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pc.RemoteDescription() == nil {
+		p.candidates = append(p.candidates, candidate)
+		return nil
+	}
+	return p.pc.AddICECandidate(candidate)
+}
+
+func (p *peer) sendCandidate(candidate webrtc.ICECandidateInit) {
+	p.signal(domain.WSMessage{Type: domain.WSVoiceCandidate, Data: domain.VoiceCandidate{
+		RoomID:           p.roomID,
+		Candidate:        candidate.Candidate,
+		SDPMid:           candidate.SDPMid,
+		SDPMLineIndex:    candidate.SDPMLineIndex,
+		UsernameFragment: candidate.UsernameFragment,
+	}})
+}
+
+// addTrack sends the speaker's track to this peer. It reports whether the peer needs a
+// new offer; a speaker who rejoined only has their old track swapped out.
+func (p *peer) addTrack(speaker uint, track *webrtc.TrackLocalStaticRTP) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false, nil
+	}
+	if sender, ok := p.senders[speaker]; ok {
+		return false, sender.ReplaceTrack(track)
+	}
+	sender, err := p.pc.AddTrack(track)
+	if err != nil {
+		return false, err
+	}
+	p.senders[speaker] = sender
+	// Reading RTCP lets the interceptors handle NACKs and receiver reports.
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := sender.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return true, nil
+}
+
+// removeTrack stops sending the speaker's track, unless it was already replaced by a
+// newer one. It reports whether the peer needs a new offer.
+func (p *peer) removeTrack(speaker uint, track *webrtc.TrackLocalStaticRTP) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sender, ok := p.senders[speaker]
+	if p.closed || !ok || sender.Track() != track {
+		return false
+	}
+	delete(p.senders, speaker)
+	return p.pc.RemoveTrack(sender) == nil
+}
+
+func (p *peer) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+	_ = p.pc.Close()
+}
@@ -1,11 +1,379 @@
 package webrtc
 
-type SFU struct{}
+import (
+	"errors"
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/pkg/utils"
+	"mafia/pkg/voice"
+	"sync"
 
-type Client struct{}
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v4"
+)
 
-func NewSFU() *SFU { return &SFU{} }
+var errNotJoined = errors.New("not connected to the room's voice channel")
 
-func (s *SFU) NewClient(_ interface{}, _ string, _ string) *Client { return &Client{} }
+// SFU forwards each member's microphone to the other members of the same room. The
+// server always makes the offers: a joining peer is offered the tracks of the members
+// already speaking and is sent a new offer whenever a track is added or removed, so
+// clients only ever answer. Forwarded tracks use the stream ID "user-<id>" of their
+// speaker. Voice only flows between peers connected to the same replica.
+//
+// Every listener gets its own copy of each speaker's track, and each packet is only
+// written to the copies of listeners the router lets hear the speaker, so muting never
+// needs a renegotiation.
+type SFU struct {
+	api        *webrtc.API
+	config     webrtc.Configuration
+	iceServers []domain.ICEServer
+	router     *voice.Router
 
-func (s *SFU) AddClient(_ string, _ *Client) {}
+	mu    sync.Mutex
+	rooms map[uint]*room
+}
+
+type room struct {
+	peers  map[uint]*peer
+	tracks map[uint]*published
+}
+
+// published is a speaker's microphone as forwarded to the rest of the room, with one
+// track per listening peer.
+type published struct {
+	from  *peer
+	codec webrtc.RTPCodecCapability
+
+	mu     sync.RWMutex
+	tracks map[*peer]*webrtc.TrackLocalStaticRTP
+}
+
+// NewSFU sets up an Opus-only media engine with the default NACK and RTCP report
+// interceptors. The router decides which listeners hear each speaker.
+func NewSFU(iceServers []domain.ICEServer, router *voice.Router) (*SFU, error) {
+	media := &webrtc.MediaEngine{}
+	err := media.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1"},
+		PayloadType:        111,
+	}, webrtc.RTPCodecTypeAudio)
+	if err != nil {
+		return nil, err
+	}
+	interceptors := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(media, interceptors); err != nil {
+		return nil, err
+	}
+
+	config := webrtc.Configuration{}
+	for _, server := range iceServers {
+		config.ICEServers = append(config.ICEServers, webrtc.ICEServer{
+			URLs:       server.URLs,
+			Username:   server.Username,
+			Credential: server.Credential,
+		})
+	}
+	return &SFU{
+		api:        webrtc.NewAPI(webrtc.WithMediaEngine(media), webrtc.WithInterceptorRegistry(interceptors)),
+		config:     config,
+		iceServers: iceServers,
+		router:     router,
+		rooms:      make(map[uint]*room),
+	}, nil
+}
+
+// Join creates the user's peer in the room, replacing one they already had, and sends
+// the client its first offer.
+func (s *SFU) Join(clientID string, roomID, userID uint, signal func(domain.WSMessage)) error {
+	pc, err := s.api.NewPeerConnection(s.config)
+	if err != nil {
+		return err
+	}
+	// The microphone arrives on this transceiver; forwarded tracks get their own.
+	if _, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+		pc.Close()
+		return err
+	}
+	p := newPeer(clientID, roomID, userID, pc, signal, s.iceServers)
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate != nil {
+			p.sendCandidate(candidate.ToJSON())
+		}
+	})
+	pc.OnTrack(func(remote *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		s.forward(p, remote)
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			s.remove(p)
+		}
+	})
+
+	s.mu.Lock()
+	r, ok := s.rooms[roomID]
+	if !ok {
+		r = &room{peers: make(map[uint]*peer), tracks: make(map[uint]*published)}
+		s.rooms[roomID] = r
+	}
+	previous := r.peers[userID]
+	r.peers[userID] = p
+	for speaker, pub := range r.tracks {
+		if speaker == userID {
+			continue
+		}
+		track, err := pub.trackFor(p)
+		if err == nil {
+			_, err = p.addTrack(speaker, track)
+		}
+		if err != nil {
+			s.mu.Unlock()
+			s.remove(p)
+			return err
+		}
+	}
+	s.mu.Unlock()
+
+	if previous != nil {
+		s.remove(previous)
+	}
+	return p.negotiate()
+}
+
+// Answer applies the client's answer to the last offer.
+func (s *SFU) Answer(clientID string, roomID, userID uint, sdp string) error {
+	p, err := s.owned(clientID, roomID, userID)
+	if err != nil {
+		return err
+	}
+	return p.answer(sdp)
+}
+
+// Candidate adds an ICE candidate gathered by the client.
+func (s *SFU) Candidate(clientID string, roomID, userID uint, candidate domain.VoiceCandidate) error {
+	p, err := s.owned(clientID, roomID, userID)
+	if err != nil {
+		return err
+	}
+	return p.addCandidate(webrtc.ICECandidateInit{
+		Candidate:        candidate.Candidate,
+		SDPMid:           candidate.SDPMid,
+		SDPMLineIndex:    candidate.SDPMLineIndex,
+		UsernameFragment: candidate.UsernameFragment,
+	})
+}
+
+// Route applies the voice rules of a room.
+func (s *SFU) Route(rules domain.VoiceRules) {
+	routed := voice.Rules{Grants: make(map[string]voice.Grant, len(rules.Grants))}
+	for userID, grant := range rules.Grants {
+		routed.Grants[utils.Key(userID)] = voice.Grant{Speaks: grant.Speaks, Hears: grant.Hears, Override: grant.Override}
+	}
+	if rules.Turn != nil {
+		routed.Turns = append(routed.Turns, voice.Turn{Channel: domain.VoiceTable, Holder: utils.Key(rules.Turn.UserID), Ends: rules.Turn.EndsAt})
+	}
+	s.router.Route(utils.Key(rules.RoomID), routed)
+}
+
+// Serving reports whether any member of the room is connected to this SFU.
+func (s *SFU) Serving(roomID uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.rooms[roomID]
+	return ok
+}
+
+// Leave drops the user's peer in the room.
+func (s *SFU) Leave(roomID, userID uint) {
+	s.mu.Lock()
+	var p *peer
+	if r, ok := s.rooms[roomID]; ok {
+		p = r.peers[userID]
+	}
+	s.mu.Unlock()
+	if p != nil {
+		s.remove(p)
+	}
+}
+
+// Release drops every peer the client owns, once its websocket is gone.
+func (s *SFU) Release(clientID string) {
+	for _, p := range s.collect(func(p *peer) bool { return p.clientID == clientID }) {
+		s.remove(p)
+	}
+}
+
+// Close drops every peer.
+func (s *SFU) Close() {
+	for _, p := range s.collect(func(*peer) bool { return true }) {
+		s.remove(p)
+	}
+}
+
+func (s *SFU) collect(match func(*peer) bool) []*peer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var peers []*peer
+	for _, r := range s.rooms {
+		for _, p := range r.peers {
+			if match(p) {
+				peers = append(peers, p)
+			}
+		}
+	}
+	return peers
+}
+
+// owned finds the user's peer, provided the client is the one that joined it.
+func (s *SFU) owned(clientID string, roomID, userID uint) (*peer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rooms[roomID]
+	if !ok {
+		return nil, errNotJoined
+	}
+	p, ok := r.peers[userID]
+	if !ok || p.clientID != clientID {
+		return nil, errNotJoined
+	}
+	return p, nil
+}
+
+// forward publishes a peer's microphone to the rest of the room and copies its packets
+// until the track ends.
+func (s *SFU) forward(p *peer, remote *webrtc.TrackRemote) {
+	if remote.Kind() != webrtc.RTPCodecTypeAudio {
+		return
+	}
+	pub := &published{from: p, codec: remote.Codec().RTPCodecCapability, tracks: make(map[*peer]*webrtc.TrackLocalStaticRTP)}
+
+	s.mu.Lock()
+	r, ok := s.rooms[p.roomID]
+	if !ok || r.peers[p.userID] != p {
+		s.mu.Unlock()
+		return
+	}
+	r.tracks[p.userID] = pub
+	listeners := r.others(p.userID)
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		track, err := pub.trackFor(listener)
+		if err != nil {
+			continue
+		}
+		if added, err := listener.addTrack(p.userID, track); err == nil && added {
+			listener.negotiate()
+		}
+	}
+
+	room, speaker := utils.Key(p.roomID), utils.Key(p.userID)
+	for {
+		packet, _, err := remote.ReadRTP()
+		if err != nil {
+			break
+		}
+		pub.mu.RLock()
+		for listener, track := range pub.tracks {
+			if !s.router.CanHear(room, utils.Key(listener.userID), speaker) {
+				continue
+			}
+			// A listener whose connection is going away just misses the packet.
+			_ = track.WriteRTP(packet)
+		}
+		pub.mu.RUnlock()
+	}
+	s.unpublish(p)
+}
+
+// trackFor returns the listener's copy of the speaker's track, creating it on first use.
+func (pub *published) trackFor(listener *peer) (*webrtc.TrackLocalStaticRTP, error) {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	if track, ok := pub.tracks[listener]; ok {
+		return track, nil
+	}
+	track, err := webrtc.NewTrackLocalStaticRTP(pub.codec, fmt.Sprintf("audio-%d", pub.from.userID), streamID(pub.from.userID))
+	if err != nil {
+		return nil, err
+	}
+	pub.tracks[listener] = track
+	return track, nil
+}
+
+// drop forgets the listener's copy of the track.
+func (pub *published) drop(listener *peer) (*webrtc.TrackLocalStaticRTP, bool) {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	track, ok := pub.tracks[listener]
+	delete(pub.tracks, listener)
+	return track, ok
+}
+
+// unpublish stops forwarding the peer's microphone to the rest of the room.
+func (s *SFU) unpublish(p *peer) {
+	s.mu.Lock()
+	r, ok := s.rooms[p.roomID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	pub, ok := r.tracks[p.userID]
+	if !ok || pub.from != p {
+		s.mu.Unlock()
+		return
+	}
+	delete(r.tracks, p.userID)
+	listeners := r.others(p.userID)
+	s.prune(p.roomID)
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		if track, ok := pub.drop(listener); ok && listener.removeTrack(p.userID, track) {
+			listener.negotiate()
+		}
+	}
+}
+
+// remove takes the peer out of its room and closes its connection.
+func (s *SFU) remove(p *peer) {
+	s.mu.Lock()
+	var tracks []*published
+	if r, ok := s.rooms[p.roomID]; ok {
+		if r.peers[p.userID] == p {
+			delete(r.peers, p.userID)
+		}
+		for _, pub := range r.tracks {
+			tracks = append(tracks, pub)
+		}
+		s.prune(p.roomID)
+	}
+	if _, ok := s.rooms[p.roomID]; !ok {
+		s.router.Stop(utils.Key(p.roomID))
+	}
+	s.mu.Unlock()
+	for _, pub := range tracks {
+		pub.drop(p)
+	}
+	s.unpublish(p)
+	p.close()
+}
+
+// prune forgets a room nobody is connected to. Callers must hold s.mu.
+func (s *SFU) prune(roomID uint) {
+	if r, ok := s.rooms[roomID]; ok && len(r.peers) == 0 && len(r.tracks) == 0 {
+		delete(s.rooms, roomID)
+	}
+}
+
+func (r *room) others(userID uint) []*peer {
+	peers := make([]*peer, 0, len(r.peers))
+	for id, p := range r.peers {
+		if id != userID {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+func streamID(userID uint) string {
+	return fmt.Sprintf("user-%d", userID)
+}
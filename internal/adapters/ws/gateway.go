@@ -0,0 +1,448 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"mafia/pkg/events"
+	"mafia/pkg/utils"
+	"mafia/pkg/websocket"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gws "github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	// maxMessage leaves room for the SDP answers of a full voice room.
+	maxMessage = 32 << 10
+	sendBuffer = 64
+	// backlogSize is how many messages per topic are kept for resuming clients.
+	backlogSize = 256
+)
+
+// Gateway upgrades authenticated HTTP requests to websockets and relays game events.
+// Every client is subscribed to its user topic; joining a room adds the room topic.
+// Published messages carry a sequence number so a client resuming its session can
+// replay what it missed. Chat is sent to each reader of its channel instead and is not
+// replayed: clients page through the stored history after resuming.
+type Gateway struct {
+	hub      *websocket.Hub
+	backlog  *websocket.Backlog
+	sessions *sessions
+	users    ports.UserService
+	games    ports.GameService
+	chats    ports.ChatService
+	bus      ports.EventBus
+	voice    ports.SFU
+	upgrader gws.Upgrader
+	nextID   atomic.Uint64
+}
+
+// NewGateway builds a gateway and subscribes it to the game topics on the event bus.
+// Sessions of dropped clients can be resumed within resumeWindow. Voice signaling is
+// handed to the SFU.
+func NewGateway(hub *websocket.Hub, users ports.UserService, games ports.GameService, chats ports.ChatService, bus ports.EventBus, voice ports.SFU, resumeWindow time.Duration) *Gateway {
+	g := &Gateway{
+		hub:      hub,
+		backlog:  websocket.NewBacklog(backlogSize),
+		sessions: newSessions(resumeWindow),
+		users:    users,
+		games:    games,
+		chats:    chats,
+		bus:      bus,
+		voice:    voice,
+		upgrader: gws.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     func(*http.Request) bool { return true },
+		},
+	}
+	if bus != nil {
+		g.subscribe(bus)
+	}
+	return g
+}
+
+// Handle godoc
+// @Summary Open the game websocket
+// @Description Upgrades to a websocket authenticated with the bearer token from the Authorization header or the token query parameter. The first message is a session carrying a resume token. Clients send subscribe, unsubscribe, chat (room, team or dead channel, following the same rules as POST /game/rooms/{id}/chat) and resume messages and receive phase_changed, player_joined, player_left, player_connected, player_disconnected, player_absent, player_returned, vote_cast, player_died, game_finished and chat messages for the rooms they subscribed to, plus their own investigation_result messages. Every broadcast carries a seq; resume with the last seq seen replays newer messages. Chat only reaches members who may read its channel and have not blocked the sender; it carries no seq and is not replayed, so page through GET /game/rooms/{id}/chat after resuming. Voice: voice_join makes the server send a voice_offer, which the client answers with voice_answer; both sides trickle voice_candidate messages and every later voice_offer must be answered too. Forwarded audio tracks carry the stream ID user-<id> of their speaker. Voice is served by the replica running the room: open the socket with the room query parameter so it is relayed there, otherwise voice_join may fail with an error naming the serving node. voice_leave, leaving the room or closing the socket hangs up. The server only forwards voice the game allows: the dead are heard in the graveyard only, at night only the mafia hear each other, silenced players cannot speak for the day. During the day speaking_turn messages announce who holds the floor, until when, and the queue after them; challenge_requested messages tell the speaker who asked to interject.
+// @Tags Game
+// @Param token query string false "JWT when the Authorization header cannot be set"
+// @Param room query int false "Room whose voice the socket will join; the socket is relayed to the replica serving it"
+// @Success 101 {object} domain.WSMessage
+// @Failure 401 {object} map[string]string
+// @Router /ws [get]
+func (g *Gateway) Handle(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+		return
+	}
+	userID, err := g.users.ValidateToken(token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	conn, err := g.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	client := &websocket.Client{
+		ID:   strconv.FormatUint(g.nextID.Add(1), 10),
+		Send: make(chan []byte, sendBuffer),
+	}
+	g.hub.Register(client)
+	g.hub.Subscribe(client.ID, userTopic(userID))
+	resumeToken := g.sessions.open(client.ID, userID)
+	go writePump(conn, client.Send)
+	g.send(client, domain.WSMessage{Type: domain.WSSession, Data: domain.Session{Token: resumeToken, Seq: g.backlog.Seq()}})
+	g.readPump(conn, client, userID)
+}
+
+// readPump handles client messages until the connection closes.
+func (g *Gateway) readPump(conn *gws.Conn, client *websocket.Client, userID uint) {
+	defer func() {
+		g.voice.Release(client.ID)
+		for _, roomID := range g.sessions.close(client.ID) {
+			if err := g.games.Disconnect(roomID, userID); err != nil {
+				logrus.WithError(err).Warn("failed to record disconnect")
+			}
+		}
+		g.hub.Unregister(client.ID)
+		conn.Close()
+	}()
+	conn.SetReadLimit(maxMessage)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if err := g.dispatch(client, userID, msg.Type, msg.Data); err != nil {
+			g.send(client, domain.WSMessage{Type: domain.WSError, Data: gin.H{"error": err.Error()}})
+		}
+	}
+}
+
+func (g *Gateway) dispatch(client *websocket.Client, userID uint, kind string, data json.RawMessage) error {
+	switch kind {
+	case domain.WSSubscribe:
+		var sub domain.RoomSubscription
+		if err := json.Unmarshal(data, &sub); err != nil {
+			return err
+		}
+		if err := g.join(client, userID, sub.RoomID); err != nil {
+			return err
+		}
+	case domain.WSUnsubscribe:
+		var sub domain.RoomSubscription
+		if err := json.Unmarshal(data, &sub); err != nil {
+			return err
+		}
+		last := g.sessions.leave(client.ID, sub.RoomID)
+		g.voice.Leave(sub.RoomID, userID)
+		g.hub.Unsubscribe(client.ID, roomTopic(sub.RoomID))
+		if last {
+			return g.games.Disconnect(sub.RoomID, userID)
+		}
+	case domain.WSResume:
+		var req domain.ResumeRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		return g.resume(client, userID, req)
+	case domain.WSChat:
+		var chat domain.ChatMessage
+		if err := json.Unmarshal(data, &chat); err != nil {
+			return err
+		}
+		// The service announces the message on the bus so members connected to other
+		// replicas get it too.
+		msg, err := g.chats.Post(chat.RoomID, userID, domain.ChatRequest{Channel: chat.Channel, Text: chat.Text})
+		if err != nil {
+			return err
+		}
+		if g.bus == nil {
+			g.relayChat(*msg)
+		}
+	case domain.WSVoiceJoin:
+		var sub domain.RoomSubscription
+		if err := json.Unmarshal(data, &sub); err != nil {
+			return err
+		}
+		if _, err := g.games.MemberRole(sub.RoomID, userID); err != nil {
+			return err
+		}
+		// Calls are mixed by the SFU of the replica serving the room; the client has to
+		// reconnect with the room query parameter to be relayed there.
+		lease, err := g.games.RoomOwner(sub.RoomID)
+		if err != nil {
+			return err
+		}
+		if lease != nil {
+			return &domain.RoomOwnedError{Lease: *lease}
+		}
+		// The room's rules must be in force before the first packet is forwarded.
+		rules, err := g.games.VoiceRules(sub.RoomID)
+		if err != nil {
+			return err
+		}
+		g.voice.Route(*rules)
+		return g.voice.Join(client.ID, sub.RoomID, userID, func(msg domain.WSMessage) { g.send(client, msg) })
+	case domain.WSVoiceAnswer:
+		var answer domain.VoiceDescription
+		if err := json.Unmarshal(data, &answer); err != nil {
+			return err
+		}
+		return g.voice.Answer(client.ID, answer.RoomID, userID, answer.SDP)
+	case domain.WSVoiceCandidate:
+		var candidate domain.VoiceCandidate
+		if err := json.Unmarshal(data, &candidate); err != nil {
+			return err
+		}
+		return g.voice.Candidate(client.ID, candidate.RoomID, userID, candidate)
+	case domain.WSVoiceLeave:
+		var sub domain.RoomSubscription
+		if err := json.Unmarshal(data, &sub); err != nil {
+			return err
+		}
+		g.voice.Leave(sub.RoomID, userID)
+	default:
+		return fmt.Errorf("unknown message type")
+	}
+	return nil
+}
+
+// join subscribes the client to a room it is a member of and marks the user connected.
+func (g *Gateway) join(client *websocket.Client, userID, roomID uint) error {
+	if err := g.games.Connect(roomID, userID); err != nil {
+		return err
+	}
+	g.sessions.join(client.ID, roomID)
+	g.hub.Subscribe(client.ID, roomTopic(roomID))
+	return nil
+}
+
+// resume restores a dropped session on the client and replays the messages published
+// to its topics after req.LastSeq.
+func (g *Gateway) resume(client *websocket.Client, userID uint, req domain.ResumeRequest) error {
+	rooms, err := g.sessions.resume(client.ID, req.Token, userID)
+	if err != nil {
+		return err
+	}
+	topics := []string{userTopic(userID)}
+	for _, roomID := range rooms {
+		if err := g.join(client, userID, roomID); err != nil {
+			g.sessions.leave(client.ID, roomID)
+			continue
+		}
+		topics = append(topics, roomTopic(roomID))
+	}
+	for _, entry := range g.backlog.Since(req.LastSeq, topics...) {
+		g.hub.Send(client.ID, entry.Payload)
+	}
+	g.send(client, domain.WSMessage{Type: domain.WSSession, Data: domain.Session{Token: req.Token, Seq: g.backlog.Seq()}})
+	return nil
+}
+
+// subscribe maps the game service events onto room broadcasts.
+func (g *Gateway) subscribe(bus ports.EventBus) {
+	phaseChanged := func(change domain.PhaseChange) {
+		g.refreshVoice(change.RoomID)
+		g.broadcast(change.RoomID, domain.WSMessage{Type: domain.WSPhaseChanged, Data: change})
+	}
+	events.Subscribe(bus, func(_ context.Context, e domain.GameBegan) { phaseChanged(e.PhaseChange) })
+	events.Subscribe(bus, func(_ context.Context, e domain.PhaseChanged) { phaseChanged(e.PhaseChange) })
+	member := func(kind string, m domain.RoomMember) {
+		g.broadcast(m.RoomID, domain.WSMessage{Type: kind, Data: m})
+	}
+	events.Subscribe(bus, func(_ context.Context, e domain.PlayerJoined) {
+		g.refreshVoice(e.RoomID)
+		member(domain.WSPlayerJoined, e.RoomMember)
+	})
+	events.Subscribe(bus, func(_ context.Context, e domain.PlayerLeft) {
+		g.voice.Leave(e.RoomID, e.UserID)
+		g.refreshVoice(e.RoomID)
+		member(domain.WSPlayerLeft, e.RoomMember)
+	})
+	events.Subscribe(bus, func(_ context.Context, e domain.PlayerKicked) { g.voice.Leave(e.RoomID, e.UserID) })
+	events.Subscribe(bus, func(_ context.Context, e domain.PlayerConnected) { member(domain.WSConnected, e.RoomMember) })
+	events.Subscribe(bus, func(_ context.Context, e domain.PlayerDisconnected) { member(domain.WSDisconnected, e.RoomMember) })
+	events.Subscribe(bus, func(_ context.Context, e domain.PlayerReturned) { member(domain.WSReturned, e.RoomMember) })
+	events.Subscribe(bus, func(_ context.Context, vote domain.VoteCast) {
+		g.broadcast(vote.RoomID, domain.WSMessage{Type: domain.WSVoteCast, Data: vote})
+	})
+	events.Subscribe(bus, func(_ context.Context, death domain.PlayerDied) {
+		g.refreshVoice(death.RoomID)
+		g.broadcast(death.RoomID, domain.WSMessage{Type: domain.WSPlayerDied, Data: death})
+	})
+	events.Subscribe(bus, func(_ context.Context, finished domain.GameFinished) {
+		g.refreshVoice(finished.RoomID)
+		// Resuming clients only need the outcome of a finished game.
+		g.backlog.Forget(roomTopic(finished.RoomID))
+		g.broadcast(finished.RoomID, domain.WSMessage{Type: domain.WSGameFinished, Data: finished})
+	})
+	events.Subscribe(bus, func(_ context.Context, retired domain.RoomRetired) {
+		g.backlog.Forget(roomTopic(retired.RoomID))
+	})
+	events.Subscribe(bus, func(_ context.Context, turn domain.SpeakingTurnChanged) {
+		g.refreshVoice(turn.RoomID)
+		g.broadcast(turn.RoomID, domain.WSMessage{Type: domain.WSSpeakingTurn, Data: turn})
+	})
+	events.Subscribe(bus, func(_ context.Context, challenge domain.ChallengeRequested) {
+		g.broadcast(challenge.RoomID, domain.WSMessage{Type: domain.WSChallenge, Data: challenge})
+	})
+	events.Subscribe(bus, func(_ context.Context, absent domain.PlayerAbsent) {
+		g.broadcast(absent.RoomID, domain.WSMessage{Type: domain.WSAbsent, Data: absent})
+	})
+	events.Subscribe(bus, func(_ context.Context, chat domain.ChatMessage) {
+		g.relayChat(chat)
+	})
+	events.Subscribe(bus, func(_ context.Context, report domain.InvestigationReport) {
+		g.publish(userTopic(report.Investigator), domain.WSMessage{Type: domain.WSInvestigated, Data: report})
+	})
+}
+
+// ExpireSessions drops the sessions whose resume window has passed, along with the
+// backlog of users who have no session left. It returns how many users it forgot.
+func (g *Gateway) ExpireSessions(now time.Time) int {
+	users := g.sessions.expire(now)
+	for _, userID := range users {
+		g.backlog.Forget(userTopic(userID))
+	}
+	return len(users)
+}
+
+// MetricsHandler godoc
+// @Summary Websocket hub metrics
+// @Description Reports connected clients, topics and delivery counters, including messages dropped and clients disconnected for falling behind.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} websocket.Metrics
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/ws/metrics [get]
+func (g *Gateway) MetricsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, g.hub.Metrics())
+}
+
+// refreshVoice reapplies the room's voice rules when members are connected to voice
+// here. Without them the SFU would keep routing by the previous phase.
+func (g *Gateway) refreshVoice(roomID uint) {
+	if !g.voice.Serving(roomID) {
+		return
+	}
+	rules, err := g.games.VoiceRules(roomID)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to refresh voice rules")
+		return
+	}
+	g.voice.Route(*rules)
+}
+
+// relayChat sends a chat message to the clients here whose user may read its channel
+// and has not blocked the sender.
+func (g *Gateway) relayChat(chat domain.ChatMessage) {
+	recipients, err := g.chats.Recipients(chat)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to resolve chat recipients")
+		return
+	}
+	readers := make(map[uint]bool, len(recipients))
+	for _, userID := range recipients {
+		readers[userID] = true
+	}
+	payload, err := json.Marshal(domain.WSMessage{Type: domain.WSChat, Data: chat})
+	if err != nil {
+		logrus.WithError(err).Warn("failed to encode websocket message")
+		return
+	}
+	for _, clientID := range g.hub.Subscribers(roomTopic(chat.RoomID)) {
+		if userID, ok := g.sessions.user(clientID); ok && readers[userID] {
+			g.hub.Send(clientID, payload)
+		}
+	}
+}
+
+func (g *Gateway) broadcast(roomID uint, msg domain.WSMessage) {
+	g.publish(roomTopic(roomID), msg)
+}
+
+func (g *Gateway) publish(topic string, msg domain.WSMessage) {
+	payload, err := g.backlog.Record(topic, func(seq uint64) ([]byte, error) {
+		msg.Seq = seq
+		return json.Marshal(msg)
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("failed to encode websocket message")
+		return
+	}
+	g.hub.Publish(topic, payload)
+}
+
+func (g *Gateway) send(client *websocket.Client, msg domain.WSMessage) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	g.hub.Send(client.ID, payload)
+}
+
+// writePump forwards queued messages to the connection and keeps it alive with pings.
+func writePump(conn *gws.Conn, send <-chan []byte) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+	for {
+		select {
+		case payload, ok := <-send:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(gws.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteMessage(gws.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(gws.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func roomTopic(roomID uint) string {
+	return "room:" + utils.Key(roomID)
+}
+
+func userTopic(userID uint) string {
+	return "user:" + utils.Key(userID)
+}
@@ -0,0 +1,173 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// session is a user's subscription state. It outlives a dropped socket for the resume
+// window so a reconnecting client can pick up where it left off.
+type session struct {
+	token    string
+	userID   uint
+	clientID string
+	rooms    map[uint]bool
+	expires  time.Time
+}
+
+// presence identifies a user in a room.
+type presence struct {
+	roomID, userID uint
+}
+
+type sessions struct {
+	mu       sync.Mutex
+	window   time.Duration
+	byToken  map[string]*session
+	byClient map[string]*session
+	// connected counts the attached sessions following a room per user, so a user with
+	// several tabs open only goes offline once the last of them leaves.
+	connected map[presence]int
+}
+
+func newSessions(window time.Duration) *sessions {
+	return &sessions{window: window, byToken: map[string]*session{}, byClient: map[string]*session{}, connected: map[presence]int{}}
+}
+
+// open starts a session for a new client and returns its resume token.
+func (s *sessions) open(clientID string, userID uint) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := &session{token: newToken(), userID: userID, clientID: clientID, rooms: map[uint]bool{}}
+	s.byToken[sess.token] = sess
+	s.byClient[clientID] = sess
+	return sess.token
+}
+
+// expire drops the detached sessions whose resume window has passed. It returns the
+// users left without any session, live or resumable.
+func (s *sessions) expire(now time.Time) []uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := map[uint]bool{}
+	for token, sess := range s.byToken {
+		if sess.clientID == "" && now.After(sess.expires) {
+			delete(s.byToken, token)
+			expired[sess.userID] = true
+		}
+	}
+	for _, sess := range s.byToken {
+		delete(expired, sess.userID)
+	}
+	users := make([]uint, 0, len(expired))
+	for userID := range expired {
+		users = append(users, userID)
+	}
+	return users
+}
+
+func (s *sessions) user(clientID string) (uint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.byClient[clientID]
+	if !ok {
+		return 0, false
+	}
+	return sess.userID, true
+}
+
+func (s *sessions) join(clientID string, roomID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.byClient[clientID]; ok && !sess.rooms[roomID] {
+		sess.rooms[roomID] = true
+		s.connected[presence{roomID, sess.userID}]++
+	}
+}
+
+// leave stops the client following the room. It reports whether that was the user's
+// last connection to the room.
+func (s *sessions) leave(clientID string, roomID uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.byClient[clientID]
+	if !ok || !sess.rooms[roomID] {
+		return false
+	}
+	delete(sess.rooms, roomID)
+	return s.release(presence{roomID, sess.userID})
+}
+
+// close detaches the client and starts the resume window. It returns the rooms the
+// user is no longer connected to through any other client.
+func (s *sessions) close(clientID string) []uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.byClient[clientID]
+	if !ok {
+		return nil
+	}
+	delete(s.byClient, clientID)
+	sess.clientID = ""
+	sess.expires = time.Now().Add(s.window)
+	var left []uint
+	for _, roomID := range roomList(sess.rooms) {
+		if s.release(presence{roomID, sess.userID}) {
+			left = append(left, roomID)
+		}
+	}
+	return left
+}
+
+// release drops one connection of the user to the room and reports whether it was
+// the last.
+func (s *sessions) release(p presence) bool {
+	s.connected[p]--
+	if s.connected[p] > 0 {
+		return false
+	}
+	delete(s.connected, p)
+	return true
+}
+
+// resume moves a detached session onto the client, replacing the session the client
+// was given on connect. It returns the rooms to restore.
+func (s *sessions) resume(clientID, token string, userID uint) ([]uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.byToken[token]
+	if !ok || sess.userID != userID || time.Now().After(sess.expires) {
+		return nil, fmt.Errorf("session cannot be resumed")
+	}
+	if sess.clientID != "" {
+		return nil, fmt.Errorf("session is still connected")
+	}
+	if fresh, ok := s.byClient[clientID]; ok {
+		delete(s.byToken, fresh.token)
+	}
+	sess.clientID = clientID
+	s.byClient[clientID] = sess
+	for roomID := range sess.rooms {
+		s.connected[presence{roomID, userID}]++
+	}
+	return roomList(sess.rooms), nil
+}
+
+func roomList(rooms map[uint]bool) []uint {
+	list := make([]uint, 0, len(rooms))
+	for id := range rooms {
+		list = append(list, id)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
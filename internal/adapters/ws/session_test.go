@@ -0,0 +1,30 @@
+package ws
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSessionsExpire(t *testing.T) {
+	s := newSessions(time.Minute)
+	s.open("a", 1)
+	s.open("b", 2)
+	s.open("c", 2)
+	s.open("d", 3)
+	s.close("a")
+	s.close("b")
+	s.close("d")
+
+	if users := s.expire(time.Now()); len(users) != 0 {
+		t.Fatalf("expired %v inside the resume window", users)
+	}
+	users := s.expire(time.Now().Add(2 * time.Minute))
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	if len(users) != 2 || users[0] != 1 || users[1] != 3 {
+		t.Fatalf("expired %v, want users 1 and 3; user 2 is still connected", users)
+	}
+	if _, err := s.resume("e", "", 1); err == nil {
+		t.Fatal("resumed an expired session")
+	}
+}
@@ -0,0 +1,363 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AbilityHandler applies the effect of a single ability action to the game being resolved.
+type AbilityHandler func(r *Resolution, action AbilityAction)
+
+// AbilityEffect describes how an ability code is resolved.
+type AbilityEffect struct {
+	Priority   int
+	TargetDead bool
+	Handler    AbilityHandler
+}
+
+var abilityEffects = map[string]AbilityEffect{}
+
+// RegisterAbility installs the effect for an ability code, replacing any previous registration.
+func RegisterAbility(code string, effect AbilityEffect) {
+	abilityEffects[code] = effect
+}
+
+// LookupAbilityEffect returns the registered effect for an ability code.
+func LookupAbilityEffect(code string) (AbilityEffect, bool) {
+	effect, ok := abilityEffects[code]
+	return effect, ok
+}
+
+// AbilityPriority returns the resolution priority for an ability, or zero when it has no handler.
+func AbilityPriority(code string) int {
+	return abilityEffects[code].Priority
+}
+
+// mafiaShots lists the abilities sharing the mafia shot, in order of precedence.
+var mafiaShots = []string{"godfather", "nato", "simple_mafia"}
+
+func init() {
+	for code, effect := range map[string]AbilityEffect{
+		// Blocks.
+		"kidnapper":   {Priority: PriorityBlock, Handler: blockTarget},
+		"saqi":        {Priority: PriorityBlock, Handler: intoxicate},
+		"clumsy_hand": {Priority: PriorityBlock, Handler: disable},
+
+		// Protections.
+		"protector":          {Priority: PriorityProtect, Handler: protect},
+		"dr_lecter":          {Priority: PriorityProtect, Handler: protectMafia},
+		"self_sacrificing":   {Priority: PriorityProtect, Handler: sacrifice},
+		"lawyer":             {Priority: PriorityProtect, Handler: defendTown},
+		"angel_of_salvation": {Priority: PriorityProtect, Handler: grantVoteImmunity},
+
+		// Modifiers.
+		"gambler":     {Priority: PriorityModify, Handler: addNextNightEffect(EffectBoosted)},
+		"sergeant":    {Priority: PriorityModify, Handler: addNextNightEffect(EffectUpgraded)},
+		"imposter":    {Priority: PriorityModify, Handler: disguise},
+		"mercenary":   {Priority: PriorityModify, Handler: frame},
+		"natasha":     {Priority: PriorityModify, Handler: addDayEffect(EffectSilenced)},
+		"swayer":      {Priority: PriorityModify, Handler: addDayEffect(EffectSwayed)},
+		"nostradamus": {Priority: PriorityModify, Handler: predict},
+		"innocent":    {Priority: PriorityModify, Handler: passive},
+		"mistress":    {Priority: PriorityModify, Handler: passive},
+
+		// Kills.
+		"godfather":      {Priority: PriorityKill, Handler: mafiaShot},
+		"nato":           {Priority: PriorityKill, Handler: mafiaShot},
+		"simple_mafia":   {Priority: PriorityKill, Handler: mafiaShot},
+		"terrorist":      {Priority: PriorityKill, Handler: detonate},
+		"assassin":       {Priority: PriorityKill, Handler: assassinate},
+		"hunter":         {Priority: PriorityKill, Handler: hunt},
+		"ranger":         {Priority: PriorityKill, Handler: shoot},
+		"pope":           {Priority: PriorityKill, Handler: exorcise},
+		"poisoner":       {Priority: PriorityKill, Handler: poison},
+		"bomb_maker":     {Priority: PriorityKill, Handler: plant(ExplosiveBomb)},
+		"dynamite_maker": {Priority: PriorityKill, Handler: plant(ExplosiveDynamite)},
+
+		// Investigations.
+		"informer":        {Priority: PriorityInvestigate, Handler: inform},
+		"hacker":          {Priority: PriorityInvestigate, Handler: revealRole},
+		"grave_digger":    {Priority: PriorityInvestigate, TargetDead: true, Handler: revealRole},
+		"fortune_teller":  {Priority: PriorityInvestigate, TargetDead: true, Handler: revealRole},
+		"sherlock_holmes": {Priority: PriorityInvestigate, Handler: deduce},
+
+		// Transformations.
+		"negotiator":       {Priority: PriorityTransform, Handler: negotiate},
+		"adopted_daughter": {Priority: PriorityTransform, Handler: adopt},
+		"adopted_son":      {Priority: PriorityTransform, Handler: inherit},
+		"thief":            {Priority: PriorityTransform, Handler: steal},
+		"thousand_faces":   {Priority: PriorityTransform, TargetDead: true, Handler: assumeRole},
+	} {
+		RegisterAbility(code, effect)
+	}
+}
+
+func blockTarget(r *Resolution, action AbilityAction) {
+	r.Block(action.TargetID)
+}
+
+// intoxicate blocks the target now and keeps their abilities suppressed through the next night.
+func intoxicate(r *Resolution, action AbilityAction) {
+	r.Block(action.TargetID)
+	r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectIntoxicated, Source: action.UserID, Day: r.State.DayCount, Until: r.State.DayCount + 1})
+}
+
+// disable removes the target's abilities for the rest of the game.
+func disable(r *Resolution, action AbilityAction) {
+	r.Block(action.TargetID)
+	r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectDisabled, Source: action.UserID, Day: r.State.DayCount})
+}
+
+// protect shields the target and cures any poison.
+func protect(r *Resolution, action AbilityAction) {
+	r.Protect(action.TargetID, r.Boosted(action.UserID))
+	r.State.RemoveEffect(action.TargetID, EffectPoisoned)
+}
+
+func protectMafia(r *Resolution, action AbilityAction) {
+	if target, ok := r.State.Assignments[action.TargetID]; ok && target.Team == "mafia" {
+		r.Protect(action.TargetID, r.Boosted(action.UserID))
+	}
+}
+
+// sacrifice makes the actor take the next mafia shot aimed at the target.
+func sacrifice(r *Resolution, action AbilityAction) {
+	if action.TargetID == action.UserID {
+		return
+	}
+	r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectSacrifice, Source: action.UserID, Day: r.NextDay(), Until: r.NextDay()})
+}
+
+func defendTown(r *Resolution, action AbilityAction) {
+	if target, ok := r.State.Assignments[action.TargetID]; ok && target.Team == "town" {
+		grantVoteImmunity(r, action)
+	}
+}
+
+// grantVoteImmunity spares the target if they are voted out on the current or coming day.
+func grantVoteImmunity(r *Resolution, action AbilityAction) {
+	r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectVoteImmune, Source: action.UserID, Day: r.State.DayCount, Until: r.State.DayCount})
+}
+
+func addNextNightEffect(code string) AbilityHandler {
+	return func(r *Resolution, action AbilityAction) {
+		next := r.State.DayCount + 1
+		r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: code, Source: action.UserID, Day: next, Until: next})
+	}
+}
+
+func addDayEffect(code string) AbilityHandler {
+	return func(r *Resolution, action AbilityAction) {
+		r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: code, Source: action.UserID, Day: r.State.DayCount, Until: r.State.DayCount})
+	}
+}
+
+// disguise makes a mafia member appear as town to investigations.
+func disguise(r *Resolution, action AbilityAction) {
+	if target, ok := r.State.Assignments[action.TargetID]; ok && target.Team == "mafia" {
+		r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectDisguised, Source: action.UserID, Day: r.NextDay(), Until: r.NextDay()})
+	}
+}
+
+// frame makes a town player appear as mafia to investigations.
+func frame(r *Resolution, action AbilityAction) {
+	if target, ok := r.State.Assignments[action.TargetID]; ok && target.Team == "town" {
+		r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectFramed, Source: action.UserID, Day: r.NextDay(), Until: r.NextDay()})
+	}
+}
+
+// predict records the side of the chosen player as Nostradamus' predicted winner.
+func predict(r *Resolution, action AbilityAction) {
+	target, ok := r.State.Assignments[action.TargetID]
+	if !ok {
+		return
+	}
+	if r.State.Predictions == nil {
+		r.State.Predictions = map[uint]string{}
+	}
+	r.State.Predictions[action.UserID] = target.Team
+}
+
+// passive covers abilities that only act through other handlers.
+func passive(*Resolution, AbilityAction) {}
+
+func mafiaShot(r *Resolution, action AbilityAction) {
+	if r.Phase != "night" || !r.UseMafiaShot() {
+		return
+	}
+	r.Attack(Attack{Target: action.TargetID, By: action.UserID, Cause: "shot", Mafia: true, Unstoppable: r.Boosted(action.UserID)})
+}
+
+// detonate kills the target regardless of ordinary protection.
+func detonate(r *Resolution, action AbilityAction) {
+	r.Attack(Attack{Target: action.TargetID, By: action.UserID, Cause: "terrorist", Unstoppable: true})
+}
+
+// assassinate only works on even nights.
+func assassinate(r *Resolution, action AbilityAction) {
+	if r.Phase != "night" || r.State.DayCount%2 != 0 {
+		return
+	}
+	r.Attack(Attack{Target: action.TargetID, By: action.UserID, Cause: "assassinated", Unstoppable: r.Boosted(action.UserID)})
+}
+
+// hunt only eliminates targets outside the town.
+func hunt(r *Resolution, action AbilityAction) {
+	if target, ok := r.State.Assignments[action.TargetID]; ok && target.Team != "town" {
+		r.Attack(Attack{Target: action.TargetID, By: action.UserID, Cause: "hunted", Unstoppable: r.Boosted(action.UserID)})
+	}
+}
+
+func shoot(r *Resolution, action AbilityAction) {
+	r.Attack(Attack{Target: action.TargetID, By: action.UserID, Cause: "shot", Unstoppable: r.Boosted(action.UserID)})
+}
+
+// exorcise eliminates the target only when they are the Devil.
+func exorcise(r *Resolution, action AbilityAction) {
+	if target, ok := r.State.Assignments[action.TargetID]; ok && strings.EqualFold(target.Role, "devil") {
+		r.Attack(Attack{Target: action.TargetID, By: action.UserID, Cause: "exorcised", Unstoppable: true})
+	}
+}
+
+// poison kills the target at the end of the coming day unless a protector cures them.
+func poison(r *Resolution, action AbilityAction) {
+	if !r.State.isAlive(action.TargetID) || r.State.HasEffect(action.TargetID, EffectPoisoned) {
+		return
+	}
+	r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectPoisoned, Source: action.UserID, Day: r.State.DayCount, Until: r.NextDay()})
+}
+
+// plant arms an explosive with a code between 1 and 4 that must be guessed before the coming day ends.
+func plant(kind string) AbilityHandler {
+	return func(r *Resolution, action AbilityAction) {
+		if !r.State.isAlive(action.TargetID) {
+			return
+		}
+		if _, armed := r.State.ArmedExplosive(action.TargetID); armed {
+			return
+		}
+		r.State.Bombs = append(r.State.Bombs, Bomb{
+			Target:    action.TargetID,
+			PlantedBy: action.UserID,
+			Kind:      kind,
+			Code:      strconv.Itoa(r.Pick(4) + 1),
+			Deadline:  r.NextDay(),
+		})
+	}
+}
+
+// inform reveals the exact role only for town players.
+func inform(r *Resolution, action AbilityAction) {
+	target, ok := r.State.Assignments[action.TargetID]
+	r.Investigate(action, ok && target.Team == "town")
+}
+
+func revealRole(r *Resolution, action AbilityAction) {
+	r.Investigate(action, true)
+}
+
+// deduce checks Sherlock's guess of the target's role; a correct guess swaps their sides.
+func deduce(r *Resolution, action AbilityAction) {
+	actor, ok := r.State.Assignments[action.UserID]
+	target, found := r.State.Assignments[action.TargetID]
+	if !ok || !found {
+		return
+	}
+	result := InvestigationResult{Day: r.State.DayCount, Investigator: action.UserID, Target: action.TargetID, Ability: action.Ability}
+	if action.Guess != "" && strings.EqualFold(action.Guess, target.Role) {
+		result.Role, result.Team = target.Role, target.Team
+		actor.Team, target.Team = target.Team, actor.Team
+		r.State.Assignments[action.UserID] = actor
+		r.State.Assignments[action.TargetID] = target
+	}
+	r.State.Investigations = append(r.State.Investigations, result)
+}
+
+// negotiate converts a town player without special abilities to the mafia.
+func negotiate(r *Resolution, action AbilityAction) {
+	target, ok := r.State.Assignments[action.TargetID]
+	if !ok || !target.Alive || target.Team != "town" || len(target.Abilities) > 0 {
+		return
+	}
+	target.Team = "mafia"
+	r.State.Assignments[action.TargetID] = target
+}
+
+// adopt converts a living town player once the mafia has lost a member.
+func adopt(r *Resolution, action AbilityAction) {
+	target, ok := r.State.Assignments[action.TargetID]
+	if !ok || !target.Alive || target.Team != "town" || len(r.State.deadMembers("mafia")) == 0 {
+		return
+	}
+	target.Team = "mafia"
+	r.State.Assignments[action.TargetID] = target
+}
+
+// inherit lets the adopted son take over the role of the last fallen mafia member
+// once two of them are dead.
+func inherit(r *Resolution, action AbilityAction) {
+	fallen := r.State.deadMembers("mafia")
+	if len(fallen) < 2 {
+		return
+	}
+	actor := r.State.Assignments[action.UserID]
+	heir := r.State.Assignments[fallen[len(fallen)-1]]
+	actor.Team = "mafia"
+	actor.Role = heir.Role
+	actor.Abilities = append([]string{}, heir.Abilities...)
+	r.State.Assignments[action.UserID] = actor
+}
+
+// steal moves the target's abilities to the thief.
+func steal(r *Resolution, action AbilityAction) {
+	if action.TargetID == action.UserID {
+		return
+	}
+	actor, ok := r.State.Assignments[action.UserID]
+	target, found := r.State.Assignments[action.TargetID]
+	if !ok || !found || len(target.Abilities) == 0 {
+		return
+	}
+	for _, ability := range target.Abilities {
+		if !containsAbility(actor.Abilities, ability) {
+			actor.Abilities = append(actor.Abilities, ability)
+		}
+	}
+	target.Abilities = []string{}
+	r.State.Assignments[action.UserID] = actor
+	r.State.Assignments[action.TargetID] = target
+	r.State.AddEffect(StatusEffect{Target: action.TargetID, Code: EffectRobbed, Source: action.UserID, Day: r.State.DayCount})
+}
+
+// assumeRole takes over the role, team and abilities of an eliminated player.
+func assumeRole(r *Resolution, action AbilityAction) {
+	actor, ok := r.State.Assignments[action.UserID]
+	target, found := r.State.Assignments[action.TargetID]
+	if !ok || !found || target.Alive {
+		return
+	}
+	actor.Role = target.Role
+	actor.Team = target.Team
+	actor.Abilities = append([]string{}, target.Abilities...)
+	r.State.Assignments[action.UserID] = actor
+}
+
+// deadMembers returns the players of a team in the order they died.
+func (g *GameState) deadMembers(team string) []uint {
+	var dead []uint
+	for _, death := range g.Deaths {
+		if player, ok := g.Assignments[death.UserID]; ok && player.Team == team {
+			dead = append(dead, death.UserID)
+		}
+	}
+	return dead
+}
+
+func shotRank(ability string) int {
+	for i, code := range mafiaShots {
+		if code == ability {
+			return i
+		}
+	}
+	return len(mafiaShots)
+}
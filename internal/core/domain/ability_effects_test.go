@@ -0,0 +1,540 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+// seat describes a player of a test game. Seats are numbered from 1 in order.
+type seat struct {
+	role      string
+	team      string
+	abilities []string
+	dead      bool
+}
+
+func town(role string, abilities ...string) seat {
+	return seat{role: role, team: "town", abilities: abilities}
+}
+
+func mafia(role string, abilities ...string) seat {
+	return seat{role: role, team: "mafia", abilities: abilities}
+}
+
+func dead(s seat) seat {
+	s.dead = true
+	return s
+}
+
+// newTestState seats the players on the given day. Dead seats are recorded as having
+// died in seat order.
+func newTestState(day int, seats ...seat) *GameState {
+	g := NewGameState("night", day)
+	for i, s := range seats {
+		id := uint(i + 1)
+		g.Assignments[id] = PlayerAssignment{Role: s.role, Team: s.team, Abilities: s.abilities, Alive: !s.dead}
+		if s.dead {
+			g.Deaths = append(g.Deaths, DeathRecord{UserID: id, Day: day - 1, Phase: "night", Cause: "shot"})
+		}
+	}
+	return g
+}
+
+// resolve records the actions for the phase in the given order and resolves it, picking
+// the first option whenever the handlers need randomness.
+func resolve(g *GameState, phase string, actions ...AbilityAction) (*Resolution, []uint) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, action := range actions {
+		action.Day, action.Phase, action.Timestamp = g.DayCount, phase, at.Add(time.Duration(i)*time.Second)
+		g.RecordAbility(action)
+	}
+	r := NewResolution(g, phase, func(int) int { return 0 })
+	r.Run()
+	return r, r.Finish()
+}
+
+func use(userID uint, ability string, targetID uint) AbilityAction {
+	return AbilityAction{UserID: userID, Ability: ability, TargetID: targetID}
+}
+
+func sameIDs(got, want []uint) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAbilityEffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		day     int
+		phase   string
+		seats   []seat
+		setup   func(g *GameState)
+		actions []AbilityAction
+		check   func(t *testing.T, g *GameState, r *Resolution, killed []uint)
+	}{
+		{
+			name:    "kidnapper blocks the mafia shot",
+			seats:   []seat{town("kidnapper", "kidnapper"), mafia("godfather", "godfather"), town("citizen")},
+			actions: []AbilityAction{use(2, "godfather", 3), use(1, "kidnapper", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				if len(killed) != 0 || !sameIDs(r.Blocked, []uint{2}) {
+					t.Fatalf("killed %v, blocked %v; want nobody killed and 2 blocked", killed, r.Blocked)
+				}
+			},
+		},
+		{
+			name:    "saqi keeps the target intoxicated through the next night",
+			seats:   []seat{mafia("saqi", "saqi"), town("protector", "protector"), town("citizen")},
+			actions: []AbilityAction{use(1, "saqi", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, _ []uint) {
+				if !r.IsBlocked(2) {
+					t.Fatal("target not blocked tonight")
+				}
+				g.DayCount++
+				if !g.HasEffect(2, EffectIntoxicated) {
+					t.Fatal("target sober the next night")
+				}
+				g.DayCount++
+				if g.HasEffect(2, EffectIntoxicated) {
+					t.Fatal("intoxication outlasted the next night")
+				}
+			},
+		},
+		{
+			name:    "clumsy hand disables the target for good",
+			seats:   []seat{mafia("clumsy_hand", "clumsy_hand"), town("ranger", "ranger")},
+			actions: []AbilityAction{use(1, "clumsy_hand", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, _ []uint) {
+				g.DayCount += 10
+				if !r.IsBlocked(2) {
+					t.Fatal("target recovered from being disabled")
+				}
+			},
+		},
+		{
+			name:    "protector saves the mafia target",
+			seats:   []seat{town("protector", "protector"), mafia("godfather", "godfather"), town("citizen")},
+			actions: []AbilityAction{use(2, "godfather", 3), use(1, "protector", 3)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				if len(killed) != 0 || !sameIDs(r.Saved, []uint{3}) {
+					t.Fatalf("killed %v, saved %v; want 3 saved", killed, r.Saved)
+				}
+			},
+		},
+		{
+			name:  "boosted shot goes through protection",
+			seats: []seat{town("protector", "protector"), mafia("godfather", "godfather"), town("citizen")},
+			setup: func(g *GameState) {
+				g.AddEffect(StatusEffect{Target: 2, Code: EffectBoosted, Day: g.DayCount, Until: g.DayCount})
+			},
+			actions: []AbilityAction{use(2, "godfather", 3), use(1, "protector", 3)},
+			check:   wantKilled(3),
+		},
+		{
+			name:    "dr lecter only protects the mafia",
+			seats:   []seat{mafia("dr_lecter", "dr_lecter"), mafia("godfather", "godfather"), town("citizen")},
+			actions: []AbilityAction{use(1, "dr_lecter", 3), use(2, "godfather", 3)},
+			check:   wantKilled(3),
+		},
+		{
+			name:    "self sacrificing takes the shot in the target's place",
+			seats:   []seat{town("self_sacrificing", "self_sacrificing"), mafia("godfather", "godfather"), town("citizen")},
+			actions: []AbilityAction{use(1, "self_sacrificing", 3), use(2, "godfather", 3)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				if !sameIDs(killed, []uint{1}) || !sameIDs(r.Saved, []uint{3}) {
+					t.Fatalf("killed %v, saved %v; want 1 killed for 3", killed, r.Saved)
+				}
+				if cause := g.Deaths[0].Cause; cause != "sacrificed" {
+					t.Fatalf("cause %q, want sacrificed", cause)
+				}
+			},
+		},
+		{
+			name:    "lawyer defends town players from the vote",
+			seats:   []seat{town("lawyer", "lawyer"), town("citizen")},
+			actions: []AbilityAction{use(1, "lawyer", 2)},
+			check:   wantEffect(2, EffectVoteImmune, true),
+		},
+		{
+			name:    "lawyer does not defend the mafia",
+			seats:   []seat{town("lawyer", "lawyer"), mafia("godfather", "godfather")},
+			actions: []AbilityAction{use(1, "lawyer", 2)},
+			check:   wantEffect(2, EffectVoteImmune, false),
+		},
+		{
+			name:    "gambler boosts the target the next night",
+			seats:   []seat{town("gambler", "gambler"), town("ranger", "ranger")},
+			actions: []AbilityAction{use(1, "gambler", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, _ []uint) {
+				if r.Boosted(2) {
+					t.Fatal("boost applied tonight")
+				}
+				g.DayCount++
+				if !r.Boosted(2) {
+					t.Fatal("boost missing the next night")
+				}
+			},
+		},
+		{
+			name:    "imposter makes a mafia member look like town",
+			seats:   []seat{mafia("imposter", "imposter"), mafia("godfather", "godfather"), town("hacker", "hacker")},
+			actions: []AbilityAction{use(3, "hacker", 2), use(1, "imposter", 2)},
+			check:   wantInvestigation("town", ""),
+		},
+		{
+			name:    "mercenary makes a town player look like mafia",
+			seats:   []seat{mafia("mercenary", "mercenary"), town("citizen"), town("hacker", "hacker")},
+			actions: []AbilityAction{use(3, "hacker", 2), use(1, "mercenary", 2)},
+			check:   wantInvestigation("mafia", ""),
+		},
+		{
+			name:    "natasha silences the target for the coming day",
+			seats:   []seat{mafia("natasha", "natasha"), town("citizen")},
+			actions: []AbilityAction{use(1, "natasha", 2)},
+			check:   wantEffect(2, EffectSilenced, true),
+		},
+		{
+			name:    "nostradamus predicts the side of the chosen player",
+			seats:   []seat{town("nostradamus", "nostradamus"), mafia("godfather", "godfather")},
+			actions: []AbilityAction{use(1, "nostradamus", 2)},
+			check: func(t *testing.T, g *GameState, _ *Resolution, _ []uint) {
+				if got := g.Predictions[1]; got != "mafia" {
+					t.Fatalf("prediction %q, want mafia", got)
+				}
+			},
+		},
+		{
+			name:    "the mafia has one shot a night",
+			seats:   []seat{mafia("godfather", "godfather"), mafia("simple_mafia", "simple_mafia"), town("citizen"), town("citizen")},
+			actions: []AbilityAction{use(1, "godfather", 3), use(2, "simple_mafia", 4)},
+			check:   wantKilled(3),
+		},
+		{
+			name:    "extra shots from a fallen mistress are spent",
+			seats:   []seat{mafia("godfather", "godfather"), mafia("simple_mafia", "simple_mafia"), town("citizen"), town("citizen")},
+			setup:   func(g *GameState) { g.MafiaShots = 1 },
+			actions: []AbilityAction{use(1, "godfather", 3), use(2, "simple_mafia", 4)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantKilled(3, 4)(t, g, r, killed)
+				if g.MafiaShots != 0 {
+					t.Fatalf("%d extra shots left, want 0", g.MafiaShots)
+				}
+			},
+		},
+		{
+			name:    "terrorist ignores protection",
+			seats:   []seat{town("protector", "protector"), mafia("terrorist", "terrorist"), town("citizen")},
+			actions: []AbilityAction{use(1, "protector", 3), use(2, "terrorist", 3)},
+			check:   wantKilled(3),
+		},
+		{
+			name:    "assassin fails on odd nights",
+			day:     1,
+			seats:   []seat{mafia("assassin", "assassin"), town("citizen")},
+			actions: []AbilityAction{use(1, "assassin", 2)},
+			check:   wantKilled(),
+		},
+		{
+			name:    "assassin kills on even nights",
+			day:     2,
+			seats:   []seat{mafia("assassin", "assassin"), town("citizen")},
+			actions: []AbilityAction{use(1, "assassin", 2)},
+			check:   wantKilled(2),
+		},
+		{
+			name:    "assassin only acts at night",
+			day:     2,
+			phase:   "day",
+			seats:   []seat{mafia("assassin", "assassin"), town("citizen")},
+			actions: []AbilityAction{use(1, "assassin", 2)},
+			check:   wantKilled(),
+		},
+		{
+			name:    "hunter spares the town",
+			seats:   []seat{town("hunter", "hunter"), town("citizen")},
+			actions: []AbilityAction{use(1, "hunter", 2)},
+			check:   wantKilled(),
+		},
+		{
+			name:    "hunter kills outside the town",
+			seats:   []seat{town("hunter", "hunter"), mafia("godfather", "godfather")},
+			actions: []AbilityAction{use(1, "hunter", 2)},
+			check:   wantKilled(2),
+		},
+		{
+			name:    "pope only exorcises the devil",
+			seats:   []seat{town("pope", "pope"), mafia("godfather", "godfather"), {role: "Devil", team: "independent"}},
+			actions: []AbilityAction{use(1, "pope", 2), use(1, "pope", 3)},
+			check:   wantKilled(3),
+		},
+		{
+			name:    "ranger's shield stops the first mafia shot",
+			seats:   []seat{mafia("godfather", "godfather"), town("ranger", "ranger")},
+			actions: []AbilityAction{use(1, "godfather", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantKilled()(t, g, r, killed)
+				wantEffect(2, EffectShieldUsed, true)(t, g, r, killed)
+			},
+		},
+		{
+			name:  "ranger's shield only works once",
+			seats: []seat{mafia("godfather", "godfather"), town("ranger", "ranger")},
+			setup: func(g *GameState) {
+				g.AddEffect(StatusEffect{Target: 2, Code: EffectShieldUsed, Day: g.DayCount - 1})
+			},
+			actions: []AbilityAction{use(1, "godfather", 2)},
+			check:   wantKilled(2),
+		},
+		{
+			name:    "shooting the innocent exposes the shooter",
+			seats:   []seat{mafia("godfather", "godfather"), town("innocent", "innocent")},
+			actions: []AbilityAction{use(1, "godfather", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantKilled(2)(t, g, r, killed)
+				if !sameIDs(r.Exposed, []uint{1}) {
+					t.Fatalf("exposed %v, want 1", r.Exposed)
+				}
+			},
+		},
+		{
+			name:    "poison kills at the end of the coming day",
+			seats:   []seat{mafia("poisoner", "poisoner"), town("citizen")},
+			actions: []AbilityAction{use(1, "poisoner", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantKilled()(t, g, r, killed)
+				if died := g.EndDay(); !sameIDs(died, []uint{2}) {
+					t.Fatalf("end of day killed %v, want 2", died)
+				}
+			},
+		},
+		{
+			name:  "the protector cures poison before the day ends",
+			phase: "day",
+			seats: []seat{mafia("poisoner", "poisoner"), town("citizen"), town("protector", "protector")},
+			setup: func(g *GameState) {
+				g.AddEffect(StatusEffect{Target: 2, Code: EffectPoisoned, Source: 1, Day: g.DayCount, Until: g.DayCount})
+			},
+			actions: []AbilityAction{use(3, "protector", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantEffect(2, EffectPoisoned, false)(t, g, r, killed)
+				if died := g.EndDay(); len(died) != 0 {
+					t.Fatalf("end of day killed %v, want nobody", died)
+				}
+			},
+		},
+		{
+			name:  "poison does not stack",
+			seats: []seat{mafia("poisoner", "poisoner"), town("citizen")},
+			setup: func(g *GameState) {
+				g.AddEffect(StatusEffect{Target: 2, Code: EffectPoisoned, Source: 1, Day: g.DayCount, Until: g.DayCount})
+			},
+			actions: []AbilityAction{use(1, "poisoner", 2)},
+			check: func(t *testing.T, g *GameState, _ *Resolution, _ []uint) {
+				if len(g.Effects) != 1 {
+					t.Fatalf("%d effects, want the one poison", len(g.Effects))
+				}
+			},
+		},
+		{
+			name:    "bomb maker arms a bomb due the coming day",
+			seats:   []seat{mafia("bomb_maker", "bomb_maker"), town("citizen")},
+			actions: []AbilityAction{use(1, "bomb_maker", 2), use(1, "bomb_maker", 2)},
+			check: func(t *testing.T, g *GameState, _ *Resolution, _ []uint) {
+				if len(g.Bombs) != 1 {
+					t.Fatalf("%d bombs armed, want 1", len(g.Bombs))
+				}
+				want := Bomb{Target: 2, PlantedBy: 1, Kind: ExplosiveBomb, Code: "1", Deadline: g.DayCount}
+				if g.Bombs[0] != want {
+					t.Fatalf("bomb %+v, want %+v", g.Bombs[0], want)
+				}
+			},
+		},
+		{
+			name:    "informer learns the role of town players",
+			seats:   []seat{town("informer", "informer"), town("ranger", "ranger")},
+			actions: []AbilityAction{use(1, "informer", 2)},
+			check:   wantInvestigation("town", "ranger"),
+		},
+		{
+			name:    "informer only learns the team of the mafia",
+			seats:   []seat{town("informer", "informer"), mafia("godfather", "godfather")},
+			actions: []AbilityAction{use(1, "informer", 2)},
+			check:   wantInvestigation("mafia", ""),
+		},
+		{
+			name:    "grave digger reads the dead",
+			seats:   []seat{town("grave_digger", "grave_digger"), dead(mafia("nato", "nato"))},
+			actions: []AbilityAction{use(1, "grave_digger", 2)},
+			check:   wantInvestigation("mafia", "nato"),
+		},
+		{
+			name:    "sherlock's correct guess swaps teams",
+			seats:   []seat{town("sherlock_holmes", "sherlock_holmes"), mafia("godfather", "godfather")},
+			actions: []AbilityAction{{UserID: 1, Ability: "sherlock_holmes", TargetID: 2, Guess: "Godfather"}},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantInvestigation("mafia", "godfather")(t, g, r, killed)
+				wantTeams(map[uint]string{1: "mafia", 2: "town"})(t, g, r, killed)
+			},
+		},
+		{
+			name:    "sherlock's wrong guess changes nothing",
+			seats:   []seat{town("sherlock_holmes", "sherlock_holmes"), mafia("godfather", "godfather")},
+			actions: []AbilityAction{{UserID: 1, Ability: "sherlock_holmes", TargetID: 2, Guess: "nato"}},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantInvestigation("", "")(t, g, r, killed)
+				wantTeams(map[uint]string{1: "town", 2: "mafia"})(t, g, r, killed)
+			},
+		},
+		{
+			name:    "negotiator converts a plain citizen",
+			seats:   []seat{mafia("negotiator", "negotiator"), town("citizen")},
+			actions: []AbilityAction{use(1, "negotiator", 2)},
+			check:   wantTeams(map[uint]string{2: "mafia"}),
+		},
+		{
+			name:    "negotiator cannot convert players with abilities",
+			seats:   []seat{mafia("negotiator", "negotiator"), town("ranger", "ranger")},
+			actions: []AbilityAction{use(1, "negotiator", 2)},
+			check:   wantTeams(map[uint]string{2: "town"}),
+		},
+		{
+			name:    "adopted daughter waits for the mafia to lose a member",
+			seats:   []seat{mafia("adopted_daughter", "adopted_daughter"), town("citizen")},
+			actions: []AbilityAction{use(1, "adopted_daughter", 2)},
+			check:   wantTeams(map[uint]string{2: "town"}),
+		},
+		{
+			name:    "adopted daughter converts once a mafia member fell",
+			seats:   []seat{mafia("adopted_daughter", "adopted_daughter"), town("citizen"), dead(mafia("godfather", "godfather"))},
+			actions: []AbilityAction{use(1, "adopted_daughter", 2)},
+			check:   wantTeams(map[uint]string{2: "mafia"}),
+		},
+		{
+			name:    "adopted son waits for two mafia members to fall",
+			seats:   []seat{town("adopted_son", "adopted_son"), dead(mafia("godfather", "godfather"))},
+			actions: []AbilityAction{use(1, "adopted_son", 1)},
+			check:   wantTeams(map[uint]string{1: "town"}),
+		},
+		{
+			name:    "adopted son inherits the last fallen mafia member",
+			seats:   []seat{town("adopted_son", "adopted_son"), dead(mafia("godfather", "godfather")), dead(mafia("nato", "nato"))},
+			actions: []AbilityAction{use(1, "adopted_son", 1)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				wantTeams(map[uint]string{1: "mafia"})(t, g, r, killed)
+				actor := g.Assignments[1]
+				if actor.Role != "nato" || !sameAbilities(actor.Abilities, []string{"nato"}) {
+					t.Fatalf("inherited %q %v, want nato", actor.Role, actor.Abilities)
+				}
+			},
+		},
+		{
+			name:    "thief takes the target's abilities",
+			seats:   []seat{town("thief", "thief"), mafia("godfather", "godfather")},
+			actions: []AbilityAction{use(1, "thief", 2)},
+			check: func(t *testing.T, g *GameState, r *Resolution, killed []uint) {
+				if got := g.Assignments[1].Abilities; !sameAbilities(got, []string{"thief", "godfather"}) {
+					t.Fatalf("thief has %v", got)
+				}
+				if got := g.Assignments[2].Abilities; len(got) != 0 {
+					t.Fatalf("target kept %v", got)
+				}
+				wantEffect(2, EffectRobbed, true)(t, g, r, killed)
+			},
+		},
+		{
+			name:    "thousand faces assumes an eliminated player's role",
+			seats:   []seat{town("thousand_faces", "thousand_faces"), dead(mafia("nato", "nato"))},
+			actions: []AbilityAction{use(1, "thousand_faces", 2)},
+			check: func(t *testing.T, g *GameState, _ *Resolution, _ []uint) {
+				actor := g.Assignments[1]
+				if actor.Role != "nato" || actor.Team != "mafia" || !sameAbilities(actor.Abilities, []string{"nato"}) {
+					t.Fatalf("actor became %+v", actor)
+				}
+			},
+		},
+		{
+			name:    "thousand faces cannot copy the living",
+			seats:   []seat{town("thousand_faces", "thousand_faces"), mafia("nato", "nato")},
+			actions: []AbilityAction{use(1, "thousand_faces", 2)},
+			check:   wantTeams(map[uint]string{1: "town"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, phase := tt.day, tt.phase
+			if day == 0 {
+				day = 3
+			}
+			if phase == "" {
+				phase = "night"
+			}
+			g := newTestState(day, tt.seats...)
+			if tt.setup != nil {
+				tt.setup(g)
+			}
+			r, killed := resolve(g, phase, tt.actions...)
+			tt.check(t, g, r, killed)
+		})
+	}
+}
+
+func wantKilled(ids ...uint) func(*testing.T, *GameState, *Resolution, []uint) {
+	return func(t *testing.T, _ *GameState, _ *Resolution, killed []uint) {
+		t.Helper()
+		if !sameIDs(killed, ids) {
+			t.Fatalf("killed %v, want %v", killed, ids)
+		}
+	}
+}
+
+func wantEffect(target uint, code string, want bool) func(*testing.T, *GameState, *Resolution, []uint) {
+	return func(t *testing.T, g *GameState, _ *Resolution, _ []uint) {
+		t.Helper()
+		if got := g.HasEffect(target, code); got != want {
+			t.Fatalf("player %d %s: %v, want %v", target, code, got, want)
+		}
+	}
+}
+
+func wantInvestigation(team, role string) func(*testing.T, *GameState, *Resolution, []uint) {
+	return func(t *testing.T, g *GameState, _ *Resolution, _ []uint) {
+		t.Helper()
+		if len(g.Investigations) != 1 {
+			t.Fatalf("%d investigation results, want 1", len(g.Investigations))
+		}
+		if got := g.Investigations[0]; got.Team != team || got.Role != role {
+			t.Fatalf("learned %q %q, want %q %q", got.Team, got.Role, team, role)
+		}
+	}
+}
+
+func wantTeams(teams map[uint]string) func(*testing.T, *GameState, *Resolution, []uint) {
+	return func(t *testing.T, g *GameState, _ *Resolution, _ []uint) {
+		t.Helper()
+		for id, team := range teams {
+			if got := g.Assignments[id].Team; got != team {
+				t.Fatalf("player %d is %s, want %s", id, got, team)
+			}
+		}
+	}
+}
+
+func sameAbilities(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
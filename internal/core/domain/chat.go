@@ -0,0 +1,153 @@
+package domain
+
+import "time"
+
+// Chat channels of a room. Everyone talks in the room channel, the living mafia among
+// themselves at night, and the dead with the spectators.
+const (
+	ChatRoom = "room"
+	ChatTeam = "team"
+	ChatDead = "dead"
+)
+
+// Chat limits.
+const (
+	MaxChatLength   = 500
+	DefaultChatPage = 50
+	MaxChatPage     = 100
+)
+
+// ChatMessage is a chat line posted to one of a room's channels.
+type ChatMessage struct {
+	ID      uint      `json:"id" gorm:"primaryKey"`
+	RoomID  uint      `json:"room_id" gorm:"index:idx_chat_messages_room_channel;not null"`
+	Channel string    `json:"channel" gorm:"index:idx_chat_messages_room_channel;not null"`
+	UserID  uint      `json:"user_id" gorm:"not null"`
+	Text    string    `json:"text"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// ChatQuery selects a page of a room's history, newest first. Before is the ID of the
+// oldest message already seen, zero for the latest page. Messages from Hidden senders
+// are left out.
+type ChatQuery struct {
+	RoomID   uint
+	Channels []string
+	Before   uint
+	Limit    int
+	Hidden   []uint
+}
+
+// UserBlock hides the chat of BlockedID from UserID.
+type UserBlock struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	BlockedID uint      `json:"blocked_id" gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserBlocked announces that a user blocked another.
+type UserBlocked struct {
+	UserID   uint `json:"user_id"`
+	TargetID uint `json:"target_id"`
+}
+
+// UserUnblocked announces that a user lifted a block.
+type UserUnblocked struct {
+	UserID   uint `json:"user_id"`
+	TargetID uint `json:"target_id"`
+}
+
+// ChatGrant lists the channels a member may post to and read.
+type ChatGrant struct {
+	Posts []string `json:"posts"`
+	Reads []string `json:"reads"`
+}
+
+// ChatRules say who may post to and read each channel of a room.
+type ChatRules struct {
+	RoomID uint               `json:"room_id"`
+	Grants map[uint]ChatGrant `json:"grants"`
+}
+
+// ChatRulesFor derives the chat rules of a room from its game, in step with its voice
+// rules. Before the game starts everyone chats in the room channel; once it has
+// finished every channel can be read. During the game:
+//   - staff who are not playing narrate and may post to and read every channel;
+//   - the dead and spectators post to the dead channel and read it and the room;
+//   - at night the living mafia post to the team channel and everyone else is quiet;
+//   - during the day the living post to the room, except those silenced for the day.
+func ChatRulesFor(room *GameRoom, state *GameState) ChatRules {
+	rules := ChatRules{RoomID: room.ID, Grants: map[uint]ChatGrant{}}
+	members := append([]uint{room.HostID}, room.CoHostIDs...)
+	members = append(members, room.ModeratorIDs...)
+	members = append(members, room.SpectatorIDs...)
+	for _, player := range room.Players {
+		members = append(members, player.ID)
+	}
+
+	playing := state != nil && room.Status == "playing"
+	all := []string{ChatRoom, ChatTeam, ChatDead}
+	for _, userID := range members {
+		switch {
+		case userID == 0:
+			continue
+		case playing:
+			rules.Grants[userID] = state.chatGrant(room, userID)
+		case room.Status == "finished":
+			rules.Grants[userID] = ChatGrant{Posts: []string{ChatRoom}, Reads: all}
+		default:
+			rules.Grants[userID] = ChatGrant{Posts: []string{ChatRoom}, Reads: []string{ChatRoom}}
+		}
+	}
+	return rules
+}
+
+func (g *GameState) chatGrant(room *GameRoom, userID uint) ChatGrant {
+	player, playing := g.Assignments[userID]
+	switch {
+	case !playing && room.Can(userID, PermAdvance):
+		all := []string{ChatRoom, ChatTeam, ChatDead}
+		return ChatGrant{Posts: all, Reads: all}
+	case !playing, !player.Alive:
+		return ChatGrant{Posts: []string{ChatDead}, Reads: []string{ChatRoom, ChatDead}}
+	case g.Phase == "night" && player.Team == "mafia":
+		return ChatGrant{Posts: []string{ChatTeam}, Reads: []string{ChatRoom, ChatTeam}}
+	case player.Team == "mafia" && g.HasEffect(userID, EffectSilenced):
+		return ChatGrant{Reads: []string{ChatRoom, ChatTeam}}
+	case player.Team == "mafia":
+		return ChatGrant{Posts: []string{ChatRoom}, Reads: []string{ChatRoom, ChatTeam}}
+	case g.Phase == "night", g.HasEffect(userID, EffectSilenced):
+		return ChatGrant{Reads: []string{ChatRoom}}
+	}
+	return ChatGrant{Posts: []string{ChatRoom}, Reads: []string{ChatRoom}}
+}
+
+// CanPost reports whether the member may post to the channel.
+func (r ChatRules) CanPost(userID uint, channel string) bool {
+	return containsChannel(r.Grants[userID].Posts, channel)
+}
+
+// CanRead reports whether the member may read the channel.
+func (r ChatRules) CanRead(userID uint, channel string) bool {
+	return containsChannel(r.Grants[userID].Reads, channel)
+}
+
+// Readers lists the members who may read the channel.
+func (r ChatRules) Readers(channel string) []uint {
+	var readers []uint
+	for userID, grant := range r.Grants {
+		if containsChannel(grant.Reads, channel) {
+			readers = append(readers, userID)
+		}
+	}
+	return readers
+}
+
+func containsChannel(channels []string, channel string) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestChatRulesFor(t *testing.T) {
+	const narrator, spectator = 100, 200
+	room := &GameRoom{
+		ID:           7,
+		HostID:       narrator,
+		Status:       "playing",
+		SpectatorIDs: []uint{spectator},
+		Players:      []User{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}},
+	}
+	newState := func(phase string) *GameState {
+		g := newTestState(2, mafia("godfather"), mafia("simple_mafia"), town("citizen"), dead(town("citizen")), town("citizen"))
+		g.Phase = phase
+		g.AddEffect(StatusEffect{Target: 2, Code: EffectSilenced, Day: 2, Until: 2})
+		g.AddEffect(StatusEffect{Target: 5, Code: EffectSilenced, Day: 2, Until: 2})
+		return g
+	}
+
+	all := []string{ChatRoom, ChatTeam, ChatDead}
+	narrating := ChatGrant{Posts: all, Reads: all}
+	departed := ChatGrant{Posts: []string{ChatDead}, Reads: []string{ChatRoom, ChatDead}}
+	quiet := ChatGrant{Reads: []string{ChatRoom}}
+
+	tests := []struct {
+		name   string
+		status string
+		phase  string
+		grants map[uint]ChatGrant
+	}{
+		{
+			name:   "night",
+			status: "playing",
+			phase:  "night",
+			grants: map[uint]ChatGrant{
+				narrator:  narrating,
+				spectator: departed,
+				1:         {Posts: []string{ChatTeam}, Reads: []string{ChatRoom, ChatTeam}},
+				2:         {Posts: []string{ChatTeam}, Reads: []string{ChatRoom, ChatTeam}},
+				3:         quiet,
+				4:         departed,
+				5:         quiet,
+			},
+		},
+		{
+			name:   "day",
+			status: "playing",
+			phase:  "day",
+			grants: map[uint]ChatGrant{
+				narrator:  narrating,
+				spectator: departed,
+				1:         {Posts: []string{ChatRoom}, Reads: []string{ChatRoom, ChatTeam}},
+				2:         {Reads: []string{ChatRoom, ChatTeam}},
+				3:         {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}},
+				4:         departed,
+				5:         quiet,
+			},
+		},
+		{
+			name:   "before the game",
+			status: "waiting",
+			grants: map[uint]ChatGrant{
+				narrator: {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}}, spectator: {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}},
+				1: {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}}, 2: {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}},
+				3: {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}}, 4: {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}},
+				5: {Posts: []string{ChatRoom}, Reads: []string{ChatRoom}},
+			},
+		},
+		{
+			name:   "after the game",
+			status: "finished",
+			grants: map[uint]ChatGrant{
+				narrator: {Posts: []string{ChatRoom}, Reads: all}, spectator: {Posts: []string{ChatRoom}, Reads: all},
+				1: {Posts: []string{ChatRoom}, Reads: all}, 2: {Posts: []string{ChatRoom}, Reads: all},
+				3: {Posts: []string{ChatRoom}, Reads: all}, 4: {Posts: []string{ChatRoom}, Reads: all},
+				5: {Posts: []string{ChatRoom}, Reads: all},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := *room
+			r.Status = tt.status
+			var state *GameState
+			if tt.phase != "" {
+				state = newState(tt.phase)
+			}
+			rules := ChatRulesFor(&r, state)
+			if !reflect.DeepEqual(rules.Grants, tt.grants) {
+				t.Fatalf("grants %+v, want %+v", rules.Grants, tt.grants)
+			}
+		})
+	}
+
+	t.Run("only the mafia and the narrator read the team channel", func(t *testing.T) {
+		readers := ChatRulesFor(room, newState("night")).Readers(ChatTeam)
+		sort.Slice(readers, func(i, j int) bool { return readers[i] < readers[j] })
+		if !sameIDs(readers, []uint{1, 2, narrator}) {
+			t.Fatalf("team channel read by %v", readers)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Node identifies an API replica and the base URL other replicas reach it on.
+type Node struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+}
+
+// RoomLease records which replica owns the live state of a room until it expires.
+type RoomLease struct {
+	RoomID    uint      `json:"room_id" gorm:"primaryKey;autoIncrement:false"`
+	Node      string    `json:"node"`
+	Address   string    `json:"address"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
+
+// RoomOwnedError reports that another replica holds the room's lease, so commands for
+// the room have to be sent there.
+type RoomOwnedError struct {
+	Lease RoomLease
+}
+
+func (e *RoomOwnedError) Error() string {
+	return fmt.Sprintf("room %d is served by node %s", e.Lease.RoomID, e.Lease.Node)
+}
@@ -0,0 +1,218 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Kinds of speaking turn.
+const (
+	TurnNarrator   = "narrator"
+	TurnDiscussion = "discussion"
+	TurnDefense    = "defense"
+	TurnChallenge  = "challenge"
+)
+
+// Turn lengths used when the room settings leave them unset.
+const (
+	DefaultTurnSeconds      = 60
+	DefaultDefenseSeconds   = 45
+	DefaultChallengeSeconds = 30
+)
+
+// SpeakingSlot is a turn waiting in the speaking queue.
+type SpeakingSlot struct {
+	UserID uint   `json:"user_id"`
+	Kind   string `json:"kind"`
+}
+
+// ChallengeRequest asks the current speaker for a challenge: a short turn taken right
+// after theirs.
+type ChallengeRequest struct {
+	UserID  uint `json:"user_id"`
+	Speaker uint `json:"speaker"`
+}
+
+// ChallengeRequested announces a request to interject after the current speaker.
+type ChallengeRequested struct {
+	RoomID uint `json:"room_id"`
+	ChallengeRequest
+}
+
+// SpeakingQueue orders the speeches of a day phase. The turn being spoken is the game's
+// Turn; Pending holds the ones after it.
+type SpeakingQueue struct {
+	Pending    []SpeakingSlot     `json:"pending"`
+	Requests   []ChallengeRequest `json:"requests,omitempty"`
+	Challenged []uint             `json:"challenged,omitempty"`
+}
+
+// TurnLength returns how long a turn of the given kind lasts in the room.
+func (s RoomSettings) TurnLength(kind string) time.Duration {
+	seconds := s.TurnSeconds
+	switch kind {
+	case TurnDefense:
+		if seconds = s.DefenseSeconds; seconds == 0 {
+			seconds = DefaultDefenseSeconds
+		}
+	case TurnChallenge:
+		if seconds = s.ChallengeSeconds; seconds == 0 {
+			seconds = DefaultChallengeSeconds
+		}
+	default:
+		if seconds == 0 {
+			seconds = DefaultTurnSeconds
+		}
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// OpenDiscussion queues the speeches of the phase that just began and gives the floor
+// to the first speaker. During the day every living player speaks once, starting one
+// seat further each day; in a defense round the accused speak in turn. Nights have no
+// queue.
+func (g *GameState) OpenDiscussion(now time.Time) {
+	g.Turn, g.Speaking = nil, nil
+	var slots []SpeakingSlot
+	switch g.Phase {
+	case "day":
+		var living []uint
+		for _, id := range g.PlayerIDs() {
+			if g.isAlive(id) {
+				living = append(living, id)
+			}
+		}
+		for i := range living {
+			id := living[(i+g.DayCount-1)%len(living)]
+			slots = append(slots, SpeakingSlot{UserID: id, Kind: TurnDiscussion})
+		}
+	case "defense":
+		for _, id := range g.Ballot.Candidates {
+			slots = append(slots, SpeakingSlot{UserID: id, Kind: TurnDefense})
+		}
+	}
+	if len(slots) == 0 {
+		return
+	}
+	g.Speaking = &SpeakingQueue{Pending: slots}
+	g.nextTurn(now)
+}
+
+// ExpireTurn moves on once the current turn has run out, or starts the next queued turn
+// when nobody holds the floor. It reports whether the turn changed.
+func (g *GameState) ExpireTurn(now time.Time) bool {
+	if g.Turn != nil && now.Before(g.Turn.EndsAt) {
+		return false
+	}
+	if g.Turn == nil && (g.Speaking == nil || len(g.Speaking.Pending) == 0) {
+		return false
+	}
+	g.nextTurn(now)
+	return true
+}
+
+// PassTurn lets the speaker give up the rest of their turn.
+func (g *GameState) PassTurn(userID uint, now time.Time) error {
+	if g.Turn == nil || g.Turn.UserID != userID {
+		return errors.New("it is not your turn to speak")
+	}
+	g.nextTurn(now)
+	return nil
+}
+
+// SkipTurn ends the current turn on the narrator's behalf.
+func (g *GameState) SkipTurn(now time.Time) error {
+	if g.Turn == nil {
+		return errors.New("nobody holds the floor")
+	}
+	g.nextTurn(now)
+	return nil
+}
+
+// RequestChallenge asks the current speaker for a challenge. Each player takes at most
+// one challenge a day, and only during discussion turns.
+func (g *GameState) RequestChallenge(userID uint) (ChallengeRequest, error) {
+	if g.Turn == nil || g.Turn.Kind != TurnDiscussion || g.Speaking == nil {
+		return ChallengeRequest{}, errors.New("challenges can only be requested during a discussion turn")
+	}
+	if !g.isAlive(userID) || g.HasEffect(userID, EffectSilenced) {
+		return ChallengeRequest{}, errors.New("player cannot speak today")
+	}
+	if g.Turn.UserID == userID {
+		return ChallengeRequest{}, errors.New("cannot challenge your own turn")
+	}
+	if containsID(g.Speaking.Challenged, userID) {
+		return ChallengeRequest{}, errors.New("player already took a challenge today")
+	}
+	request := ChallengeRequest{UserID: userID, Speaker: g.Turn.UserID}
+	for _, existing := range g.Speaking.Requests {
+		if existing == request {
+			return ChallengeRequest{}, errors.New("challenge already requested")
+		}
+	}
+	g.Speaking.Requests = append(g.Speaking.Requests, request)
+	return request, nil
+}
+
+// AcceptChallenge lets the speaker grant one of the challenges requested during their
+// turn. The challenger speaks as soon as the turn ends; the other requests lapse.
+func (g *GameState) AcceptChallenge(speakerID, challengerID uint) error {
+	if g.Turn == nil || g.Turn.UserID != speakerID || g.Speaking == nil {
+		return errors.New("it is not your turn to speak")
+	}
+	request := ChallengeRequest{UserID: challengerID, Speaker: speakerID}
+	found := false
+	for _, existing := range g.Speaking.Requests {
+		found = found || existing == request
+	}
+	if !found {
+		return errors.New("no such challenge request")
+	}
+	g.Speaking.Pending = append([]SpeakingSlot{{UserID: challengerID, Kind: TurnChallenge}}, g.Speaking.Pending...)
+	g.Speaking.Challenged = append(g.Speaking.Challenged, challengerID)
+	g.Speaking.Requests = nil
+	return nil
+}
+
+// Queue returns a copy of the turns waiting after the current one.
+func (g *GameState) Queue() []SpeakingSlot {
+	if g.Speaking == nil {
+		return nil
+	}
+	return append([]SpeakingSlot(nil), g.Speaking.Pending...)
+}
+
+// SpeakingLeft returns how long the speaking queue still runs: the rest of the current
+// turn and the full length of every queued one.
+func (g *GameState) SpeakingLeft(now time.Time) time.Duration {
+	var left time.Duration
+	if g.Turn != nil && g.Turn.EndsAt.After(now) {
+		left = g.Turn.EndsAt.Sub(now)
+	}
+	if g.Speaking != nil {
+		for _, slot := range g.Speaking.Pending {
+			left += g.Settings.TurnLength(slot.Kind)
+		}
+	}
+	return left
+}
+
+// nextTurn gives the floor to the next queued player who can still speak, or opens the
+// table once the queue is empty.
+func (g *GameState) nextTurn(now time.Time) {
+	g.Turn = nil
+	if g.Speaking == nil {
+		return
+	}
+	g.Speaking.Requests = nil
+	for len(g.Speaking.Pending) > 0 {
+		slot := g.Speaking.Pending[0]
+		g.Speaking.Pending = g.Speaking.Pending[1:]
+		if !g.isAlive(slot.UserID) || g.HasEffect(slot.UserID, EffectSilenced) {
+			continue
+		}
+		g.Turn = &SpeakingTurn{UserID: slot.UserID, Kind: slot.Kind, EndsAt: now.Add(g.Settings.TurnLength(slot.Kind))}
+		return
+	}
+	g.Speaking = nil
+}
@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// speakers lists who holds the floor now and who is queued after them.
+func speakers(g *GameState) []uint {
+	var ids []uint
+	if g.Turn != nil {
+		ids = append(ids, g.Turn.UserID)
+	}
+	for _, slot := range g.Queue() {
+		ids = append(ids, slot.UserID)
+	}
+	return ids
+}
+
+func TestOpenDiscussion(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seats := []seat{town("citizen"), dead(town("citizen")), town("citizen"), town("citizen")}
+	tests := []struct {
+		name   string
+		phase  string
+		day    int
+		setup  func(g *GameState)
+		want   []uint
+		kind   string
+		length time.Duration
+	}{
+		{name: "the living speak in seat order on the first day", phase: "day", day: 1, want: []uint{1, 3, 4}, kind: TurnDiscussion, length: DefaultTurnSeconds * time.Second},
+		{name: "each day starts one seat further", phase: "day", day: 2, want: []uint{3, 4, 1}, kind: TurnDiscussion, length: DefaultTurnSeconds * time.Second},
+		{
+			name:   "the silenced lose their turn",
+			phase:  "day",
+			day:    1,
+			setup:  func(g *GameState) { g.AddEffect(StatusEffect{Target: 1, Code: EffectSilenced, Day: 1, Until: 1}) },
+			want:   []uint{3, 4},
+			kind:   TurnDiscussion,
+			length: DefaultTurnSeconds * time.Second,
+		},
+		{
+			name:   "the accused defend themselves",
+			phase:  "defense",
+			day:    1,
+			setup:  func(g *GameState) { g.Ballot = Ballot{Round: 1, Candidates: []uint{4, 3}} },
+			want:   []uint{4, 3},
+			kind:   TurnDefense,
+			length: DefaultDefenseSeconds * time.Second,
+		},
+		{
+			name:   "room settings set the turn length",
+			phase:  "day",
+			day:    1,
+			setup:  func(g *GameState) { g.Settings.TurnSeconds = 90 },
+			want:   []uint{1, 3, 4},
+			kind:   TurnDiscussion,
+			length: 90 * time.Second,
+		},
+		{name: "nights have no queue", phase: "night", day: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestState(tt.day, seats...)
+			g.Phase = tt.phase
+			if tt.setup != nil {
+				tt.setup(g)
+			}
+			g.OpenDiscussion(at)
+			if got := speakers(g); !sameIDs(got, tt.want) {
+				t.Fatalf("speakers %v, want %v", got, tt.want)
+			}
+			if tt.want == nil {
+				return
+			}
+			if g.Turn.Kind != tt.kind || !g.Turn.EndsAt.Equal(at.Add(tt.length)) {
+				t.Fatalf("turn %+v, want a %s turn of %v", g.Turn, tt.kind, tt.length)
+			}
+		})
+	}
+}
+
+func TestSpeakingQueueMovesOn(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := newTestState(1, town("citizen"), town("citizen"), town("citizen"))
+	g.Phase = "day"
+	g.OpenDiscussion(at)
+
+	if g.ExpireTurn(at.Add(time.Second)) {
+		t.Fatal("expired a turn with time left")
+	}
+	if left := g.SpeakingLeft(at.Add(10 * time.Second)); left != 170*time.Second {
+		t.Fatalf("%v of speaking left, want 170s", left)
+	}
+	if err := g.PassTurn(2, at); err == nil {
+		t.Fatal("a player passed someone else's turn")
+	}
+	if err := g.PassTurn(1, at); err != nil || g.Turn.UserID != 2 {
+		t.Fatalf("pass: %v, turn %+v", err, g.Turn)
+	}
+	g.Kill(3, "day", "shot", 0)
+	if !g.ExpireTurn(at.Add(time.Hour)) || g.Turn != nil || g.Speaking != nil {
+		t.Fatalf("turn %+v, queue %+v; want the table open once the dead speaker is skipped", g.Turn, g.Speaking)
+	}
+	if g.ExpireTurn(at.Add(2 * time.Hour)) {
+		t.Fatal("the turn changed with nobody left to speak")
+	}
+	if err := g.SkipTurn(at); err == nil {
+		t.Fatal("skipped a turn nobody held")
+	}
+}
+
+func TestChallenges(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := newTestState(1, town("citizen"), town("citizen"), town("citizen"), dead(town("citizen")))
+	g.Phase = "day"
+	g.OpenDiscussion(at)
+
+	tests := []struct {
+		name    string
+		userID  uint
+		wantErr bool
+	}{
+		{name: "the speaker cannot challenge their own turn", userID: 1, wantErr: true},
+		{name: "the dead cannot challenge", userID: 4, wantErr: true},
+		{name: "a living player asks", userID: 3},
+		{name: "the same request twice", userID: 3, wantErr: true},
+		{name: "another player asks", userID: 2},
+	}
+	for _, tt := range tests {
+		if _, err := g.RequestChallenge(tt.userID); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: got %v", tt.name, err)
+		}
+	}
+
+	if err := g.AcceptChallenge(2, 3); err == nil {
+		t.Fatal("a player accepted a challenge on someone else's turn")
+	}
+	if err := g.AcceptChallenge(1, 4); err == nil {
+		t.Fatal("accepted a challenge nobody asked for")
+	}
+	if err := g.AcceptChallenge(1, 3); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	want := []SpeakingSlot{{UserID: 3, Kind: TurnChallenge}, {UserID: 2, Kind: TurnDiscussion}, {UserID: 3, Kind: TurnDiscussion}}
+	if got := g.Queue(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("queue %+v, want %+v", got, want)
+	}
+
+	if err := g.PassTurn(1, at); err != nil {
+		t.Fatal(err)
+	}
+	if g.Turn.UserID != 3 || g.Turn.Kind != TurnChallenge || !g.Turn.EndsAt.Equal(at.Add(DefaultChallengeSeconds*time.Second)) {
+		t.Fatalf("turn %+v, want the challenger's short turn", g.Turn)
+	}
+	if _, err := g.RequestChallenge(2); err == nil {
+		t.Fatal("requested a challenge during a challenge turn")
+	}
+	if err := g.PassTurn(3, at); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Speaking.Requests) != 0 {
+		t.Fatalf("requests %+v outlived the turn they were made in", g.Speaking.Requests)
+	}
+	if _, err := g.RequestChallenge(3); err == nil {
+		t.Fatal("a player took a second challenge on the same day")
+	}
+}
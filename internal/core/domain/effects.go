@@ -0,0 +1,136 @@
+package domain
+
+import "errors"
+
+// Status effect codes applied by ability handlers.
+const (
+	EffectPoisoned    = "poisoned"
+	EffectSilenced    = "silenced"
+	EffectDisabled    = "disabled"
+	EffectIntoxicated = "intoxicated"
+	EffectBoosted     = "boosted"
+	EffectUpgraded    = "upgraded"
+	EffectDisguised   = "disguised"
+	EffectFramed      = "framed"
+	EffectVoteImmune  = "vote_immune"
+	EffectSacrifice   = "sacrifice"
+	EffectSwayed      = "swayed"
+	EffectShieldUsed  = "shield_used"
+	EffectRobbed      = "robbed"
+)
+
+// Explosive kinds planted by the bomb and dynamite makers.
+const (
+	ExplosiveBomb     = "bomb"
+	ExplosiveDynamite = "dynamite"
+)
+
+// StatusEffect is a lasting condition on a player. It applies from Day through Until
+// (inclusive); an Until of zero keeps it for the rest of the game.
+type StatusEffect struct {
+	Target uint   `json:"target"`
+	Code   string `json:"code"`
+	Source uint   `json:"source,omitempty"`
+	Day    int    `json:"day"`
+	Until  int    `json:"until,omitempty"`
+}
+
+// Bomb is an explosive planted on a player that goes off at the end of Deadline unless disarmed.
+type Bomb struct {
+	Target    uint   `json:"target"`
+	PlantedBy uint   `json:"planted_by"`
+	Kind      string `json:"kind"`
+	Code      string `json:"code"`
+	Deadline  int    `json:"deadline"`
+	Resolved  bool   `json:"resolved"`
+}
+
+// AddEffect applies a status effect to a player.
+func (g *GameState) AddEffect(effect StatusEffect) {
+	g.Effects = append(g.Effects, effect)
+}
+
+// HasEffect reports whether the player currently has the effect.
+func (g *GameState) HasEffect(userID uint, code string) bool {
+	_, ok := g.FindEffect(userID, code)
+	return ok
+}
+
+// FindEffect returns the active effect with the given code on the player.
+func (g *GameState) FindEffect(userID uint, code string) (StatusEffect, bool) {
+	for _, effect := range g.Effects {
+		if effect.Target == userID && effect.Code == code && effect.activeOn(g.DayCount) {
+			return effect, true
+		}
+	}
+	return StatusEffect{}, false
+}
+
+// RemoveEffect clears every instance of the effect from the player.
+func (g *GameState) RemoveEffect(userID uint, code string) {
+	kept := g.Effects[:0]
+	for _, effect := range g.Effects {
+		if effect.Target == userID && effect.Code == code {
+			continue
+		}
+		kept = append(kept, effect)
+	}
+	g.Effects = kept
+}
+
+// ArmedExplosive returns the index of the unresolved explosive planted on the player.
+func (g *GameState) ArmedExplosive(target uint) (int, bool) {
+	for i, bomb := range g.Bombs {
+		if bomb.Target == target && !bomb.Resolved {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Disarm lets a player guess the code of an armed explosive. A bomb can only be disarmed
+// by the player carrying it, dynamite only by someone else. A wrong guess sets it off.
+func (g *GameState) Disarm(userID, target uint, guess string) (bool, error) {
+	if !g.isAlive(userID) {
+		return false, errors.New("player not active in this room")
+	}
+	idx, ok := g.ArmedExplosive(target)
+	if !ok {
+		return false, errors.New("no armed explosive on this player")
+	}
+	bomb := g.Bombs[idx]
+	switch bomb.Kind {
+	case ExplosiveBomb:
+		if userID != target {
+			return false, errors.New("only the bombed player can disarm a bomb")
+		}
+	case ExplosiveDynamite:
+		if userID == target {
+			return false, errors.New("dynamite must be disarmed by another player")
+		}
+	}
+
+	g.record(EventDisarmAttempted, DisarmAttempt{UserID: userID, Target: target, Guess: guess})
+	g.Bombs[idx].Resolved = true
+	if guess == bomb.Code {
+		return true, nil
+	}
+	g.Kill(target, g.Phase, bomb.Kind, bomb.PlantedBy)
+	return false, nil
+}
+
+// expireEffects drops effects that no longer apply after the current day.
+func (g *GameState) expireEffects() {
+	kept := g.Effects[:0]
+	for _, effect := range g.Effects {
+		if effect.Until != 0 && effect.Until <= g.DayCount {
+			continue
+		}
+		kept = append(kept, effect)
+	}
+	g.Effects = kept
+}
+
+func (e StatusEffect) activeOn(day int) bool {
+	return e.Day <= day && (e.Until == 0 || day <= e.Until)
+}
@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Game event types appended to a room's event log.
+const (
+	EventGameStarted       = "game_started"
+	EventVoteCast          = "vote_cast"
+	EventAbilityUsed       = "ability_used"
+	EventDisarmAttempted   = "disarm_attempted"
+	EventPhaseAdvanced     = "phase_advanced"
+	EventPlayerRemoved     = "player_removed"
+	EventControllerChanged = "controller_changed"
+	EventGameFinished      = "game_finished"
+)
+
+// GameEvent is an entry in a room's append-only event log. Seq numbers the events of a
+// room from one without gaps.
+type GameEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	RoomID    uint      `json:"room_id" gorm:"uniqueIndex:idx_game_events_room_seq;not null"`
+	Seq       int       `json:"seq" gorm:"uniqueIndex:idx_game_events_room_seq;not null"`
+	Type      string    `json:"type"`
+	Payload   string    `json:"payload" gorm:"type:json"`
+}
+
+// GameStarted is the payload of EventGameStarted.
+type GameStarted struct {
+	Phase       string                    `json:"phase"`
+	Day         int                       `json:"day"`
+	Settings    RoomSettings              `json:"settings"`
+	Assignments map[uint]PlayerAssignment `json:"assignments"`
+}
+
+// DisarmAttempt is the payload of EventDisarmAttempted.
+type DisarmAttempt struct {
+	UserID uint   `json:"user_id"`
+	Target uint   `json:"target"`
+	Guess  string `json:"guess"`
+}
+
+// PhaseAdvanced is the payload of EventPhaseAdvanced. Picks holds the random choices
+// made while resolving, in order, so the transition can be replayed exactly.
+type PhaseAdvanced struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Day   int    `json:"day"`
+	Picks []int  `json:"picks,omitempty"`
+}
+
+// PlayerRemoved is the payload of EventPlayerRemoved.
+type PlayerRemoved struct {
+	UserID uint   `json:"user_id"`
+	Cause  string `json:"cause"`
+}
+
+// ControllerChanged is the payload of EventControllerChanged.
+type ControllerChanged struct {
+	UserID     uint   `json:"user_id"`
+	Controller string `json:"controller"`
+}
+
+// TimelineEntry is a decoded event in a replay.
+type TimelineEntry struct {
+	Seq     int             `json:"seq"`
+	Type    string          `json:"type"`
+	At      time.Time       `json:"at"`
+	Payload json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// Replay is the full timeline of a game with the state rebuilt from it.
+type Replay struct {
+	RoomID   uint            `json:"room_id"`
+	Timeline []TimelineEntry `json:"timeline"`
+	State    *GameState      `json:"state"`
+}
+
+// RecordStart logs the dealt roles and settings as the first event of the game.
+func (g *GameState) RecordStart() {
+	g.record(EventGameStarted, GameStarted{Phase: g.Phase, Day: g.DayCount, Settings: g.Settings, Assignments: g.Assignments})
+}
+
+// TakeEvents returns the events recorded since the last call and clears them.
+func (g *GameState) TakeEvents() []GameEvent {
+	events := g.events
+	g.events = nil
+	return events
+}
+
+// ReplayGameState rebuilds a game state by applying a room's events in order.
+func ReplayGameState(events []GameEvent) (*GameState, error) {
+	g := NewGameState("", 0)
+	for _, event := range events {
+		if err := g.Apply(event); err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.Seq, err)
+		}
+	}
+	g.events = nil
+	return g, nil
+}
+
+// Apply replays a single event onto the state.
+func (g *GameState) Apply(event GameEvent) error {
+	switch event.Type {
+	case EventGameStarted:
+		var started GameStarted
+		if err := json.Unmarshal([]byte(event.Payload), &started); err != nil {
+			return err
+		}
+		*g = *NewGameState(started.Phase, started.Day)
+		g.Settings = started.Settings
+		g.Assignments = started.Assignments
+	case EventVoteCast:
+		var vote VoteLog
+		if err := json.Unmarshal([]byte(event.Payload), &vote); err != nil {
+			return err
+		}
+		return g.CastVote(vote)
+	case EventAbilityUsed:
+		var action AbilityAction
+		if err := json.Unmarshal([]byte(event.Payload), &action); err != nil {
+			return err
+		}
+		g.RecordAbility(action)
+	case EventDisarmAttempted:
+		var attempt DisarmAttempt
+		if err := json.Unmarshal([]byte(event.Payload), &attempt); err != nil {
+			return err
+		}
+		_, err := g.Disarm(attempt.UserID, attempt.Target, attempt.Guess)
+		return err
+	case EventPhaseAdvanced:
+		var advanced PhaseAdvanced
+		if err := json.Unmarshal([]byte(event.Payload), &advanced); err != nil {
+			return err
+		}
+		if g.Phase != advanced.From {
+			return fmt.Errorf("expected phase %s, state is in %s", advanced.From, g.Phase)
+		}
+		g.AdvancePhase(replayPicks(advanced.Picks))
+		if g.Phase != advanced.To || g.DayCount != advanced.Day {
+			return fmt.Errorf("replay diverged at %s of day %d", advanced.To, advanced.Day)
+		}
+	case EventPlayerRemoved:
+		var removed PlayerRemoved
+		if err := json.Unmarshal([]byte(event.Payload), &removed); err != nil {
+			return err
+		}
+		g.RemovePlayer(removed.UserID, removed.Cause)
+	case EventControllerChanged:
+		var changed ControllerChanged
+		if err := json.Unmarshal([]byte(event.Payload), &changed); err != nil {
+			return err
+		}
+		g.SetController(changed.UserID, changed.Controller)
+	case EventGameFinished:
+		var result GameResult
+		if err := json.Unmarshal([]byte(event.Payload), &result); err != nil {
+			return err
+		}
+		g.Result = &result
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return nil
+}
+
+// Timeline decodes events for presentation.
+func Timeline(events []GameEvent) []TimelineEntry {
+	timeline := make([]TimelineEntry, 0, len(events))
+	for _, event := range events {
+		timeline = append(timeline, TimelineEntry{Seq: event.Seq, Type: event.Type, At: event.CreatedAt, Payload: json.RawMessage(event.Payload)})
+	}
+	return timeline
+}
+
+func (g *GameState) record(kind string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	g.events = append(g.events, GameEvent{Type: kind, Payload: string(data)})
+}
+
+func replayPicks(picks []int) func(n int) int {
+	next := 0
+	return func(n int) int {
+		if next >= len(picks) {
+			return 0
+		}
+		pick := picks[next]
+		next++
+		return pick
+	}
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordGame plays a short game with random tie-breaks and returns the live state with
+// the events it logged, numbered like the event log numbers them.
+func recordGame(t *testing.T) (*GameState, []GameEvent) {
+	t.Helper()
+	g := newTestState(1,
+		mafia("godfather", "godfather"), mafia("simple_mafia"), town("protector", "protector"),
+		town("citizen"), town("citizen"), town("citizen"), town("citizen"),
+	)
+	g.Settings.TieBreak = TieRandom
+	g.RecordStart()
+	last := func(n int) int { return n - 1 }
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Night 1: the mafia shoots 4 while 3 guards 5.
+	g.RecordAbility(AbilityAction{UserID: 1, Ability: "godfather", TargetID: 4, Day: 1, Phase: "night", Timestamp: at})
+	g.RecordAbility(AbilityAction{UserID: 3, Ability: "protector", TargetID: 5, Day: 1, Phase: "night", Timestamp: at.Add(time.Second)})
+	g.AdvancePhase(last)
+
+	// Day 1: a tie between 5 and 6 is broken at random.
+	for i, v := range [][2]uint{{1, 5}, {2, 6}, {3, 6}, {5, 5}} {
+		if err := g.CastVote(VoteLog{Voter: v[0], Target: v[1], Phase: "day", Timestamp: at.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatalf("vote %v: %v", v, err)
+		}
+	}
+	g.SetController(7, "bot")
+	g.AdvancePhase(last)
+
+	// Night 2: 7 leaves before the mafia shoots 3.
+	g.RemovePlayer(7, "left")
+	g.RecordAbility(AbilityAction{UserID: 1, Ability: "godfather", TargetID: 3, Day: 2, Phase: "night", Timestamp: at.Add(time.Hour)})
+	g.AdvancePhase(last)
+	g.EvaluateWinner()
+
+	events := g.TakeEvents()
+	for i := range events {
+		events[i].Seq = i + 1
+	}
+	return g, events
+}
+
+func TestReplayGameState(t *testing.T) {
+	live, events := recordGame(t)
+	if live.Result == nil || len(live.Days) != 1 || live.Days[0].Eliminated != 6 {
+		t.Fatalf("recorded game went another way: days %+v, result %+v", live.Days, live.Result)
+	}
+	replayed, err := ReplayGameState(events)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if !reflect.DeepEqual(replayed, live) {
+		want, _ := json.Marshal(live)
+		got, _ := json.Marshal(replayed)
+		t.Fatalf("replayed state\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReplayGameStateDiverging(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(advanced *PhaseAdvanced)
+		want   string
+	}{
+		{name: "another destination", change: func(a *PhaseAdvanced) { a.To = "defense" }, want: "diverged"},
+		{name: "another day", change: func(a *PhaseAdvanced) { a.Day++ }, want: "diverged"},
+		{name: "another starting phase", change: func(a *PhaseAdvanced) { a.From = "defense" }, want: "expected phase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, events := recordGame(t)
+			for i, event := range events {
+				if event.Type != EventPhaseAdvanced {
+					continue
+				}
+				var advanced PhaseAdvanced
+				if err := json.Unmarshal([]byte(event.Payload), &advanced); err != nil {
+					t.Fatal(err)
+				}
+				tt.change(&advanced)
+				payload, _ := json.Marshal(advanced)
+				events[i].Payload = string(payload)
+				break
+			}
+			_, err := ReplayGameState(events)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error saying %q", err, tt.want)
+			}
+		})
+	}
+}
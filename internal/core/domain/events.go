@@ -0,0 +1,211 @@
+package domain
+
+import "time"
+
+// Event bus topics. Each topic carries exactly one event type below, whose EventVersion
+// is bumped whenever its JSON shape changes in a way older consumers cannot read.
+const (
+	TopicUserRegistered     = "user.registered"
+	TopicUserVerified       = "user.verified"
+	TopicChallengeCompleted = "challenge.completed"
+	TopicGroupCreated       = "group.created"
+	TopicGroupMemberAdded   = "group.member_added"
+	TopicGroupMemberRemoved = "group.member_removed"
+	TopicRoomCreated        = "game.room_created"
+	TopicHostChanged        = "game.host_changed"
+	TopicRoomRetired        = "game.room_retired"
+	TopicGameStarted        = "game.started"
+	TopicPhaseChanged       = "game.phase_changed"
+	TopicPlayerJoined       = "game.player_joined"
+	TopicPlayerLeft         = "game.player_left"
+	TopicPlayerKicked       = "game.player_kicked"
+	TopicPlayerConnected    = "game.player_connected"
+	TopicPlayerDisconnected = "game.player_disconnected"
+	TopicPlayerReturned     = "game.player_returned"
+	TopicPlayerAbsent       = "game.player_absent"
+	TopicVoteCast           = "game.vote_cast"
+	TopicPlayerDied         = "game.player_died"
+	TopicInvestigation      = "game.investigation"
+	TopicGameFinished       = "game.finished"
+	TopicChat               = "game.chat"
+	TopicSpeakingTurn       = "game.speaking_turn"
+	TopicChallengeRequested = "game.challenge_requested"
+	TopicUserBlocked        = "user.blocked"
+	TopicUserUnblocked      = "user.unblocked"
+)
+
+// Event is a typed payload published on the event bus.
+type Event interface {
+	EventTopic() string
+	EventVersion() int
+}
+
+// BroadcastEvents lists the events relayed between replicas. Other events stay on the
+// replica that published them.
+var BroadcastEvents = []Event{
+	GameBegan{}, PhaseChanged{}, RoomRetired{},
+	PlayerJoined{}, PlayerLeft{}, PlayerKicked{},
+	PlayerConnected{}, PlayerDisconnected{}, PlayerReturned{}, PlayerAbsent{},
+	VoteCast{}, PlayerDied{}, InvestigationReport{}, GameFinished{}, ChatMessage{},
+	SpeakingTurnChanged{}, ChallengeRequested{}, UserBlocked{}, UserUnblocked{},
+}
+
+// EventCatalog lists every event type, so stored or relayed envelopes can be decoded.
+var EventCatalog = append([]Event{
+	UserRegistered{}, UserVerified{}, ChallengeCompleted{},
+	GroupCreated{}, GroupMemberAdded{}, GroupMemberRemoved{},
+	RoomCreated{}, HostChanged{},
+}, BroadcastEvents...)
+
+// OutboxMessage is an event stored in the same transaction as the change it reports,
+// waiting for the relay to deliver it. EventID stays the same across redeliveries so
+// consumers can drop duplicates. Messages a relay could not decode are set aside until
+// RetryAt, with the reason in LastError.
+type OutboxMessage struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	EventID    string     `json:"event_id" gorm:"uniqueIndex;not null"`
+	Topic      string     `json:"topic" gorm:"not null"`
+	Version    int        `json:"version"`
+	Payload    string     `json:"payload" gorm:"type:json"`
+	OccurredAt time.Time  `json:"occurred_at"`
+	Attempts   int        `json:"attempts"`
+	RetryAt    *time.Time `json:"retry_at" gorm:"index"`
+	LastError  string     `json:"last_error"`
+}
+
+// UserRegistered announces a new account. It deliberately leaves out the phone number
+// and verification code.
+type UserRegistered struct {
+	UserID       uint      `json:"user_id"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// UserVerified announces that a user confirmed their phone number.
+type UserVerified struct {
+	UserID uint `json:"user_id"`
+}
+
+// ChallengeCompleted announces a challenge reward paid out to a user.
+type ChallengeCompleted struct {
+	ChallengeID uint `json:"challenge_id"`
+	UserID      uint `json:"user_id"`
+}
+
+// GroupCreated announces a new group.
+type GroupCreated struct {
+	GroupID uint   `json:"group_id"`
+	Name    string `json:"name"`
+	OwnerID uint   `json:"owner_id"`
+}
+
+// GroupMember identifies a user joining or leaving a group.
+type GroupMember struct {
+	GroupID uint `json:"group_id"`
+	UserID  uint `json:"user_id"`
+}
+
+// GroupMemberAdded announces a user joining a group.
+type GroupMemberAdded struct{ GroupMember }
+
+// GroupMemberRemoved announces a user removed from a group.
+type GroupMemberRemoved struct{ GroupMember }
+
+// RoomCreated announces a new game room.
+type RoomCreated struct {
+	RoomID     uint   `json:"room_id"`
+	Code       string `json:"code"`
+	Type       string `json:"type"`
+	ScenarioID uint   `json:"scenario_id"`
+	HostID     uint   `json:"host_id"`
+}
+
+// HostChanged announces that the host role passed to another member.
+type HostChanged struct {
+	RoomID uint `json:"room_id"`
+	HostID uint `json:"host_id"`
+}
+
+// RoomRetired announces that the replica serving an idle room unloaded it.
+type RoomRetired struct {
+	RoomID uint `json:"room_id"`
+}
+
+// GameBegan announces the first phase of a game.
+type GameBegan struct{ PhaseChange }
+
+// PhaseChanged announces the phase a running game moved to.
+type PhaseChanged struct{ PhaseChange }
+
+// PlayerJoined announces a player taking a seat.
+type PlayerJoined struct{ RoomMember }
+
+// PlayerLeft announces a member leaving the room.
+type PlayerLeft struct{ RoomMember }
+
+// PlayerKicked announces a member removed by staff.
+type PlayerKicked struct{ RoomMember }
+
+// PlayerConnected announces a member's connection to the room.
+type PlayerConnected struct{ RoomMember }
+
+// PlayerDisconnected announces a member's connection dropping.
+type PlayerDisconnected struct{ RoomMember }
+
+// PlayerReturned announces an absent player taking their seat back.
+type PlayerReturned struct{ RoomMember }
+
+func (UserRegistered) EventTopic() string      { return TopicUserRegistered }
+func (UserVerified) EventTopic() string        { return TopicUserVerified }
+func (ChallengeCompleted) EventTopic() string  { return TopicChallengeCompleted }
+func (GroupCreated) EventTopic() string        { return TopicGroupCreated }
+func (GroupMemberAdded) EventTopic() string    { return TopicGroupMemberAdded }
+func (GroupMemberRemoved) EventTopic() string  { return TopicGroupMemberRemoved }
+func (RoomCreated) EventTopic() string         { return TopicRoomCreated }
+func (HostChanged) EventTopic() string         { return TopicHostChanged }
+func (RoomRetired) EventTopic() string         { return TopicRoomRetired }
+func (GameBegan) EventTopic() string           { return TopicGameStarted }
+func (PhaseChanged) EventTopic() string        { return TopicPhaseChanged }
+func (PlayerJoined) EventTopic() string        { return TopicPlayerJoined }
+func (PlayerLeft) EventTopic() string          { return TopicPlayerLeft }
+func (PlayerKicked) EventTopic() string        { return TopicPlayerKicked }
+func (PlayerConnected) EventTopic() string     { return TopicPlayerConnected }
+func (PlayerDisconnected) EventTopic() string  { return TopicPlayerDisconnected }
+func (PlayerReturned) EventTopic() string      { return TopicPlayerReturned }
+func (PlayerAbsent) EventTopic() string        { return TopicPlayerAbsent }
+func (VoteCast) EventTopic() string            { return TopicVoteCast }
+func (PlayerDied) EventTopic() string          { return TopicPlayerDied }
+func (InvestigationReport) EventTopic() string { return TopicInvestigation }
+func (GameFinished) EventTopic() string        { return TopicGameFinished }
+func (ChatMessage) EventTopic() string         { return TopicChat }
+func (SpeakingTurnChanged) EventTopic() string { return TopicSpeakingTurn }
+func (ChallengeRequested) EventTopic() string  { return TopicChallengeRequested }
+func (UserBlocked) EventTopic() string         { return TopicUserBlocked }
+func (UserUnblocked) EventTopic() string       { return TopicUserUnblocked }
+
+func (UserRegistered) EventVersion() int      { return 1 }
+func (UserVerified) EventVersion() int        { return 1 }
+func (ChallengeCompleted) EventVersion() int  { return 1 }
+func (GroupCreated) EventVersion() int        { return 1 }
+func (GroupMemberAdded) EventVersion() int    { return 1 }
+func (GroupMemberRemoved) EventVersion() int  { return 1 }
+func (RoomCreated) EventVersion() int         { return 1 }
+func (HostChanged) EventVersion() int         { return 1 }
+func (RoomRetired) EventVersion() int         { return 1 }
+func (GameBegan) EventVersion() int           { return 1 }
+func (PhaseChanged) EventVersion() int        { return 1 }
+func (PlayerJoined) EventVersion() int        { return 1 }
+func (PlayerLeft) EventVersion() int          { return 1 }
+func (PlayerKicked) EventVersion() int        { return 1 }
+func (PlayerConnected) EventVersion() int     { return 1 }
+func (PlayerDisconnected) EventVersion() int  { return 1 }
+func (PlayerReturned) EventVersion() int      { return 1 }
+func (PlayerAbsent) EventVersion() int        { return 1 }
+func (VoteCast) EventVersion() int            { return 1 }
+func (PlayerDied) EventVersion() int          { return 1 }
+func (InvestigationReport) EventVersion() int { return 1 }
+func (GameFinished) EventVersion() int        { return 1 }
+func (ChatMessage) EventVersion() int         { return 1 }
+func (SpeakingTurnChanged) EventVersion() int { return 1 }
+func (ChallengeRequested) EventVersion() int  { return 1 }
+func (UserBlocked) EventVersion() int         { return 1 }
+func (UserUnblocked) EventVersion() int       { return 1 }
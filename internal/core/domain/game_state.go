@@ -2,9 +2,71 @@ package domain
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 	"time"
 )
 
+// Tie-break strategies applied when the day vote ends in a tie.
+const (
+	TieNoElimination = "none"
+	TieRevote        = "revote"
+	TieRandom        = "random"
+	TieDefense       = "defense"
+)
+
+// PhaseDurations maps a phase name to its length in seconds.
+type PhaseDurations map[string]int
+
+// Duration returns the length of a phase, or zero when the phase is untimed.
+func (d PhaseDurations) Duration(phase string) time.Duration {
+	return time.Duration(d[phase]) * time.Second
+}
+
+// Merge returns a copy of the durations with the overrides applied on top.
+func (d PhaseDurations) Merge(overrides PhaseDurations) PhaseDurations {
+	merged := PhaseDurations{}
+	for phase, seconds := range d {
+		merged[phase] = seconds
+	}
+	for phase, seconds := range overrides {
+		merged[phase] = seconds
+	}
+	return merged
+}
+
+// RoomSettings holds the host-configurable rules of a room. Turn lengths are in seconds
+// and fall back to the defaults when zero.
+type RoomSettings struct {
+	TieBreak         string         `json:"tie_break"`
+	PhaseDurations   PhaseDurations `json:"phase_durations,omitempty"`
+	TurnSeconds      int            `json:"turn_seconds,omitempty"`
+	DefenseSeconds   int            `json:"defense_seconds,omitempty"`
+	ChallengeSeconds int            `json:"challenge_seconds,omitempty"`
+}
+
+// Normalize fills defaults and validates the settings.
+func (s RoomSettings) Normalize() (RoomSettings, error) {
+	switch s.TieBreak {
+	case "":
+		s.TieBreak = TieNoElimination
+	case TieNoElimination, TieRevote, TieRandom, TieDefense:
+	default:
+		return s, fmt.Errorf("unknown tie break %q", s.TieBreak)
+	}
+	for phase, seconds := range s.PhaseDurations {
+		if seconds < 0 {
+			return s, fmt.Errorf("invalid duration for %s phase", phase)
+		}
+	}
+	for _, seconds := range []int{s.TurnSeconds, s.DefenseSeconds, s.ChallengeSeconds} {
+		if seconds < 0 || time.Duration(seconds)*time.Second > MaxSpeakingTurn {
+			return s, fmt.Errorf("speaking turns must last at most %s", MaxSpeakingTurn)
+		}
+	}
+	return s, nil
+}
+
 // AbilityUsage tracks when a player last used a specific ability.
 type AbilityUsage struct {
 	Day   int    `json:"day"`
@@ -18,6 +80,7 @@ type PlayerAssignment struct {
 	Abilities     []string                `json:"abilities"`
 	Alive         bool                    `json:"alive"`
 	UsedAbilities map[string]AbilityUsage `json:"used_abilities"`
+	Controller    string                  `json:"controller,omitempty"`
 }
 
 // VoteLog captures a single vote action during the day phase.
@@ -26,6 +89,7 @@ type VoteLog struct {
 	Target    uint      `json:"target"`
 	Phase     string    `json:"phase"`
 	Day       int       `json:"day"`
+	Round     int       `json:"round"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -34,31 +98,92 @@ type AbilityAction struct {
 	UserID    uint      `json:"user_id"`
 	Ability   string    `json:"ability"`
 	TargetID  uint      `json:"target_id"`
+	Guess     string    `json:"guess,omitempty"`
 	Phase     string    `json:"phase"`
 	Day       int       `json:"day"`
 	Timestamp time.Time `json:"timestamp"`
+	Resolved  bool      `json:"resolved,omitempty"`
+}
+
+// DeathRecord notes when and how a player was eliminated.
+type DeathRecord struct {
+	UserID uint   `json:"user_id"`
+	Day    int    `json:"day"`
+	Phase  string `json:"phase"`
+	Cause  string `json:"cause"`
+	By     uint   `json:"by,omitempty"`
+}
+
+// InvestigationResult is the private answer delivered to an investigating player.
+type InvestigationResult struct {
+	Day          int    `json:"day"`
+	Investigator uint   `json:"investigator"`
+	Target       uint   `json:"target"`
+	Ability      string `json:"ability"`
+	Role         string `json:"role,omitempty"`
+	Team         string `json:"team"`
+}
+
+// NightSummary captures the outcome of resolving a single night.
+type NightSummary struct {
+	Day     int    `json:"day"`
+	Blocked []uint `json:"blocked"`
+	Saved   []uint `json:"saved"`
+	Killed  []uint `json:"killed"`
+	Exposed []uint `json:"exposed,omitempty"`
 }
 
 // GameState keeps the serialized state of an in-progress game.
 type GameState struct {
-	Phase       string                    `json:"phase"`
-	DayCount    int                       `json:"day_count"`
-	Assignments map[uint]PlayerAssignment `json:"assignments"`
-	Votes       []VoteLog                 `json:"votes"`
-	Abilities   []AbilityAction           `json:"abilities"`
+	Phase          string                    `json:"phase"`
+	DayCount       int                       `json:"day_count"`
+	Settings       RoomSettings              `json:"settings"`
+	Assignments    map[uint]PlayerAssignment `json:"assignments"`
+	Ballot         Ballot                    `json:"ballot"`
+	Votes          []VoteLog                 `json:"votes"`
+	Abilities      []AbilityAction           `json:"abilities"`
+	Deaths         []DeathRecord             `json:"deaths"`
+	Investigations []InvestigationResult     `json:"investigations"`
+	Nights         []NightSummary            `json:"nights"`
+	Days           []DaySummary              `json:"days"`
+	Effects        []StatusEffect            `json:"effects"`
+	Bombs          []Bomb                    `json:"bombs"`
+	Predictions    map[uint]string           `json:"predictions,omitempty"`
+	MafiaShots     int                       `json:"mafia_shots"`
+	Turn           *SpeakingTurn             `json:"turn,omitempty"`
+	Speaking       *SpeakingQueue            `json:"speaking,omitempty"`
+	Result         *GameResult               `json:"result,omitempty"`
+
+	events []GameEvent
 }
 
 // NewGameState creates an empty state for the given phase/day.
 func NewGameState(phase string, day int) *GameState {
 	return &GameState{
-		Phase:       phase,
-		DayCount:    day,
-		Assignments: map[uint]PlayerAssignment{},
-		Votes:       []VoteLog{},
-		Abilities:   []AbilityAction{},
+		Phase:          phase,
+		DayCount:       day,
+		Assignments:    map[uint]PlayerAssignment{},
+		Votes:          []VoteLog{},
+		Abilities:      []AbilityAction{},
+		Deaths:         []DeathRecord{},
+		Investigations: []InvestigationResult{},
+		Nights:         []NightSummary{},
+		Days:           []DaySummary{},
+		Effects:        []StatusEffect{},
+		Bombs:          []Bomb{},
 	}
 }
 
+// PlayerIDs returns the IDs of every assigned player in ascending order.
+func (g *GameState) PlayerIDs() []uint {
+	ids := make([]uint, 0, len(g.Assignments))
+	for id := range g.Assignments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
 // Serialize renders the game state into a string for persistence.
 func (g *GameState) Serialize() (string, error) {
 	data, err := json.Marshal(g)
@@ -68,6 +193,16 @@ func (g *GameState) Serialize() (string, error) {
 	return string(data), nil
 }
 
+// Clone returns a deep copy of the state that can be read while the original keeps
+// changing. Events that were not taken yet stay with the original.
+func (g *GameState) Clone() (*GameState, error) {
+	raw, err := g.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return ParseGameState(raw)
+}
+
 // ParseGameState converts the stored string version back into a structured GameState.
 func ParseGameState(raw string) (*GameState, error) {
 	if raw == "" {
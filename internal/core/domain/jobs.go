@@ -0,0 +1,13 @@
+package domain
+
+// Names of the background jobs services hand to the job queue.
+const (
+	JobSendNotification = "notification.send"
+)
+
+// NotificationJob asks for a message to be delivered to a user on a channel.
+type NotificationJob struct {
+	UserID  uint   `json:"user_id"`
+	Channel string `json:"channel"`
+	Message string `json:"message"`
+}
@@ -51,19 +51,26 @@ type Role struct {
 }
 
 type GameRoom struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
-	Code      string     `json:"code" gorm:"unique"`
-	Type      string     `json:"type"`
-	HostID    uint       `json:"host_id"`
-	Players   []User     `json:"players" gorm:"many2many:room_players;"`
-	Status    string     `json:"status" gorm:"default:waiting"`
-	Phase     string     `json:"phase"`
-	DayCount  int        `json:"day_count"`
-	Winner    string     `json:"winner"`
-	Results   string     `json:"results" gorm:"type:json"`
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	DeletedAt    *time.Time   `json:"deleted_at,omitempty"`
+	Code         string       `json:"code" gorm:"unique"`
+	Type         string       `json:"type"`
+	ScenarioID   uint         `json:"scenario_id"`
+	HostID       uint         `json:"host_id"`
+	CoHostIDs    []uint       `json:"co_host_ids" gorm:"serializer:json"`
+	ModeratorIDs []uint       `json:"moderator_ids" gorm:"serializer:json"`
+	SpectatorIDs []uint       `json:"spectator_ids" gorm:"serializer:json"`
+	Players      []User       `json:"players" gorm:"many2many:room_players;"`
+	Status       string       `json:"status" gorm:"default:waiting"`
+	Phase        string       `json:"phase"`
+	PhaseEndsAt  *time.Time   `json:"phase_ends_at,omitempty"`
+	DayCount     int          `json:"day_count"`
+	Winner       string       `json:"winner"`
+	Settings     RoomSettings `json:"settings" gorm:"serializer:json"`
+	Results      string       `json:"-" gorm:"type:json"`
+	Version      int          `json:"version" gorm:"not null;default:0"`
 }
 
 type Group struct {
@@ -159,12 +166,16 @@ type GameRule struct {
 }
 
 type Scenario struct {
-	ID          uint       `json:"id" gorm:"primaryKey"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Rules       []string   `json:"rules" gorm:"serializer:json"`
-	Roles       []string   `json:"roles" gorm:"serializer:json"`
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   *time.Time        `json:"deleted_at,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Rules       []string          `json:"rules" gorm:"serializer:json"`
+	Roles       []string          `json:"roles" gorm:"serializer:json"`
+	Brackets    []ScenarioBracket `json:"brackets" gorm:"serializer:json"`
+	// PhaseDurations override the room type's phase lengths, in seconds, for rooms
+	// playing the scenario. Room settings override them in turn.
+	PhaseDurations PhaseDurations `json:"phase_durations,omitempty" gorm:"serializer:json"`
 }
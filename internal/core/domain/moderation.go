@@ -0,0 +1,25 @@
+package domain
+
+// Kinds of user-generated text checked by the content filter.
+const (
+	ContentChat  = "chat"
+	ContentName  = "name"
+	ContentGroup = "group"
+)
+
+// Actions taken on text the content filter catches. Mask stars out the offending words,
+// reject refuses the text, and flag lets it through verbatim but files a Report for a
+// moderator to review. Reports filed by the filter have no reporter.
+const (
+	ModerationMask   = "mask"
+	ModerationReject = "reject"
+	ModerationFlag   = "flag"
+)
+
+// ContentSubject identifies who wrote a piece of text and where. RoomID is only set
+// for chat.
+type ContentSubject struct {
+	Kind   string
+	UserID uint
+	RoomID uint
+}
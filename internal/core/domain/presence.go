@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Presence statuses tracked for room members.
+const (
+	PresenceOnline  = "online"
+	PresenceOffline = "offline"
+)
+
+// Policies applied to a player who stays disconnected past the grace window. The policy
+// name doubles as the controller recorded on the player's assignment.
+const (
+	AbsenceSkip = "skip"
+	AbsenceAFK  = "afk"
+	AbsenceBot  = "bot"
+)
+
+// Presence is the connection status of a user in a room.
+type Presence struct {
+	RoomID  uint      `json:"room_id"`
+	UserID  uint      `json:"user_id"`
+	Status  string    `json:"status"`
+	Since   time.Time `json:"since"`
+	Handled bool      `json:"handled"`
+}
+
+// ValidAbsencePolicy reports whether the policy is known.
+func ValidAbsencePolicy(policy string) bool {
+	switch policy {
+	case AbsenceSkip, AbsenceAFK, AbsenceBot:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package domain
+
+import "time"
+
+// Message types pushed to websocket clients.
+const (
+	WSPhaseChanged = "phase_changed"
+	WSPlayerJoined = "player_joined"
+	WSPlayerLeft   = "player_left"
+	WSVoteCast     = "vote_cast"
+	WSPlayerDied   = "player_died"
+	WSGameFinished = "game_finished"
+	WSChat         = "chat"
+	WSInvestigated = "investigation_result"
+	WSConnected    = "player_connected"
+	WSDisconnected = "player_disconnected"
+	WSAbsent       = "player_absent"
+	WSReturned     = "player_returned"
+	WSSession      = "session"
+	WSError        = "error"
+	WSVoiceOffer   = "voice_offer"
+	WSSpeakingTurn = "speaking_turn"
+	WSChallenge    = "challenge_requested"
+)
+
+// Message types sent by websocket clients.
+const (
+	WSSubscribe   = "subscribe"
+	WSUnsubscribe = "unsubscribe"
+	WSResume      = "resume"
+	WSVoiceJoin   = "voice_join"
+	WSVoiceAnswer = "voice_answer"
+	WSVoiceLeave  = "voice_leave"
+)
+
+// WSVoiceCandidate trickles ICE candidates in both directions.
+const WSVoiceCandidate = "voice_candidate"
+
+// RoomMember identifies a user entering or leaving a room.
+type RoomMember struct {
+	RoomID uint `json:"room_id"`
+	UserID uint `json:"user_id"`
+}
+
+// PlayerAbsent announces the policy applied to a player who did not reconnect in time.
+type PlayerAbsent struct {
+	RoomID uint   `json:"room_id"`
+	UserID uint   `json:"user_id"`
+	Policy string `json:"policy"`
+}
+
+// Session is sent on connect with the token a client presents to resume after a drop.
+type Session struct {
+	Token string `json:"token"`
+	Seq   uint64 `json:"seq"`
+}
+
+// ResumeRequest asks to restore a dropped session and replay messages after LastSeq.
+type ResumeRequest struct {
+	Token   string `json:"token"`
+	LastSeq uint64 `json:"last_seq"`
+}
+
+// PhaseChange announces the phase a room moved to.
+type PhaseChange struct {
+	RoomID      uint       `json:"room_id"`
+	Phase       string     `json:"phase"`
+	DayCount    int        `json:"day_count"`
+	PhaseEndsAt *time.Time `json:"phase_ends_at,omitempty"`
+}
+
+// VoteCast announces a vote recorded on the open ballot.
+type VoteCast struct {
+	RoomID uint `json:"room_id"`
+	Voter  uint `json:"voter"`
+	Target uint `json:"target"`
+	Day    int  `json:"day"`
+	Round  int  `json:"round"`
+}
+
+// PlayerDied announces a death to the room.
+type PlayerDied struct {
+	RoomID uint `json:"room_id"`
+	PublicDeath
+}
+
+// GameFinished announces the end of a game.
+type GameFinished struct {
+	RoomID uint        `json:"room_id"`
+	Result *GameResult `json:"result"`
+}
+
+// InvestigationReport delivers an investigation result privately to the investigator.
+type InvestigationReport struct {
+	RoomID uint `json:"room_id"`
+	InvestigationResult
+}
+
+// RoomSubscription is the payload of subscribe, unsubscribe, voice_join and
+// voice_leave messages.
+type RoomSubscription struct {
+	RoomID uint `json:"room_id"`
+}
+
+// ICEServer is a STUN or TURN server voice clients use to reach the SFU.
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// VoiceDescription carries an SDP offer from the server, or the client's answer to it.
+// Offers list the ICE servers the client should use.
+type VoiceDescription struct {
+	RoomID     uint        `json:"room_id"`
+	SDP        string      `json:"sdp"`
+	ICEServers []ICEServer `json:"ice_servers,omitempty"`
+}
+
+// VoiceCandidate is an ICE candidate in the shape of the browser's RTCIceCandidateInit.
+type VoiceCandidate struct {
+	RoomID           uint    `json:"room_id"`
+	Candidate        string  `json:"candidate"`
+	SDPMid           *string `json:"sdpMid,omitempty"`
+	SDPMLineIndex    *uint16 `json:"sdpMLineIndex,omitempty"`
+	UsernameFragment *string `json:"usernameFragment,omitempty"`
+}
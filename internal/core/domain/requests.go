@@ -26,7 +26,17 @@ type PurchaseRequest struct {
 }
 
 type CreateRoomRequest struct {
-	Type string `json:"type"`
+	Type       string       `json:"type"`
+	ScenarioID uint         `json:"scenario_id" binding:"required"`
+	Settings   RoomSettings `json:"settings"`
+}
+
+type KickRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+type MemberRoleRequest struct {
+	Role string `json:"role" binding:"required"`
 }
 
 type VoteRequest struct {
@@ -36,10 +46,31 @@ type VoteRequest struct {
 type AbilityRequest struct {
 	Ability  string `json:"ability"`
 	TargetID uint   `json:"target_id"`
+	Guess    string `json:"guess,omitempty"`
+}
+
+type DisarmRequest struct {
+	TargetID uint   `json:"target_id" binding:"required"`
+	Guess    string `json:"guess" binding:"required"`
+}
+
+type SpeakingTurnRequest struct {
+	UserID  uint `json:"user_id" binding:"required"`
+	Seconds int  `json:"seconds" binding:"required"`
+}
+
+type AcceptChallengeRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+type ChatRequest struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text" binding:"required"`
 }
 
 type WSMessage struct {
 	Type string      `json:"type"`
+	Seq  uint64      `json:"seq,omitempty"`
 	Data interface{} `json:"data"`
 }
 
@@ -63,8 +94,10 @@ type RuleRequest struct {
 }
 
 type ScenarioRequest struct {
-	Name        string   `json:"name" binding:"required"`
-	Description string   `json:"description"`
-	Rules       []string `json:"rules"`
-	Roles       []string `json:"roles"`
+	Name           string            `json:"name" binding:"required"`
+	Description    string            `json:"description"`
+	Rules          []string          `json:"rules"`
+	Roles          []string          `json:"roles"`
+	Brackets       []ScenarioBracket `json:"brackets"`
+	PhaseDurations PhaseDurations    `json:"phase_durations"`
 }
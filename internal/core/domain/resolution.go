@@ -0,0 +1,364 @@
+package domain
+
+import "sort"
+
+// Ability resolution priorities; lower values resolve first within a phase.
+const (
+	PriorityBlock = iota + 1
+	PriorityProtect
+	PriorityModify
+	PriorityKill
+	PriorityInvestigate
+	PriorityTransform
+)
+
+// Attack describes a kill attempt made while a phase is resolved.
+type Attack struct {
+	Target      uint
+	By          uint
+	Cause       string
+	Mafia       bool
+	Unstoppable bool
+}
+
+// Resolution carries the bookkeeping of a single phase while its actions are applied.
+type Resolution struct {
+	State   *GameState
+	Phase   string
+	Pick    func(n int) int
+	Blocked []uint
+	Saved   []uint
+	Exposed []uint
+
+	blocked   map[uint]bool
+	protected map[uint]bool
+	shielded  map[uint]bool
+	kills     []Attack
+	mafiaShot bool
+}
+
+// NewResolution prepares the resolution of the given phase. pick is used by handlers
+// that need randomness, such as bomb codes.
+func NewResolution(state *GameState, phase string, pick func(n int) int) *Resolution {
+	return &Resolution{
+		State:     state,
+		Phase:     phase,
+		Pick:      pick,
+		Blocked:   []uint{},
+		Saved:     []uint{},
+		Exposed:   []uint{},
+		blocked:   map[uint]bool{},
+		protected: map[uint]bool{},
+		shielded:  map[uint]bool{},
+	}
+}
+
+// ResolveNight evaluates the night actions recorded for the current day in priority
+// order and writes the resulting deaths, saves and investigation results into the state.
+func (g *GameState) ResolveNight(pick func(n int) int) NightSummary {
+	r := NewResolution(g, "night", pick)
+	r.Run()
+	summary := NightSummary{Day: g.DayCount, Blocked: r.Blocked, Saved: r.Saved, Killed: r.Finish(), Exposed: r.Exposed}
+	g.Nights = append(g.Nights, summary)
+	return summary
+}
+
+// ResolveDay applies the day actions that have not been resolved yet and returns the
+// players they killed. It runs before every tally so protections affect the vote.
+func (g *GameState) ResolveDay(pick func(n int) int) []uint {
+	r := NewResolution(g, "day", pick)
+	r.Run()
+	return r.Finish()
+}
+
+// AdvancePhase resolves the current phase and moves on: the night resolves into the day,
+// and the day vote either reopens for a revote, moves to the defense or ends the day.
+func (g *GameState) AdvancePhase(pick func(n int) int) {
+	var picks []int
+	recorded := func(n int) int {
+		i := pick(n)
+		picks = append(picks, i)
+		return i
+	}
+
+	from := g.Phase
+	g.Turn, g.Speaking = nil, nil
+	if g.Phase == "night" {
+		g.ResolveNight(recorded)
+		g.Phase = "day"
+	} else {
+		g.ResolveDay(recorded)
+		switch g.TallyVotes(recorded).Outcome {
+		case OutcomeRevote:
+			g.Phase = "day"
+		case OutcomeDefense:
+			g.Phase = "defense"
+		default:
+			g.EndDay()
+			g.Phase = "night"
+			g.DayCount++
+		}
+	}
+	g.record(EventPhaseAdvanced, PhaseAdvanced{From: from, To: g.Phase, Day: g.DayCount, Picks: picks})
+}
+
+// RecordAbility logs an ability use for resolution and marks it used for the phase.
+func (g *GameState) RecordAbility(action AbilityAction) {
+	if player, ok := g.Assignments[action.UserID]; ok {
+		if player.UsedAbilities == nil {
+			player.UsedAbilities = map[string]AbilityUsage{}
+		}
+		player.UsedAbilities[action.Ability] = AbilityUsage{Day: action.Day, Phase: action.Phase}
+		g.Assignments[action.UserID] = player
+	}
+	g.Abilities = append(g.Abilities, action)
+	g.record(EventAbilityUsed, action)
+}
+
+// RemovePlayer takes a player out of a running game, counting them as dead.
+func (g *GameState) RemovePlayer(userID uint, cause string) bool {
+	if !g.Kill(userID, g.Phase, cause, 0) {
+		return false
+	}
+	g.record(EventPlayerRemoved, PlayerRemoved{UserID: userID, Cause: cause})
+	return true
+}
+
+// SetController records who plays a seat: an empty controller means the player
+// themselves, otherwise the absence policy that took over.
+func (g *GameState) SetController(userID uint, controller string) bool {
+	player, ok := g.Assignments[userID]
+	if !ok || player.Controller == controller {
+		return false
+	}
+	player.Controller = controller
+	g.Assignments[userID] = player
+	g.record(EventControllerChanged, ControllerChanged{UserID: userID, Controller: controller})
+	return true
+}
+
+// Run applies every unresolved action of the phase through its registered handler.
+func (r *Resolution) Run() {
+	for _, idx := range r.State.pendingActions(r.Phase) {
+		action := r.State.Abilities[idx]
+		r.State.Abilities[idx].Resolved = true
+		if r.IsBlocked(action.UserID) {
+			continue
+		}
+		effect, _ := LookupAbilityEffect(action.Ability)
+		effect.Handler(r, action)
+	}
+}
+
+// Finish applies the pending kills and returns the players who died.
+func (r *Resolution) Finish() []uint {
+	killed := []uint{}
+	for _, attack := range r.kills {
+		if r.State.Kill(attack.Target, r.Phase, attack.Cause, attack.By) {
+			killed = append(killed, attack.Target)
+		}
+	}
+	r.kills = nil
+	return killed
+}
+
+// IsBlocked reports whether a player's actions are suppressed for this resolution.
+func (r *Resolution) IsBlocked(userID uint) bool {
+	return r.blocked[userID] || r.State.HasEffect(userID, EffectIntoxicated) || r.State.HasEffect(userID, EffectDisabled)
+}
+
+// Block suppresses the target's remaining actions for this resolution.
+func (r *Resolution) Block(target uint) {
+	if _, ok := r.State.Assignments[target]; !ok || r.blocked[target] {
+		return
+	}
+	r.blocked[target] = true
+	r.Blocked = append(r.Blocked, target)
+}
+
+// Protect shields the target from attacks; strong protection also stops unstoppable attacks.
+func (r *Resolution) Protect(target uint, strong bool) {
+	if _, ok := r.State.Assignments[target]; !ok {
+		return
+	}
+	r.protected[target] = true
+	if strong {
+		r.shielded[target] = true
+	}
+}
+
+// Attack queues a kill attempt, honouring protections, sacrifices and passive defences.
+func (r *Resolution) Attack(a Attack) {
+	g := r.State
+	target, ok := g.Assignments[a.Target]
+	if !ok || !target.Alive || r.pendingKill(a.Target) {
+		return
+	}
+
+	if a.Mafia {
+		if sacrifice, ok := g.FindEffect(a.Target, EffectSacrifice); ok {
+			g.RemoveEffect(a.Target, EffectSacrifice)
+			r.Saved = appendUnique(r.Saved, a.Target)
+			a.Target, a.Cause = sacrifice.Source, "sacrificed"
+		} else if containsAbility(target.Abilities, "ranger") && !g.HasEffect(a.Target, EffectShieldUsed) {
+			g.AddEffect(StatusEffect{Target: a.Target, Code: EffectShieldUsed, Day: g.DayCount})
+			r.Saved = appendUnique(r.Saved, a.Target)
+			return
+		}
+	}
+
+	if r.shielded[a.Target] || (r.protected[a.Target] && !a.Unstoppable) {
+		r.Saved = appendUnique(r.Saved, a.Target)
+		return
+	}
+
+	if a.Mafia && containsAbility(g.Assignments[a.Target].Abilities, "innocent") {
+		r.Exposed = appendUnique(r.Exposed, a.By)
+	}
+	r.kills = append(r.kills, a)
+}
+
+// UseMafiaShot consumes the mafia's shot for this resolution, falling back to any
+// extra shots granted earlier in the game.
+func (r *Resolution) UseMafiaShot() bool {
+	if !r.mafiaShot {
+		r.mafiaShot = true
+		return true
+	}
+	if r.State.MafiaShots > 0 {
+		r.State.MafiaShots--
+		return true
+	}
+	return false
+}
+
+// Boosted reports whether the actor's ability is strengthened for this resolution.
+func (r *Resolution) Boosted(userID uint) bool {
+	return r.State.HasEffect(userID, EffectBoosted) || r.State.HasEffect(userID, EffectUpgraded)
+}
+
+// NextDay returns the day on which a delayed effect started now comes due: the coming
+// day for night actions, the following day for day actions.
+func (r *Resolution) NextDay() int {
+	if r.Phase == "night" {
+		return r.State.DayCount
+	}
+	return r.State.DayCount + 1
+}
+
+// Investigate records what the investigator learns about the target, taking disguises
+// and frames into account. When revealRole is false only the apparent team is shown.
+func (r *Resolution) Investigate(action AbilityAction, revealRole bool) {
+	target, ok := r.State.Assignments[action.TargetID]
+	if !ok {
+		return
+	}
+	result := InvestigationResult{
+		Day:          r.State.DayCount,
+		Investigator: action.UserID,
+		Target:       action.TargetID,
+		Ability:      action.Ability,
+		Team:         target.Team,
+	}
+	if revealRole {
+		result.Role = target.Role
+	}
+	switch {
+	case r.State.HasEffect(action.TargetID, EffectDisguised):
+		result.Team, result.Role = "town", ""
+	case r.State.HasEffect(action.TargetID, EffectFramed):
+		result.Team, result.Role = "mafia", ""
+	}
+	r.State.Investigations = append(r.State.Investigations, result)
+}
+
+func (r *Resolution) pendingKill(target uint) bool {
+	for _, a := range r.kills {
+		if a.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Kill marks a player as dead and records the death.
+func (g *GameState) Kill(userID uint, phase, cause string, by uint) bool {
+	player, ok := g.Assignments[userID]
+	if !ok || !player.Alive {
+		return false
+	}
+	player.Alive = false
+	g.Assignments[userID] = player
+	g.Deaths = append(g.Deaths, DeathRecord{UserID: userID, Day: g.DayCount, Phase: phase, Cause: cause, By: by})
+	// The mistress grants the mafia an additional shot when she falls.
+	if containsAbility(player.Abilities, "mistress") {
+		g.MafiaShots++
+	}
+	return true
+}
+
+// EndDay applies the consequences that come due once the day vote is final:
+// untreated poison and bombs that were not disarmed.
+func (g *GameState) EndDay() []uint {
+	killed := []uint{}
+	for _, effect := range g.Effects {
+		if effect.Code == EffectPoisoned && effect.Until == g.DayCount && g.Kill(effect.Target, "day", "poisoned", effect.Source) {
+			killed = append(killed, effect.Target)
+		}
+	}
+	for i, bomb := range g.Bombs {
+		if bomb.Resolved || bomb.Deadline != g.DayCount {
+			continue
+		}
+		g.Bombs[i].Resolved = true
+		if g.Kill(bomb.Target, "day", bomb.Kind, bomb.PlantedBy) {
+			killed = append(killed, bomb.Target)
+		}
+	}
+	g.expireEffects()
+	return killed
+}
+
+// pendingActions returns the indexes of the unresolved actions of the phase ordered for resolution.
+func (g *GameState) pendingActions(phase string) []int {
+	var idx []int
+	for i, action := range g.Abilities {
+		if action.Resolved || action.Day != g.DayCount || action.Phase != phase {
+			continue
+		}
+		if _, ok := LookupAbilityEffect(action.Ability); !ok {
+			continue
+		}
+		idx = append(idx, i)
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := g.Abilities[idx[i]], g.Abilities[idx[j]]
+		if pa, pb := AbilityPriority(a.Ability), AbilityPriority(b.Ability); pa != pb {
+			return pa < pb
+		}
+		if ra, rb := shotRank(a.Ability), shotRank(b.Ability); ra != rb {
+			return ra < rb
+		}
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		return a.UserID < b.UserID
+	})
+	return idx
+}
+
+func appendUnique(list []uint, id uint) []uint {
+	if containsID(list, id) {
+		return list
+	}
+	return append(list, id)
+}
+
+func containsAbility(list []string, ability string) bool {
+	for _, a := range list {
+		if a == ability {
+			return true
+		}
+	}
+	return false
+}
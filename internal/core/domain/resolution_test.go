@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPendingActionsOrder(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		actions []AbilityAction
+		want    []string
+	}{
+		{
+			name: "priorities resolve block, protect, modify, kill, investigate, transform",
+			actions: []AbilityAction{
+				{UserID: 1, Ability: "thief"},
+				{UserID: 2, Ability: "informer"},
+				{UserID: 3, Ability: "godfather"},
+				{UserID: 4, Ability: "natasha"},
+				{UserID: 5, Ability: "protector"},
+				{UserID: 6, Ability: "kidnapper"},
+			},
+			want: []string{"kidnapper", "protector", "natasha", "godfather", "informer", "thief"},
+		},
+		{
+			name: "mafia shots follow their precedence",
+			actions: []AbilityAction{
+				{UserID: 1, Ability: "simple_mafia"},
+				{UserID: 2, Ability: "nato"},
+				{UserID: 3, Ability: "godfather"},
+			},
+			want: []string{"godfather", "nato", "simple_mafia"},
+		},
+		{
+			name: "earlier actions of the same priority go first",
+			actions: []AbilityAction{
+				{UserID: 1, Ability: "ranger", Timestamp: at.Add(time.Second)},
+				{UserID: 2, Ability: "hunter", Timestamp: at},
+			},
+			want: []string{"hunter", "ranger"},
+		},
+		{
+			name: "ties fall back to the lower user ID",
+			actions: []AbilityAction{
+				{UserID: 2, Ability: "ranger", Timestamp: at},
+				{UserID: 1, Ability: "hunter", Timestamp: at},
+			},
+			want: []string{"hunter", "ranger"},
+		},
+		{
+			name: "unknown abilities are left out",
+			actions: []AbilityAction{
+				{UserID: 1, Ability: "juggler"},
+				{UserID: 2, Ability: "ranger"},
+			},
+			want: []string{"ranger"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGameState("night", 2)
+			for _, action := range tt.actions {
+				action.Day, action.Phase = g.DayCount, "night"
+				g.Abilities = append(g.Abilities, action)
+			}
+			var got []string
+			for _, idx := range g.pendingActions("night") {
+				got = append(got, g.Abilities[idx].Ability)
+			}
+			if !sameAbilities(got, tt.want) {
+				t.Fatalf("order %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPendingActionsSkipsOtherPhases(t *testing.T) {
+	g := NewGameState("night", 2)
+	g.Abilities = []AbilityAction{
+		{UserID: 1, Ability: "ranger", Day: 2, Phase: "night", Resolved: true},
+		{UserID: 2, Ability: "ranger", Day: 1, Phase: "night"},
+		{UserID: 3, Ability: "ranger", Day: 2, Phase: "day"},
+		{UserID: 4, Ability: "ranger", Day: 2, Phase: "night"},
+	}
+	idx := g.pendingActions("night")
+	if len(idx) != 1 || g.Abilities[idx[0]].UserID != 4 {
+		t.Fatalf("pending %v, want only the unresolved action of tonight", idx)
+	}
+}
+
+func TestResolutionOrdering(t *testing.T) {
+	tests := []struct {
+		name    string
+		seats   []seat
+		actions []AbilityAction
+		killed  []uint
+	}{
+		{
+			name:    "a block recorded after the shot still stops it",
+			seats:   []seat{mafia("godfather", "godfather"), town("kidnapper", "kidnapper"), town("citizen")},
+			actions: []AbilityAction{use(1, "godfather", 3), use(2, "kidnapper", 1)},
+			killed:  []uint{},
+		},
+		{
+			name:    "a protection recorded after the shot still saves",
+			seats:   []seat{mafia("godfather", "godfather"), town("protector", "protector"), town("citizen")},
+			actions: []AbilityAction{use(1, "godfather", 3), use(2, "protector", 3)},
+			killed:  []uint{},
+		},
+		{
+			name:    "the godfather's shot wins over an earlier simple mafia shot",
+			seats:   []seat{mafia("godfather", "godfather"), mafia("simple_mafia", "simple_mafia"), town("citizen"), town("citizen")},
+			actions: []AbilityAction{use(2, "simple_mafia", 4), use(1, "godfather", 3)},
+			killed:  []uint{3},
+		},
+		{
+			name:    "a blocked godfather leaves the shot to the next in line",
+			seats:   []seat{mafia("godfather", "godfather"), mafia("nato", "nato"), town("citizen"), town("citizen"), town("kidnapper", "kidnapper")},
+			actions: []AbilityAction{use(1, "godfather", 3), use(2, "nato", 4), use(5, "kidnapper", 1)},
+			killed:  []uint{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestState(3, tt.seats...)
+			_, killed := resolve(g, "night", tt.actions...)
+			if !sameIDs(killed, tt.killed) {
+				t.Fatalf("killed %v, want %v", killed, tt.killed)
+			}
+		})
+	}
+}
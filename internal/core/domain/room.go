@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"fmt"
+	apperrors "mafia/pkg/errors"
+	"sort"
+)
+
+// Member roles within a room, from most to least privileged.
+const (
+	RoomRoleHost      = "host"
+	RoomRoleCoHost    = "co_host"
+	RoomRoleModerator = "moderator"
+	RoomRolePlayer    = "player"
+	RoomRoleSpectator = "spectator"
+)
+
+// Room permissions checked before controlling a room.
+const (
+	PermStart    = "start"
+	PermAdvance  = "advance"
+	PermKick     = "kick"
+	PermSettings = "settings"
+	PermRoles    = "roles"
+)
+
+var roomPermissions = map[string][]string{
+	RoomRoleHost:      {PermStart, PermAdvance, PermKick, PermSettings, PermRoles},
+	RoomRoleCoHost:    {PermStart, PermAdvance, PermKick, PermSettings},
+	RoomRoleModerator: {PermAdvance, PermKick},
+}
+
+var roomRoleRank = map[string]int{
+	RoomRoleHost:      4,
+	RoomRoleCoHost:    3,
+	RoomRoleModerator: 2,
+	RoomRolePlayer:    1,
+	RoomRoleSpectator: 0,
+}
+
+// RoleOf returns the user's role in the room, or an empty string for non-members.
+func (r *GameRoom) RoleOf(userID uint) string {
+	switch {
+	case userID != 0 && userID == r.HostID:
+		return RoomRoleHost
+	case containsID(r.CoHostIDs, userID):
+		return RoomRoleCoHost
+	case containsID(r.ModeratorIDs, userID):
+		return RoomRoleModerator
+	case r.HasPlayer(userID):
+		return RoomRolePlayer
+	case containsID(r.SpectatorIDs, userID):
+		return RoomRoleSpectator
+	}
+	return ""
+}
+
+// Can reports whether the user holds the permission in the room.
+func (r *GameRoom) Can(userID uint, permission string) bool {
+	for _, granted := range roomPermissions[r.RoleOf(userID)] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Outranks reports whether the actor's room role is strictly above the target's.
+func (r *GameRoom) Outranks(actorID, targetID uint) bool {
+	actor, target := r.RoleOf(actorID), r.RoleOf(targetID)
+	return actor != "" && (target == "" || roomRoleRank[actor] > roomRoleRank[target])
+}
+
+// HasPlayer reports whether the user has joined the room as a player.
+func (r *GameRoom) HasPlayer(userID uint) bool {
+	for _, p := range r.Players {
+		if p.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMemberRole grants the user a staff or spectator role, clearing any previous one.
+// Assigning RoomRolePlayer only removes the staff role.
+func (r *GameRoom) SetMemberRole(userID uint, role string) {
+	r.RemoveMember(userID)
+	switch role {
+	case RoomRoleCoHost:
+		r.CoHostIDs = append(r.CoHostIDs, userID)
+	case RoomRoleModerator:
+		r.ModeratorIDs = append(r.ModeratorIDs, userID)
+	case RoomRoleSpectator:
+		r.SpectatorIDs = append(r.SpectatorIDs, userID)
+	}
+}
+
+// RemoveMember drops the user from the co-host, moderator and spectator lists.
+func (r *GameRoom) RemoveMember(userID uint) {
+	r.CoHostIDs = removeID(r.CoHostIDs, userID)
+	r.ModeratorIDs = removeID(r.ModeratorIDs, userID)
+	r.SpectatorIDs = removeID(r.SpectatorIDs, userID)
+}
+
+// NextHost picks who inherits the room when the host leaves: the first co-host, then
+// the first moderator, then the remaining player with the lowest ID.
+func (r *GameRoom) NextHost() uint {
+	for _, candidates := range [][]uint{r.CoHostIDs, r.ModeratorIDs} {
+		for _, id := range candidates {
+			if id != r.HostID {
+				return id
+			}
+		}
+	}
+	var players []uint
+	for _, p := range r.Players {
+		if p.ID != r.HostID {
+			players = append(players, p.ID)
+		}
+	}
+	if len(players) == 0 {
+		return 0
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i] < players[j] })
+	return players[0]
+}
+
+func removeID(list []uint, id uint) []uint {
+	kept := make([]uint, 0, len(list))
+	for _, existing := range list {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
+
+// Participants returns the players who take part in the game, leaving out moderators
+// who narrate instead of playing.
+func (r *GameRoom) Participants() []User {
+	var players []User
+	for _, p := range r.Players {
+		if !containsID(r.ModeratorIDs, p.ID) {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// DropPlayer removes the user from the loaded player list.
+func (r *GameRoom) DropPlayer(userID uint) {
+	kept := make([]User, 0, len(r.Players))
+	for _, p := range r.Players {
+		if p.ID != userID {
+			kept = append(kept, p)
+		}
+	}
+	r.Players = kept
+}
+
+// VersionConflictError reports that a room was changed by someone else after it was
+// loaded, so an update based on the stale copy was rejected.
+type VersionConflictError struct {
+	RoomID  uint
+	Version int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("room %d was modified concurrently (version %d is stale)", e.RoomID, e.Version)
+}
+
+// Unwrap lets callers match the conflict with errors.Is(err, apperrors.ErrConflict).
+func (e *VersionConflictError) Unwrap() error {
+	return apperrors.ErrConflict
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// VillagerRole pads a scenario layout when it lists fewer roles than there are players.
+const VillagerRole = "Villager"
+
+// ScenarioBracket is the role layout a scenario uses for a range of player counts.
+type ScenarioBracket struct {
+	MinPlayers int      `json:"min_players"`
+	MaxPlayers int      `json:"max_players"`
+	Roles      []string `json:"roles"`
+}
+
+// Validate checks that the brackets cover sensible, non-overlapping player ranges and
+// that no phase lasts a negative time.
+func (s *Scenario) Validate() error {
+	for phase, seconds := range s.PhaseDurations {
+		if seconds < 0 {
+			return fmt.Errorf("invalid duration for %s phase", phase)
+		}
+	}
+	for i, b := range s.Brackets {
+		if b.MinPlayers <= 0 || b.MaxPlayers < b.MinPlayers {
+			return fmt.Errorf("bracket %d has an invalid player range", i)
+		}
+		if len(b.Roles) > b.MinPlayers {
+			return fmt.Errorf("bracket %d lists more roles than its minimum player count", i)
+		}
+		for j := 0; j < i; j++ {
+			other := s.Brackets[j]
+			if b.MinPlayers <= other.MaxPlayers && other.MinPlayers <= b.MaxPlayers {
+				return fmt.Errorf("brackets %d and %d overlap", j, i)
+			}
+		}
+	}
+	return nil
+}
+
+// RolesFor returns the role layout for the player count, padded with villagers. The
+// bracket covering the count is preferred over the scenario's default role list.
+func (s *Scenario) RolesFor(players int) ([]string, error) {
+	layout := s.Roles
+	for _, b := range s.Brackets {
+		if players >= b.MinPlayers && players <= b.MaxPlayers {
+			layout = b.Roles
+			break
+		}
+	}
+	if len(layout) > players {
+		return nil, fmt.Errorf("scenario %q needs at least %d players", s.Name, len(layout))
+	}
+	pool := append([]string{}, layout...)
+	for len(pool) < players {
+		pool = append(pool, VillagerRole)
+	}
+	return pool, nil
+}
+
+// ValidateDistribution checks that a dealt role pool is legal: every role exists, none
+// exceeds its MaxCount, and the mafia is present but outnumbered by everyone else.
+func ValidateDistribution(pool []string, roles map[string]Role) error {
+	counts := map[string]int{}
+	mafia := 0
+	for _, name := range pool {
+		role, ok := roles[name]
+		if !ok && name != VillagerRole {
+			return fmt.Errorf("unknown role %q", name)
+		}
+		counts[name]++
+		if role.MaxCount > 0 && counts[name] > role.MaxCount {
+			return fmt.Errorf("role %q exceeds its limit of %d", name, role.MaxCount)
+		}
+		if role.Team == "mafia" {
+			mafia++
+		}
+	}
+	if mafia == 0 {
+		return errors.New("role distribution has no mafia")
+	}
+	if mafia*2 >= len(pool) {
+		return errors.New("role distribution has too many mafia")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScenarioValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario Scenario
+		want     string
+	}{
+		{
+			name: "separate brackets",
+			scenario: Scenario{Brackets: []ScenarioBracket{
+				{MinPlayers: 6, MaxPlayers: 9, Roles: []string{"Godfather", "Doctor"}},
+				{MinPlayers: 10, MaxPlayers: 14, Roles: []string{"Godfather", "Mafia", "Doctor"}},
+			}},
+		},
+		{name: "an empty range", scenario: Scenario{Brackets: []ScenarioBracket{{MinPlayers: 9, MaxPlayers: 6}}}, want: "invalid player range"},
+		{name: "no players", scenario: Scenario{Brackets: []ScenarioBracket{{MinPlayers: 0, MaxPlayers: 6}}}, want: "invalid player range"},
+		{
+			name:     "more roles than the smallest table",
+			scenario: Scenario{Brackets: []ScenarioBracket{{MinPlayers: 2, MaxPlayers: 6, Roles: []string{"Godfather", "Doctor", "Detective"}}}},
+			want:     "more roles",
+		},
+		{
+			name: "overlapping brackets",
+			scenario: Scenario{Brackets: []ScenarioBracket{
+				{MinPlayers: 6, MaxPlayers: 10},
+				{MinPlayers: 10, MaxPlayers: 14},
+			}},
+			want: "overlap",
+		},
+		{name: "a negative phase", scenario: Scenario{PhaseDurations: PhaseDurations{"night": -1}}, want: "invalid duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scenario.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("rejected a valid scenario: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error about %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScenarioRolesFor(t *testing.T) {
+	s := Scenario{
+		Name:  "classic",
+		Roles: []string{"Godfather", "Doctor"},
+		Brackets: []ScenarioBracket{
+			{MinPlayers: 6, MaxPlayers: 8, Roles: []string{"Godfather", "Mafia", "Doctor"}},
+		},
+	}
+	tests := []struct {
+		name    string
+		players int
+		want    []string
+		wantErr bool
+	}{
+		{name: "the covering bracket", players: 6, want: []string{"Godfather", "Mafia", "Doctor", VillagerRole, VillagerRole, VillagerRole}},
+		{name: "the default roles outside every bracket", players: 4, want: []string{"Godfather", "Doctor", VillagerRole, VillagerRole}},
+		{name: "too few players for the default roles", players: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.RolesFor(tt.players)
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDistribution(t *testing.T) {
+	roles := map[string]Role{
+		"Godfather": {Name: "Godfather", Team: "mafia", MaxCount: 1},
+		"Mafia":     {Name: "Mafia", Team: "mafia"},
+		"Doctor":    {Name: "Doctor", Team: "town", MaxCount: 1},
+	}
+	tests := []struct {
+		name string
+		pool []string
+		want string
+	}{
+		{name: "a legal table", pool: []string{"Godfather", "Doctor", VillagerRole, VillagerRole, VillagerRole}},
+		{name: "an unknown role", pool: []string{"Godfather", "Juggler", VillagerRole}, want: "unknown role"},
+		{name: "a role over its limit", pool: []string{"Godfather", "Doctor", "Doctor", VillagerRole, VillagerRole}, want: "exceeds its limit"},
+		{name: "no mafia", pool: []string{"Doctor", VillagerRole, VillagerRole}, want: "no mafia"},
+		{name: "mafia at half the table", pool: []string{"Godfather", "Mafia", "Doctor", VillagerRole}, want: "too many mafia"},
+		{name: "mafia just under half", pool: []string{"Godfather", "Mafia", "Doctor", VillagerRole, VillagerRole}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDistribution(tt.pool, roles)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("rejected a legal pool: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error about %q", err, tt.want)
+			}
+		})
+	}
+}
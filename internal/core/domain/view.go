@@ -0,0 +1,90 @@
+package domain
+
+// PublicDeath is a death as announced to the table, without who caused it.
+type PublicDeath struct {
+	UserID uint   `json:"user_id"`
+	Day    int    `json:"day"`
+	Phase  string `json:"phase"`
+	Cause  string `json:"cause"`
+}
+
+// Teammate is a fellow mafia member revealed to the mafia.
+type Teammate struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+	Alive  bool   `json:"alive"`
+}
+
+// PlayerView is the part of the game state a single user is allowed to see. State is
+// only filled in for moderators and once the game is over.
+type PlayerView struct {
+	Phase          string                `json:"phase"`
+	DayCount       int                   `json:"day_count"`
+	Assignment     *PlayerAssignment     `json:"assignment,omitempty"`
+	Teammates      []Teammate            `json:"teammates,omitempty"`
+	Alive          map[uint]bool         `json:"alive"`
+	Controllers    map[uint]string       `json:"controllers,omitempty"`
+	Deaths         []PublicDeath         `json:"deaths"`
+	Investigations []InvestigationResult `json:"investigations"`
+	Ballot         Ballot                `json:"ballot"`
+	Votes          []VoteLog             `json:"votes"`
+	Days           []DaySummary          `json:"days"`
+	Turn           *SpeakingTurn         `json:"turn,omitempty"`
+	Queue          []SpeakingSlot        `json:"queue,omitempty"`
+	Result         *GameResult           `json:"result,omitempty"`
+	State          *GameState            `json:"state,omitempty"`
+}
+
+// ViewFor projects the state for a user. With full set, the complete state is attached.
+func (g *GameState) ViewFor(userID uint, full bool) PlayerView {
+	view := PlayerView{
+		Phase:          g.Phase,
+		DayCount:       g.DayCount,
+		Alive:          map[uint]bool{},
+		Deaths:         []PublicDeath{},
+		Investigations: []InvestigationResult{},
+		Ballot:         g.Ballot,
+		Votes:          g.Votes,
+		Days:           g.Days,
+		Turn:           g.Turn,
+		Queue:          g.Queue(),
+		Result:         g.Result,
+	}
+	if full {
+		view.State = g
+	}
+
+	for _, id := range g.PlayerIDs() {
+		player := g.Assignments[id]
+		view.Alive[id] = player.Alive
+		if player.Controller != "" {
+			if view.Controllers == nil {
+				view.Controllers = map[uint]string{}
+			}
+			view.Controllers[id] = player.Controller
+		}
+	}
+	for _, death := range g.Deaths {
+		view.Deaths = append(view.Deaths, PublicDeath{UserID: death.UserID, Day: death.Day, Phase: death.Phase, Cause: death.Cause})
+	}
+
+	player, ok := g.Assignments[userID]
+	if !ok {
+		return view
+	}
+	view.Assignment = &player
+	for _, result := range g.Investigations {
+		if result.Investigator == userID {
+			view.Investigations = append(view.Investigations, result)
+		}
+	}
+	if player.Team == "mafia" {
+		for _, id := range g.PlayerIDs() {
+			mate := g.Assignments[id]
+			if id != userID && mate.Team == "mafia" {
+				view.Teammates = append(view.Teammates, Teammate{UserID: id, Role: mate.Role, Alive: mate.Alive})
+			}
+		}
+	}
+	return view
+}
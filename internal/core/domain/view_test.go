@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestViewFor(t *testing.T) {
+	g := newTestState(2, mafia("godfather", "godfather"), mafia("simple_mafia", "simple_mafia"), town("detective", "detective"), dead(town("citizen")))
+	g.Deaths[0].By = 1
+	g.Investigations = []InvestigationResult{{Day: 1, Investigator: 3, Target: 2, Ability: "detective", Team: "mafia"}}
+	const spectator = 9
+
+	tests := []struct {
+		name           string
+		userID         uint
+		full           bool
+		role           string
+		teammates      []Teammate
+		investigations int
+		fullState      bool
+		living         bool
+	}{
+		{name: "alive player sees their role and own investigations", userID: 3, role: "detective", investigations: 1, living: true},
+		{name: "dead player keeps their role but learns nothing more", userID: 4, role: "citizen"},
+		{name: "mafia sees their teammates", userID: 1, role: "godfather", teammates: []Teammate{{UserID: 2, Role: "simple_mafia", Alive: true}}, living: true},
+		{name: "spectator sees only the table", userID: spectator},
+		{name: "narrator gets the full state", userID: spectator, full: true, fullState: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := g.ViewFor(tt.userID, tt.full)
+
+			if tt.role == "" {
+				if view.Assignment != nil {
+					t.Fatalf("non-player sees assignment %+v", view.Assignment)
+				}
+			} else if view.Assignment == nil || view.Assignment.Role != tt.role || view.Assignment.Alive != tt.living {
+				t.Fatalf("assignment %+v, want role %s alive %v", view.Assignment, tt.role, tt.living)
+			}
+			if !reflect.DeepEqual(view.Teammates, tt.teammates) {
+				t.Fatalf("teammates %+v, want %+v", view.Teammates, tt.teammates)
+			}
+			if len(view.Investigations) != tt.investigations {
+				t.Fatalf("sees %d investigations, want %d", len(view.Investigations), tt.investigations)
+			}
+			if (view.State != nil) != tt.fullState {
+				t.Fatalf("full state attached: %v, want %v", view.State != nil, tt.fullState)
+			}
+
+			// Everyone sees who is alive and the deaths, but never who caused them.
+			table := map[uint]bool{1: true, 2: true, 3: true, 4: false}
+			if !reflect.DeepEqual(view.Alive, table) {
+				t.Fatalf("alive %v, want %v", view.Alive, table)
+			}
+			deaths := []PublicDeath{{UserID: 4, Day: 1, Phase: "night", Cause: "shot"}}
+			if !reflect.DeepEqual(view.Deaths, deaths) {
+				t.Fatalf("deaths %+v, want %+v", view.Deaths, deaths)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Voice channels of a room. The living talk at the table, the dead in the graveyard and
+// the living mafia among themselves at night.
+const (
+	VoiceTable     = "table"
+	VoiceGraveyard = "graveyard"
+	VoiceMafia     = "mafia"
+)
+
+// MaxSpeakingTurn caps how long the narrator can hand the floor to one player.
+const MaxSpeakingTurn = 5 * time.Minute
+
+// SpeakingTurn gives a player the table until EndsAt; the other players are muted
+// meanwhile. GrantedBy is zero for turns taken from the speaking queue.
+type SpeakingTurn struct {
+	UserID    uint      `json:"user_id"`
+	Kind      string    `json:"kind"`
+	GrantedBy uint      `json:"granted_by,omitempty"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// VoiceGrant lists the channels a member is heard on and listens to. Override keeps the
+// member audible while a player holds the floor.
+type VoiceGrant struct {
+	Speaks   []string `json:"speaks"`
+	Hears    []string `json:"hears"`
+	Override bool     `json:"override,omitempty"`
+}
+
+// VoiceRules say who hears whom in a room.
+type VoiceRules struct {
+	RoomID uint                `json:"room_id"`
+	Grants map[uint]VoiceGrant `json:"grants"`
+	Turn   *SpeakingTurn       `json:"turn,omitempty"`
+}
+
+// SpeakingTurnChanged announces the player given the floor, or a nil turn when the
+// floor is open again, with the turns still queued after it.
+type SpeakingTurnChanged struct {
+	RoomID uint           `json:"room_id"`
+	Turn   *SpeakingTurn  `json:"turn"`
+	Queue  []SpeakingSlot `json:"queue,omitempty"`
+}
+
+// VoiceRulesFor derives the voice rules of a room from its game. Before the game starts
+// and after it ends everyone talks at the table. During the game:
+//   - staff who are not playing narrate: they hear every channel and are heard on all
+//     of them, even while a player holds the floor;
+//   - the dead talk in the graveyard and still listen to the table;
+//   - spectators only listen, to the table and the graveyard;
+//   - at night the living mafia talk among themselves and everyone else only hears the
+//     narrator;
+//   - during the day the living talk at the table, except those silenced for the day.
+func VoiceRulesFor(room *GameRoom, state *GameState) VoiceRules {
+	rules := VoiceRules{RoomID: room.ID, Grants: map[uint]VoiceGrant{}}
+	members := append([]uint{room.HostID}, room.CoHostIDs...)
+	members = append(members, room.ModeratorIDs...)
+	members = append(members, room.SpectatorIDs...)
+	for _, player := range room.Players {
+		members = append(members, player.ID)
+	}
+
+	playing := state != nil && room.Status == "playing"
+	for _, userID := range members {
+		if userID == 0 {
+			continue
+		}
+		if !playing {
+			rules.Grants[userID] = VoiceGrant{Speaks: []string{VoiceTable}, Hears: []string{VoiceTable}}
+			continue
+		}
+		rules.Grants[userID] = state.voiceGrant(room, userID)
+	}
+	if playing && state.Phase != "night" && state.Turn != nil {
+		turn := *state.Turn
+		rules.Turn = &turn
+	}
+	return rules
+}
+
+func (g *GameState) voiceGrant(room *GameRoom, userID uint) VoiceGrant {
+	player, playing := g.Assignments[userID]
+	switch {
+	case !playing && room.Can(userID, PermAdvance):
+		all := []string{VoiceTable, VoiceGraveyard, VoiceMafia}
+		return VoiceGrant{Speaks: all, Hears: all, Override: true}
+	case !playing:
+		return VoiceGrant{Hears: []string{VoiceTable, VoiceGraveyard}}
+	case !player.Alive:
+		return VoiceGrant{Speaks: []string{VoiceGraveyard}, Hears: []string{VoiceTable, VoiceGraveyard}}
+	case g.Phase == "night" && player.Team == "mafia":
+		return VoiceGrant{Speaks: []string{VoiceMafia}, Hears: []string{VoiceTable, VoiceMafia}}
+	case g.Phase == "night":
+		return VoiceGrant{Hears: []string{VoiceTable}}
+	case g.HasEffect(userID, EffectSilenced):
+		return VoiceGrant{Hears: []string{VoiceTable}}
+	}
+	return VoiceGrant{Speaks: []string{VoiceTable}, Hears: []string{VoiceTable}}
+}
+
+// GiveTurn hands the table to a living player for the given time, replacing any turn
+// in progress. The speaking queue carries on with its next turn afterwards.
+func (g *GameState) GiveTurn(grantedBy, userID uint, d time.Duration, now time.Time) (SpeakingTurn, error) {
+	if g.Phase == "night" {
+		return SpeakingTurn{}, errors.New("speaking turns are only given during the day")
+	}
+	if !g.isAlive(userID) {
+		return SpeakingTurn{}, errors.New("player not active in this room")
+	}
+	if g.HasEffect(userID, EffectSilenced) {
+		return SpeakingTurn{}, errors.New("player is silenced for the day")
+	}
+	if d < time.Second || d > MaxSpeakingTurn {
+		return SpeakingTurn{}, errors.New("speaking turn must last between one second and five minutes")
+	}
+	turn := SpeakingTurn{UserID: userID, Kind: TurnNarrator, GrantedBy: grantedBy, EndsAt: now.Add(d)}
+	g.Turn = &turn
+	return turn, nil
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVoiceRulesFor(t *testing.T) {
+	const narrator, spectator = 100, 200
+	room := &GameRoom{
+		ID:           7,
+		HostID:       narrator,
+		Status:       "playing",
+		SpectatorIDs: []uint{spectator},
+		Players:      []User{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}},
+	}
+	newState := func(phase string) *GameState {
+		g := newTestState(2, mafia("godfather"), dead(mafia("simple_mafia")), town("citizen"), dead(town("citizen")), town("citizen"))
+		g.Phase = phase
+		g.AddEffect(StatusEffect{Target: 5, Code: EffectSilenced, Day: 2, Until: 2})
+		return g
+	}
+
+	all := []string{VoiceTable, VoiceGraveyard, VoiceMafia}
+	narrating := VoiceGrant{Speaks: all, Hears: all, Override: true}
+	watching := VoiceGrant{Hears: []string{VoiceTable, VoiceGraveyard}}
+	graveyard := VoiceGrant{Speaks: []string{VoiceGraveyard}, Hears: []string{VoiceTable, VoiceGraveyard}}
+	table := VoiceGrant{Speaks: []string{VoiceTable}, Hears: []string{VoiceTable}}
+	listening := VoiceGrant{Hears: []string{VoiceTable}}
+
+	tests := []struct {
+		name   string
+		phase  string
+		grants map[uint]VoiceGrant
+	}{
+		{
+			name:  "night",
+			phase: "night",
+			grants: map[uint]VoiceGrant{
+				narrator:  narrating,
+				spectator: watching,
+				1:         {Speaks: []string{VoiceMafia}, Hears: []string{VoiceTable, VoiceMafia}},
+				2:         graveyard,
+				3:         listening,
+				4:         graveyard,
+				5:         listening,
+			},
+		},
+		{
+			name:  "day",
+			phase: "day",
+			grants: map[uint]VoiceGrant{
+				narrator:  narrating,
+				spectator: watching,
+				1:         table,
+				2:         graveyard,
+				3:         table,
+				4:         graveyard,
+				5:         listening,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := VoiceRulesFor(room, newState(tt.phase))
+			for userID, want := range tt.grants {
+				if got := rules.Grants[userID]; !reflect.DeepEqual(got, want) {
+					t.Errorf("member %d: %+v, want %+v", userID, got, want)
+				}
+			}
+			if len(rules.Grants) != len(tt.grants) {
+				t.Errorf("granted %d members, want %d", len(rules.Grants), len(tt.grants))
+			}
+		})
+	}
+
+	t.Run("only the living mafia and the narrator are heard on the mafia channel", func(t *testing.T) {
+		rules := VoiceRulesFor(room, newState("night"))
+		var speakers, listeners []uint
+		for _, userID := range []uint{1, 2, 3, 4, 5, narrator, spectator} {
+			grant := rules.Grants[userID]
+			if containsString(grant.Speaks, VoiceMafia) {
+				speakers = append(speakers, userID)
+			}
+			if containsString(grant.Hears, VoiceMafia) {
+				listeners = append(listeners, userID)
+			}
+		}
+		if !sameIDs(speakers, []uint{1, narrator}) || !sameIDs(listeners, []uint{1, narrator}) {
+			t.Fatalf("mafia channel spoken by %v and heard by %v, want 1 and the narrator", speakers, listeners)
+		}
+	})
+
+	t.Run("everyone talks at the table outside a game", func(t *testing.T) {
+		waiting := *room
+		waiting.Status = "waiting"
+		for userID, grant := range VoiceRulesFor(&waiting, nil).Grants {
+			if !reflect.DeepEqual(grant, table) {
+				t.Errorf("member %d: %+v, want the table", userID, grant)
+			}
+		}
+	})
+
+	t.Run("the floor is only held during the day", func(t *testing.T) {
+		turn := &SpeakingTurn{UserID: 3, Kind: TurnNarrator, EndsAt: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)}
+		day, night := newState("day"), newState("night")
+		day.Turn, night.Turn = turn, turn
+		if rules := VoiceRulesFor(room, day); rules.Turn == nil || *rules.Turn != *turn {
+			t.Fatalf("day turn %+v, want %+v", rules.Turn, turn)
+		}
+		if rules := VoiceRulesFor(room, night); rules.Turn != nil {
+			t.Fatalf("night turn %+v, want none", rules.Turn)
+		}
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+)
+
+// Day vote outcomes recorded in DaySummary.
+const (
+	OutcomeEliminated = "eliminated"
+	OutcomeNoVotes    = "no_votes"
+	OutcomeTie        = "tie"
+	OutcomeRevote     = "revote"
+	OutcomeDefense    = "defense"
+	OutcomeSpared     = "spared"
+)
+
+// Ballot describes the vote currently open during the day.
+type Ballot struct {
+	Round      int    `json:"round"`
+	Candidates []uint `json:"candidates,omitempty"`
+}
+
+// DaySummary captures the outcome of a single day vote round.
+type DaySummary struct {
+	Day        int          `json:"day"`
+	Round      int          `json:"round"`
+	Tally      map[uint]int `json:"tally"`
+	Candidates []uint       `json:"candidates,omitempty"`
+	Eliminated uint         `json:"eliminated,omitempty"`
+	Outcome    string       `json:"outcome"`
+}
+
+// CastVote records a vote for the open ballot, replacing any earlier vote by the same voter.
+func (g *GameState) CastVote(vote VoteLog) error {
+	if len(g.Ballot.Candidates) > 0 && !containsID(g.Ballot.Candidates, vote.Target) {
+		return errors.New("target is not on the ballot")
+	}
+	vote.Day = g.DayCount
+	vote.Round = g.Ballot.Round
+	g.record(EventVoteCast, vote)
+	for i, existing := range g.Votes {
+		if existing.Voter == vote.Voter && existing.Day == vote.Day && existing.Round == vote.Round {
+			g.Votes[i] = vote
+			return nil
+		}
+	}
+	g.Votes = append(g.Votes, vote)
+	return nil
+}
+
+// TallyVotes counts the latest vote of each living voter for the open ballot, eliminates
+// the target with the most votes and applies the configured tie-break. pick is used to
+// choose among tied candidates when the tie-break is random.
+func (g *GameState) TallyVotes(pick func(n int) int) DaySummary {
+	summary := DaySummary{Day: g.DayCount, Round: g.Ballot.Round, Tally: g.currentTally()}
+
+	var leaders []uint
+	best := 0
+	for target, count := range summary.Tally {
+		switch {
+		case count > best:
+			best = count
+			leaders = []uint{target}
+		case count == best:
+			leaders = append(leaders, target)
+		}
+	}
+	sort.Slice(leaders, func(i, j int) bool { return leaders[i] < leaders[j] })
+
+	switch {
+	case best == 0:
+		summary.Outcome = OutcomeNoVotes
+	case len(leaders) == 1:
+		summary.Eliminated = leaders[0]
+	default:
+		summary.Candidates = leaders
+		summary.Outcome = OutcomeTie
+		// Tie-break rounds are only granted once per day.
+		if g.Ballot.Round == 0 {
+			switch g.Settings.TieBreak {
+			case TieRevote:
+				summary.Outcome = OutcomeRevote
+			case TieDefense:
+				summary.Outcome = OutcomeDefense
+			case TieRandom:
+				summary.Eliminated = leaders[pick(len(leaders))]
+			}
+		}
+	}
+
+	if summary.Eliminated != 0 {
+		if g.HasEffect(summary.Eliminated, EffectVoteImmune) {
+			summary.Outcome = OutcomeSpared
+		} else {
+			summary.Outcome = OutcomeEliminated
+			g.Kill(summary.Eliminated, "day", "voted", 0)
+		}
+	}
+
+	if summary.Outcome == OutcomeRevote || summary.Outcome == OutcomeDefense {
+		g.Ballot = Ballot{Round: g.Ballot.Round + 1, Candidates: leaders}
+	} else {
+		g.Ballot = Ballot{}
+	}
+	g.Days = append(g.Days, summary)
+	return summary
+}
+
+// currentTally counts the latest vote per living voter for the open ballot. Silenced
+// voters are ignored and swayed voters follow the vote of whoever swayed them.
+func (g *GameState) currentTally() map[uint]int {
+	latest := map[uint]VoteLog{}
+	for _, vote := range g.Votes {
+		if vote.Day != g.DayCount || vote.Round != g.Ballot.Round {
+			continue
+		}
+		if prev, ok := latest[vote.Voter]; ok && prev.Timestamp.After(vote.Timestamp) {
+			continue
+		}
+		latest[vote.Voter] = vote
+	}
+
+	tally := map[uint]int{}
+	for voter, vote := range latest {
+		if !g.isAlive(voter) || g.HasEffect(voter, EffectSilenced) {
+			continue
+		}
+		target := vote.Target
+		if sway, ok := g.FindEffect(voter, EffectSwayed); ok {
+			if swayer, voted := latest[sway.Source]; voted {
+				target = swayer.Target
+			}
+		}
+		if !g.isAlive(target) {
+			continue
+		}
+		tally[target]++
+	}
+	return tally
+}
+
+func (g *GameState) isAlive(userID uint) bool {
+	player, ok := g.Assignments[userID]
+	return ok && player.Alive
+}
+
+func containsID(list []uint, id uint) bool {
+	for _, existing := range list {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTallyVotes(t *testing.T) {
+	citizens := []seat{town("citizen"), town("citizen"), town("citizen"), town("citizen"), town("citizen")}
+	tests := []struct {
+		name       string
+		seats      []seat
+		tieBreak   string
+		setup      func(g *GameState)
+		votes      [][2]uint
+		outcome    string
+		eliminated uint
+		candidates []uint
+		ballot     Ballot
+	}{
+		{name: "the most votes eliminate", votes: [][2]uint{{1, 3}, {2, 3}, {4, 5}}, outcome: OutcomeEliminated, eliminated: 3},
+		{name: "no votes eliminate nobody", outcome: OutcomeNoVotes},
+		{name: "a changed vote counts once", votes: [][2]uint{{1, 3}, {2, 4}, {1, 4}}, outcome: OutcomeEliminated, eliminated: 4},
+		{
+			name:       "dead voters and dead targets are not counted",
+			seats:      []seat{town("citizen"), town("citizen"), town("citizen"), town("citizen"), dead(town("citizen"))},
+			votes:      [][2]uint{{5, 3}, {1, 5}, {3, 5}, {2, 4}},
+			outcome:    OutcomeEliminated,
+			eliminated: 4,
+		},
+		{
+			name:       "silenced voters are not counted",
+			setup:      func(g *GameState) { g.AddEffect(StatusEffect{Target: 1, Code: EffectSilenced, Day: 1}) },
+			votes:      [][2]uint{{1, 3}, {2, 4}},
+			outcome:    OutcomeEliminated,
+			eliminated: 4,
+		},
+		{
+			name:       "swayed voters follow whoever swayed them",
+			setup:      func(g *GameState) { g.AddEffect(StatusEffect{Target: 1, Code: EffectSwayed, Source: 2, Day: 1}) },
+			votes:      [][2]uint{{1, 3}, {2, 4}, {5, 3}},
+			outcome:    OutcomeEliminated,
+			eliminated: 4,
+		},
+		{
+			name:       "vote immune leaders are spared",
+			setup:      func(g *GameState) { g.AddEffect(StatusEffect{Target: 3, Code: EffectVoteImmune, Day: 1}) },
+			votes:      [][2]uint{{1, 3}, {2, 3}},
+			outcome:    OutcomeSpared,
+			eliminated: 3,
+		},
+		{name: "ties eliminate nobody by default", tieBreak: TieNoElimination, votes: [][2]uint{{1, 3}, {2, 4}}, outcome: OutcomeTie, candidates: []uint{3, 4}},
+		{
+			name:       "ties go to a revote between the leaders",
+			tieBreak:   TieRevote,
+			votes:      [][2]uint{{1, 4}, {2, 3}},
+			outcome:    OutcomeRevote,
+			candidates: []uint{3, 4},
+			ballot:     Ballot{Round: 1, Candidates: []uint{3, 4}},
+		},
+		{
+			name:       "ties go to a defense between the leaders",
+			tieBreak:   TieDefense,
+			votes:      [][2]uint{{1, 3}, {2, 4}},
+			outcome:    OutcomeDefense,
+			candidates: []uint{3, 4},
+			ballot:     Ballot{Round: 1, Candidates: []uint{3, 4}},
+		},
+		{name: "ties pick a random leader", tieBreak: TieRandom, votes: [][2]uint{{1, 3}, {2, 4}}, outcome: OutcomeEliminated, eliminated: 4, candidates: []uint{3, 4}},
+		{
+			name:       "a tied revote is not broken again",
+			tieBreak:   TieRevote,
+			setup:      func(g *GameState) { g.Ballot = Ballot{Round: 1, Candidates: []uint{3, 4}} },
+			votes:      [][2]uint{{1, 3}, {2, 4}},
+			outcome:    OutcomeTie,
+			candidates: []uint{3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seats := tt.seats
+			if seats == nil {
+				seats = citizens
+			}
+			g := newTestState(1, seats...)
+			g.Phase, g.Settings.TieBreak = "day", tt.tieBreak
+			if tt.setup != nil {
+				tt.setup(g)
+			}
+			at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, v := range tt.votes {
+				if err := g.CastVote(VoteLog{Voter: v[0], Target: v[1], Phase: "day", Timestamp: at.Add(time.Duration(i) * time.Second)}); err != nil {
+					t.Fatalf("vote %v: %v", v, err)
+				}
+			}
+
+			summary := g.TallyVotes(func(n int) int { return n - 1 })
+			if summary.Outcome != tt.outcome || summary.Eliminated != tt.eliminated || !sameIDs(summary.Candidates, tt.candidates) {
+				t.Fatalf("got %s of %d among %v, want %s of %d among %v", summary.Outcome, summary.Eliminated, summary.Candidates, tt.outcome, tt.eliminated, tt.candidates)
+			}
+			if !reflect.DeepEqual(g.Ballot, tt.ballot) {
+				t.Fatalf("next ballot %+v, want %+v", g.Ballot, tt.ballot)
+			}
+			if alive := tt.outcome != OutcomeEliminated; tt.eliminated != 0 && g.isAlive(tt.eliminated) != alive {
+				t.Fatalf("player %d alive %v, want %v", tt.eliminated, !alive, alive)
+			}
+			if len(g.Days) != 1 {
+				t.Fatalf("recorded %d day summaries, want 1", len(g.Days))
+			}
+		})
+	}
+}
+
+func TestCastVoteKeepsToTheBallot(t *testing.T) {
+	g := newTestState(1, town("citizen"), town("citizen"), town("citizen"), town("citizen"))
+	g.Ballot = Ballot{Round: 1, Candidates: []uint{3, 4}}
+
+	if err := g.CastVote(VoteLog{Voter: 1, Target: 2}); err == nil {
+		t.Fatal("accepted a vote for a player off the ballot")
+	}
+	if err := g.CastVote(VoteLog{Voter: 1, Target: 3}); err != nil {
+		t.Fatalf("vote on the ballot: %v", err)
+	}
+	if err := g.CastVote(VoteLog{Voter: 1, Target: 4}); err != nil {
+		t.Fatalf("changed vote: %v", err)
+	}
+	if len(g.Votes) != 1 || g.Votes[0].Target != 4 || g.Votes[0].Round != 1 {
+		t.Fatalf("votes %+v, want one round 1 vote for 4", g.Votes)
+	}
+}
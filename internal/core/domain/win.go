@@ -0,0 +1,77 @@
+package domain
+
+// Winners reported on a finished game.
+const (
+	WinnerTown    = "town"
+	WinnerMafia   = "mafia"
+	WinnerNeutral = "neutral"
+	WinnerDraw    = "draw"
+)
+
+// GameResult summarizes how a game ended.
+type GameResult struct {
+	Winner  string `json:"winner"`
+	Reason  string `json:"reason"`
+	Day     int    `json:"day"`
+	Players []uint `json:"players"`
+}
+
+// EvaluateWinner checks the win conditions against the living players and records the
+// result once one side has won. It returns nil while the game is still undecided.
+func (g *GameState) EvaluateWinner() *GameResult {
+	if g.Result != nil {
+		return g.Result
+	}
+
+	var mafia, town, neutral int
+	for _, player := range g.Assignments {
+		if !player.Alive {
+			continue
+		}
+		switch player.Team {
+		case "mafia":
+			mafia++
+		case "neutral":
+			neutral++
+		default:
+			town++
+		}
+	}
+
+	result := &GameResult{Day: g.DayCount}
+	switch {
+	case mafia+town+neutral == 0:
+		result.Winner, result.Reason = WinnerDraw, "no players left alive"
+	case mafia == 0 && town == 0:
+		result.Winner, result.Reason = WinnerNeutral, "only neutral players remain"
+	case mafia == 0:
+		result.Winner, result.Reason = WinnerTown, "all mafia eliminated"
+	case mafia >= town+neutral:
+		result.Winner, result.Reason = WinnerMafia, "mafia reached parity"
+	default:
+		return nil
+	}
+
+	result.Players = g.winningPlayers(result.Winner)
+	g.Result = result
+	g.record(EventGameFinished, result)
+	return result
+}
+
+// winningPlayers lists the players sharing a win: members of the winning team, living
+// neutrals on a neutral win, and Nostradamus players who predicted the winning side.
+func (g *GameState) winningPlayers(winner string) []uint {
+	var players []uint
+	for _, id := range g.PlayerIDs() {
+		player := g.Assignments[id]
+		switch {
+		case winner == WinnerNeutral && player.Team == "neutral" && player.Alive:
+			players = append(players, id)
+		case winner != WinnerNeutral && player.Team == winner:
+			players = append(players, id)
+		case g.Predictions[id] != "" && g.Predictions[id] == winner:
+			players = append(players, id)
+		}
+	}
+	return players
+}
@@ -0,0 +1,87 @@
+package domain
+
+import "testing"
+
+func neutral(role string) seat {
+	return seat{role: role, team: "neutral"}
+}
+
+func TestEvaluateWinner(t *testing.T) {
+	tests := []struct {
+		name        string
+		seats       []seat
+		predictions map[uint]string
+		winner      string
+		players     []uint
+	}{
+		{
+			name:    "town wins once the mafia is gone",
+			seats:   []seat{dead(mafia("godfather")), town("citizen"), dead(town("detective"))},
+			winner:  WinnerTown,
+			players: []uint{2, 3},
+		},
+		{
+			name:    "mafia wins at parity",
+			seats:   []seat{mafia("godfather"), mafia("simple_mafia"), town("citizen"), town("citizen")},
+			winner:  WinnerMafia,
+			players: []uint{1, 2},
+		},
+		{
+			name:    "mafia wins above parity",
+			seats:   []seat{mafia("godfather"), mafia("simple_mafia"), town("citizen"), dead(town("citizen"))},
+			winner:  WinnerMafia,
+			players: []uint{1, 2},
+		},
+		{
+			name:  "the game goes on while the town outnumbers the mafia",
+			seats: []seat{mafia("godfather"), town("citizen"), town("citizen")},
+		},
+		{
+			name:  "neutrals count against mafia parity",
+			seats: []seat{mafia("godfather"), mafia("simple_mafia"), town("citizen"), neutral("killer"), neutral("nostradamus")},
+		},
+		{
+			name:    "living neutrals win alone",
+			seats:   []seat{dead(mafia("godfather")), dead(town("citizen")), neutral("killer"), dead(neutral("killer"))},
+			winner:  WinnerNeutral,
+			players: []uint{3},
+		},
+		{
+			name:   "nobody left alive is a draw",
+			seats:  []seat{dead(mafia("godfather")), dead(town("citizen"))},
+			winner: WinnerDraw,
+		},
+		{
+			name:        "a correct prediction shares the win",
+			seats:       []seat{mafia("godfather"), town("citizen"), dead(neutral("nostradamus")), dead(neutral("nostradamus"))},
+			predictions: map[uint]string{3: WinnerMafia, 4: WinnerTown},
+			winner:      WinnerMafia,
+			players:     []uint{1, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestState(3, tt.seats...)
+			g.Predictions = tt.predictions
+			result := g.EvaluateWinner()
+			if tt.winner == "" {
+				if result != nil {
+					t.Fatalf("decided %+v, want the game to go on", result)
+				}
+				return
+			}
+			if result == nil || result.Winner != tt.winner || result.Day != 3 || !sameIDs(result.Players, tt.players) {
+				t.Fatalf("got %+v, want %s won by %v", result, tt.winner, tt.players)
+			}
+		})
+	}
+}
+
+func TestEvaluateWinnerIsFinal(t *testing.T) {
+	g := newTestState(2, mafia("godfather"), town("citizen"))
+	first := g.EvaluateWinner()
+	g.Kill(1, "day", "voted", 0)
+	if again := g.EvaluateWinner(); again != first || again.Winner != WinnerMafia {
+		t.Fatalf("result changed to %+v after the game was decided", again)
+	}
+}
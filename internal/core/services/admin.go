@@ -94,7 +94,10 @@ func (s *adminService) ListRules() ([]domain.GameRule, error) {
 }
 
 func (s *adminService) CreateScenario(req domain.ScenarioRequest) (*domain.Scenario, error) {
-	scenario := domain.Scenario{Name: req.Name, Description: req.Description, Rules: req.Rules, Roles: req.Roles}
+	scenario := domain.Scenario{Name: req.Name, Description: req.Description, Rules: req.Rules, Roles: req.Roles, Brackets: req.Brackets, PhaseDurations: req.PhaseDurations}
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
 	if err := s.scenarioRepo.Create(&scenario); err != nil {
 		return nil, err
 	}
@@ -120,6 +123,11 @@ func (s *adminService) UpdateScenario(id uint, req domain.ScenarioRequest) (*dom
 	target.Description = req.Description
 	target.Rules = req.Rules
 	target.Roles = req.Roles
+	target.Brackets = req.Brackets
+	target.PhaseDurations = req.PhaseDurations
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
 	if err := s.scenarioRepo.Update(target); err != nil {
 		return nil, err
 	}
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"context"
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
 )
@@ -9,12 +8,11 @@ import (
 type challengeService struct {
 	challengeRepo ports.ChallengeRepository
 	userRepo      ports.UserRepository
-	walletRepo    ports.WalletRepository
-	events        ports.EventBus
+	tx            ports.Transactor
 }
 
-func NewChallengeService(challengeRepo ports.ChallengeRepository, userRepo ports.UserRepository, walletRepo ports.WalletRepository, events ports.EventBus) ports.ChallengeService {
-	return &challengeService{challengeRepo: challengeRepo, userRepo: userRepo, walletRepo: walletRepo, events: events}
+func NewChallengeService(challengeRepo ports.ChallengeRepository, userRepo ports.UserRepository, tx ports.Transactor) ports.ChallengeService {
+	return &challengeService{challengeRepo: challengeRepo, userRepo: userRepo, tx: tx}
 }
 
 func (s *challengeService) List() ([]domain.Challenge, error) {
@@ -26,17 +24,18 @@ func (s *challengeService) Complete(challengeID, userID uint) error {
 	if err != nil {
 		return err
 	}
-	wallet, err := s.walletRepo.FindByUserID(userID)
-	if err != nil {
-		return err
-	}
-	wallet.Coins += challenge.RewardCoins
-	wallet.Diamonds += challenge.RewardDiamonds
-	if err := s.walletRepo.Update(wallet); err != nil {
-		return err
-	}
-	if s.events != nil {
-		s.events.Publish(context.Background(), "challenge.completed", map[string]uint{"challenge_id": challengeID, "user_id": userID})
-	}
-	return nil
+	// The reward and its event commit together, so the event is sent exactly when the
+	// reward was paid.
+	return s.tx.Transact(func(tx ports.Repositories) error {
+		wallet, err := tx.Wallet.FindByUserID(userID)
+		if err != nil {
+			return err
+		}
+		wallet.Coins += challenge.RewardCoins
+		wallet.Diamonds += challenge.RewardDiamonds
+		if err := tx.Wallet.Update(wallet); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(domain.ChallengeCompleted{ChallengeID: challengeID, UserID: userID})
+	})
 }
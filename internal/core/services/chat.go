@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"mafia/pkg/block"
+	"mafia/pkg/chat"
+	apperrors "mafia/pkg/errors"
+	"mafia/pkg/events"
+	"mafia/pkg/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type chatService struct {
+	chatRepo   ports.ChatRepository
+	blockRepo  ports.BlockRepository
+	games      ports.GameService
+	moderation ports.ModerationService
+	events     ports.EventBus
+	limiter    *chat.Limiter
+
+	// blocks caches the block lists of the users loaded so far. Replicas keep theirs
+	// in step through the block events.
+	mu     sync.Mutex
+	blocks *block.List
+	loaded map[uint]bool
+}
+
+func NewChatService(chatRepo ports.ChatRepository, blockRepo ports.BlockRepository, games ports.GameService, moderation ports.ModerationService, bus ports.EventBus, options ports.ChatOptions) ports.ChatService {
+	s := &chatService{
+		chatRepo:   chatRepo,
+		blockRepo:  blockRepo,
+		games:      games,
+		moderation: moderation,
+		events:     bus,
+		limiter:    chat.NewLimiter(options.RateLimit, options.RateWindow),
+		blocks:     block.NewList(),
+		loaded:     make(map[uint]bool),
+	}
+	if bus != nil {
+		events.Subscribe(bus, func(_ context.Context, e domain.UserBlocked) {
+			s.blocks.Block(utils.Key(e.UserID), utils.Key(e.TargetID))
+		})
+		events.Subscribe(bus, func(_ context.Context, e domain.UserUnblocked) {
+			s.blocks.Unblock(utils.Key(e.UserID), utils.Key(e.TargetID))
+		})
+	}
+	return s
+}
+
+// Post stores a message in one of the room's channels, the room channel by default,
+// and announces it to the members who may read it.
+func (s *chatService) Post(roomID, userID uint, req domain.ChatRequest) (*domain.ChatMessage, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: empty message", apperrors.ErrInvalid)
+	}
+	if utf8.RuneCountInString(text) > domain.MaxChatLength {
+		return nil, fmt.Errorf("%w: messages are limited to %d characters", apperrors.ErrInvalid, domain.MaxChatLength)
+	}
+	channel, err := chatChannel(req.Channel)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.games.ChatRules(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rules.Grants[userID]; !ok {
+		return nil, fmt.Errorf("%w: not a member of this room", apperrors.ErrForbidden)
+	}
+	if !rules.CanPost(userID, channel) {
+		return nil, fmt.Errorf("%w: cannot post to the %s channel now", apperrors.ErrForbidden, channel)
+	}
+	now := time.Now()
+	if !s.limiter.Allow(utils.Key(userID), now) {
+		return nil, fmt.Errorf("%w: sending messages too fast", apperrors.ErrRateLimited)
+	}
+	text, err = s.moderation.Screen(domain.ContentSubject{Kind: domain.ContentChat, UserID: userID, RoomID: roomID}, text)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &domain.ChatMessage{RoomID: roomID, Channel: channel, UserID: userID, Text: text, SentAt: now}
+	if err := s.chatRepo.Create(msg); err != nil {
+		return nil, err
+	}
+	if s.events != nil {
+		events.Publish(context.Background(), s.events, *msg)
+	}
+	return msg, nil
+}
+
+// History pages back through a channel from the message before, newest first.
+func (s *chatService) History(roomID, userID uint, channel string, before uint, limit int) ([]domain.ChatMessage, error) {
+	channel, err := chatChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.games.ChatRules(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rules.Grants[userID]; !ok {
+		return nil, fmt.Errorf("%w: not a member of this room", apperrors.ErrForbidden)
+	}
+	if !rules.CanRead(userID, channel) {
+		return nil, fmt.Errorf("%w: cannot read the %s channel now", apperrors.ErrForbidden, channel)
+	}
+	if limit <= 0 {
+		limit = domain.DefaultChatPage
+	}
+	if limit > domain.MaxChatPage {
+		limit = domain.MaxChatPage
+	}
+	hidden, err := s.Blocked(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.chatRepo.List(domain.ChatQuery{RoomID: roomID, Channels: []string{channel}, Before: before, Limit: limit, Hidden: hidden})
+}
+
+// Recipients lists the members who may read the message's channel, the sender
+// included, leaving out those who blocked the sender.
+func (s *chatService) Recipients(msg domain.ChatMessage) ([]uint, error) {
+	rules, err := s.games.ChatRules(msg.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	var recipients []uint
+	for _, userID := range rules.Readers(msg.Channel) {
+		if err := s.load(userID); err != nil {
+			return nil, err
+		}
+		if !s.blocks.IsBlocked(utils.Key(userID), utils.Key(msg.UserID)) {
+			recipients = append(recipients, userID)
+		}
+	}
+	return recipients, nil
+}
+
+// Block hides the target's chat from the user.
+func (s *chatService) Block(userID, targetID uint) error {
+	if userID == targetID {
+		return fmt.Errorf("%w: cannot block yourself", apperrors.ErrInvalid)
+	}
+	if err := s.load(userID); err != nil {
+		return err
+	}
+	if err := s.blockRepo.Block(userID, targetID); err != nil {
+		return err
+	}
+	s.blocks.Block(utils.Key(userID), utils.Key(targetID))
+	if s.events != nil {
+		events.Publish(context.Background(), s.events, domain.UserBlocked{UserID: userID, TargetID: targetID})
+	}
+	return nil
+}
+
+// Unblock shows the target's chat to the user again.
+func (s *chatService) Unblock(userID, targetID uint) error {
+	if err := s.load(userID); err != nil {
+		return err
+	}
+	if err := s.blockRepo.Unblock(userID, targetID); err != nil {
+		return err
+	}
+	s.blocks.Unblock(utils.Key(userID), utils.Key(targetID))
+	if s.events != nil {
+		events.Publish(context.Background(), s.events, domain.UserUnblocked{UserID: userID, TargetID: targetID})
+	}
+	return nil
+}
+
+// Blocked returns the users the user has blocked.
+func (s *chatService) Blocked(userID uint) ([]uint, error) {
+	if err := s.load(userID); err != nil {
+		return nil, err
+	}
+	keys := s.blocks.Blocked(utils.Key(userID))
+	blocked := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		id, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		blocked = append(blocked, uint(id))
+	}
+	return blocked, nil
+}
+
+// load fills the cache with the user's block list on first use.
+func (s *chatService) load(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded[userID] {
+		return nil
+	}
+	blocked, err := s.blockRepo.ListBlocked(userID)
+	if err != nil {
+		return err
+	}
+	for _, targetID := range blocked {
+		s.blocks.Block(utils.Key(userID), utils.Key(targetID))
+	}
+	s.loaded[userID] = true
+	return nil
+}
+
+// chatChannel checks a requested channel, defaulting to the room channel.
+func chatChannel(channel string) (string, error) {
+	switch channel {
+	case "":
+		return domain.ChatRoom, nil
+	case domain.ChatRoom, domain.ChatTeam, domain.ChatDead:
+		return channel, nil
+	}
+	return "", fmt.Errorf("%w: unknown chat channel", apperrors.ErrInvalid)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+// chatRoom serves fixed chat rules for every room.
+type chatRoom struct {
+	ports.GameService
+	rules domain.ChatRules
+}
+
+func (r chatRoom) ChatRules(uint) (*domain.ChatRules, error) { return &r.rules, nil }
+
+type chatLog struct {
+	ports.ChatRepository
+	messages []domain.ChatMessage
+}
+
+func (l *chatLog) Create(msg *domain.ChatMessage) error {
+	l.messages = append(l.messages, *msg)
+	return nil
+}
+
+type blockBook struct {
+	ports.BlockRepository
+	blocked map[uint][]uint
+}
+
+func (b blockBook) ListBlocked(userID uint) ([]uint, error) { return b.blocked[userID], nil }
+
+type passAll struct{}
+
+func (passAll) Screen(_ domain.ContentSubject, text string) (string, error) { return text, nil }
+
+// newChatRoom is a day at a table of two living players, 1 of them mafia, and a dead
+// player 3 that player 2 has blocked.
+func newChatRoom(limit int) (ports.ChatService, *chatLog) {
+	room := &domain.GameRoom{ID: 7, Status: "playing", Players: []domain.User{{ID: 1}, {ID: 2}, {ID: 3}}}
+	state := domain.NewGameState("day", 1)
+	state.Assignments[1] = domain.PlayerAssignment{Team: "mafia", Alive: true}
+	state.Assignments[2] = domain.PlayerAssignment{Team: "town", Alive: true}
+	state.Assignments[3] = domain.PlayerAssignment{Team: "town"}
+	log := &chatLog{}
+	blocks := blockBook{blocked: map[uint][]uint{2: {3}}}
+	options := ports.ChatOptions{RateLimit: limit, RateWindow: time.Minute}
+	return NewChatService(log, blocks, chatRoom{rules: domain.ChatRulesFor(room, state)}, passAll{}, nil, options), log
+}
+
+func TestChatPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  uint
+		channel string
+		text    string
+		want    error
+	}{
+		{name: "the living post to the room by default", userID: 2, text: "hello"},
+		{name: "the mafia post to the room by day", userID: 1, channel: domain.ChatRoom, text: "hi"},
+		{name: "the dead post to their own channel", userID: 3, channel: domain.ChatDead, text: "boo"},
+		{name: "the dead do not post to the room", userID: 3, text: "boo", want: apperrors.ErrForbidden},
+		{name: "the mafia do not talk as a team by day", userID: 1, channel: domain.ChatTeam, text: "psst", want: apperrors.ErrForbidden},
+		{name: "strangers post nowhere", userID: 9, text: "hello", want: apperrors.ErrForbidden},
+		{name: "an unknown channel", userID: 2, channel: "lobby", text: "hello", want: apperrors.ErrInvalid},
+		{name: "an empty message", userID: 2, text: "   ", want: apperrors.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, log := newChatRoom(10)
+			msg, err := s.Post(7, tt.userID, domain.ChatRequest{Channel: tt.channel, Text: tt.text})
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) || len(log.messages) != 0 {
+					t.Fatalf("got %v with %d stored, want %v", err, len(log.messages), tt.want)
+				}
+				return
+			}
+			if err != nil || len(log.messages) != 1 || msg.UserID != tt.userID {
+				t.Fatalf("got %+v, %v; want the message stored", msg, err)
+			}
+		})
+	}
+}
+
+func TestChatPostRateLimit(t *testing.T) {
+	s, _ := newChatRoom(2)
+	for i := 0; i < 2; i++ {
+		if _, err := s.Post(7, 2, domain.ChatRequest{Text: "hello"}); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+	}
+	if _, err := s.Post(7, 2, domain.ChatRequest{Text: "hello"}); !errors.Is(err, apperrors.ErrRateLimited) {
+		t.Fatalf("third message: %v, want ErrRateLimited", err)
+	}
+	if _, err := s.Post(7, 1, domain.ChatRequest{Text: "hello"}); err != nil {
+		t.Fatalf("another sender was held to the first one's limit: %v", err)
+	}
+}
+
+func TestChatRecipients(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  domain.ChatMessage
+		want []uint
+	}{
+		{name: "the room reaches everyone", msg: domain.ChatMessage{RoomID: 7, Channel: domain.ChatRoom, UserID: 1}, want: []uint{1, 2, 3}},
+		{name: "blocked senders are left out", msg: domain.ChatMessage{RoomID: 7, Channel: domain.ChatRoom, UserID: 3}, want: []uint{1, 3}},
+		{name: "the dead channel stays with the dead", msg: domain.ChatMessage{RoomID: 7, Channel: domain.ChatDead, UserID: 3}, want: []uint{3}},
+		{name: "the team channel stays with the mafia", msg: domain.ChatMessage{RoomID: 7, Channel: domain.ChatTeam, UserID: 1}, want: []uint{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newChatRoom(10)
+			got, err := s.Recipients(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if len(got) != len(tt.want) {
+				t.Fatalf("recipients %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("recipients %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/pkg/events"
+	"time"
+)
+
+// GiveSpeakingTurn lets the narrator hand the table to a living player for a while.
+func (s *gameService) GiveSpeakingTurn(roomID, actorID uint, req domain.SpeakingTurnRequest) (*domain.SpeakingTurn, error) {
+	var turn domain.SpeakingTurn
+	err := s.changeTurn(roomID, func(room *domain.GameRoom, state *domain.GameState) error {
+		if err := authorize(room, actorID, domain.PermAdvance); err != nil {
+			return err
+		}
+		var err error
+		turn, err = state.GiveTurn(actorID, req.UserID, time.Duration(req.Seconds)*time.Second, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &turn, nil
+}
+
+// EndSpeakingTurn lets the narrator cut the current turn short. The next queued player
+// gets the floor, or the table opens to every living player once the queue is done.
+func (s *gameService) EndSpeakingTurn(roomID, actorID uint) error {
+	return s.changeTurn(roomID, func(room *domain.GameRoom, state *domain.GameState) error {
+		if err := authorize(room, actorID, domain.PermAdvance); err != nil {
+			return err
+		}
+		return state.SkipTurn(time.Now())
+	})
+}
+
+// PassTurn lets the speaker hand the floor on before their time is up.
+func (s *gameService) PassTurn(roomID, userID uint) error {
+	return s.changeTurn(roomID, func(_ *domain.GameRoom, state *domain.GameState) error {
+		return state.PassTurn(userID, time.Now())
+	})
+}
+
+// RequestChallenge asks the current speaker for a challenge.
+func (s *gameService) RequestChallenge(roomID, userID uint) error {
+	var request domain.ChallengeRequest
+	err := s.execWithRetry(roomID, func(a *roomActor) error {
+		if err := ensurePlaying(a.room); err != nil {
+			return err
+		}
+		state, err := a.gameState()
+		if err != nil {
+			return err
+		}
+		if request, err = state.RequestChallenge(userID); err != nil {
+			return err
+		}
+		return a.commit(state)
+	})
+	if err == nil && s.events != nil {
+		events.Publish(context.Background(), s.events, domain.ChallengeRequested{RoomID: roomID, ChallengeRequest: request})
+	}
+	return err
+}
+
+// AcceptChallenge lets the speaker grant a requested challenge.
+func (s *gameService) AcceptChallenge(roomID, speakerID, challengerID uint) error {
+	return s.changeTurn(roomID, func(_ *domain.GameRoom, state *domain.GameState) error {
+		return state.AcceptChallenge(speakerID, challengerID)
+	})
+}
+
+// AdvanceSpeakingTurns moves on every room served here whose speaking turn ran out.
+func (s *gameService) AdvanceSpeakingTurns(now time.Time) (int, error) {
+	advanced := 0
+	var errs []error
+	for _, roomID := range s.actors.rooms() {
+		changed := false
+		err := s.send(roomID, false, func(a *roomActor) error {
+			if a.room == nil || a.room.Status != "playing" {
+				return nil
+			}
+			state, err := a.gameState()
+			if err != nil {
+				return err
+			}
+			if !state.ExpireTurn(now) {
+				return nil
+			}
+			changed = true
+			a.raise(turnChange(a.room.ID, state))
+			return a.persist()
+		})
+		if errors.Is(err, errActorsStopped) {
+			return advanced, errors.Join(append(errs, err)...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %d: %w", roomID, err))
+			continue
+		}
+		if changed {
+			advanced++
+		}
+	}
+	return advanced, errors.Join(errs...)
+}
+
+// changeTurn runs a command that moves the floor, saves it right away because replicas
+// carrying the room's voice route it from the snapshot, and announces the new turn.
+func (s *gameService) changeTurn(roomID uint, change func(room *domain.GameRoom, state *domain.GameState) error) error {
+	return s.execWithRetry(roomID, func(a *roomActor) error {
+		if err := ensurePlaying(a.room); err != nil {
+			return err
+		}
+		state, err := a.gameState()
+		if err != nil {
+			return err
+		}
+		if err := change(a.room, state); err != nil {
+			return err
+		}
+		a.raise(turnChange(a.room.ID, state))
+		return a.persist()
+	})
+}
+
+// turnChange describes the room's floor in a form that is safe to publish.
+func turnChange(roomID uint, state *domain.GameState) domain.SpeakingTurnChanged {
+	change := domain.SpeakingTurnChanged{RoomID: roomID, Queue: state.Queue()}
+	if state.Turn != nil {
+		turn := *state.Turn
+		change.Turn = &turn
+	}
+	return change
+}
@@ -1,37 +1,62 @@
 package services
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
 	"math/rand"
 	"time"
 )
 
+// maxConflictRetries bounds how often an action is retried after losing a version race.
+const maxConflictRetries = 3
+
 type gameService struct {
 	roomRepo  ports.RoomRepository
 	roleRepo  ports.RoleRepository
+	scenarios ports.ScenarioRepository
+	eventLog  ports.GameEventRepository
 	userRepo  ports.UserRepository
+	tx        ports.Transactor
 	events    ports.EventBus
+	presence  ports.PresenceStore
+	leases    ports.LeaseRegistry
+	options   ports.GameOptions
 	abilities map[string]domain.AbilityOption
+	actors    *actorRegistry
 }
 
-func NewGameService(roomRepo ports.RoomRepository, roleRepo ports.RoleRepository, userRepo ports.UserRepository, events ports.EventBus) ports.GameService {
-	return &gameService{roomRepo: roomRepo, roleRepo: roleRepo, userRepo: userRepo, events: events, abilities: domain.AbilityIndex()}
+func NewGameService(roomRepo ports.RoomRepository, roleRepo ports.RoleRepository, scenarios ports.ScenarioRepository, eventLog ports.GameEventRepository, userRepo ports.UserRepository, tx ports.Transactor, events ports.EventBus, presence ports.PresenceStore, leases ports.LeaseRegistry, options ports.GameOptions) ports.GameService {
+	return &gameService{roomRepo: roomRepo, roleRepo: roleRepo, scenarios: scenarios, eventLog: eventLog, userRepo: userRepo, tx: tx, events: events, presence: presence, leases: leases, options: options, abilities: domain.AbilityIndex(), actors: newActorRegistry()}
 }
 
-func (s *gameService) CreateRoom(hostID uint, roomType string) (*domain.GameRoom, error) {
-	room := &domain.GameRoom{
-		HostID: hostID,
-		Type:   roomType,
-		Code:   randString(6),
-	}
-	if err := s.roomRepo.Create(room); err != nil {
+func (s *gameService) CreateRoom(hostID uint, req domain.CreateRoomRequest) (*domain.GameRoom, error) {
+	settings, err := req.Settings.Normalize()
+	if err != nil {
 		return nil, err
 	}
-	if s.events != nil {
-		s.events.Publish(context.Background(), "game.room_created", room)
+	scenario, err := s.scenarios.FindByID(req.ScenarioID)
+	if err != nil {
+		return nil, fmt.Errorf("unknown scenario")
+	}
+	settings.PhaseDurations = s.phaseDurations(req.Type, scenario).Merge(settings.PhaseDurations)
+	room := &domain.GameRoom{
+		HostID:     hostID,
+		Type:       req.Type,
+		ScenarioID: req.ScenarioID,
+		Code:       randString(6),
+		Settings:   settings,
+	}
+	err = s.tx.Transact(func(tx ports.Repositories) error {
+		if err := tx.Room.Create(room); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(domain.RoomCreated{RoomID: room.ID, Code: room.Code, Type: room.Type, ScenarioID: room.ScenarioID, HostID: room.HostID})
+	})
+	if err != nil {
+		return nil, err
 	}
 	return room, nil
 }
@@ -41,73 +66,196 @@ func (s *gameService) ListRooms() ([]domain.GameRoom, error) {
 }
 
 func (s *gameService) JoinRoom(roomID, userID uint) error {
-	room, err := s.roomRepo.FindByID(roomID)
-	if err != nil || len(room.Players) >= 20 {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
 		return fmt.Errorf("cannot join")
 	}
-	return s.roomRepo.AddPlayer(roomID, userID)
+	return s.exec(roomID, func(a *roomActor) error {
+		if a.room.Status != "waiting" {
+			return fmt.Errorf("game already started")
+		}
+		if a.room.RoleOf(userID) != "" {
+			return fmt.Errorf("already a member of this room")
+		}
+		if len(a.room.Players) >= 20 {
+			return fmt.Errorf("cannot join")
+		}
+		a.room.Players = append(a.room.Players, *user)
+		a.seat(userID, true)
+		a.raise(domain.PlayerJoined{RoomMember: domain.RoomMember{RoomID: roomID, UserID: userID}})
+		return a.persist()
+	})
 }
 
+// Spectate lets a user watch a room without taking part in the game.
+func (s *gameService) Spectate(roomID, userID uint) error {
+	return s.execWithRetry(roomID, func(a *roomActor) error {
+		if a.room.RoleOf(userID) != "" {
+			return fmt.Errorf("already a member of this room")
+		}
+		a.room.SetMemberRole(userID, domain.RoomRoleSpectator)
+		return a.persist()
+	})
+}
+
+// LeaveRoom removes the user from the room, handing the host role to the next co-host,
+// moderator or player when the host leaves.
 func (s *gameService) LeaveRoom(roomID, userID uint) error {
-	return s.roomRepo.RemovePlayer(roomID, userID)
+	return s.execWithRetry(roomID, func(a *roomActor) error {
+		return s.removeMember(a, userID, "left")
+	})
 }
 
-func (s *gameService) StartGame(roomID uint) error {
-	room, err := s.roomRepo.FindByID(roomID)
-	if err != nil || len(room.Players) < 6 {
-		return fmt.Errorf("not enough players")
-	}
+// KickPlayer removes a member who ranks below the actor. Players kicked from a running
+// game are counted as dead.
+func (s *gameService) KickPlayer(roomID, actorID, targetID uint) error {
+	return s.execWithRetry(roomID, func(a *roomActor) error {
+		room := a.room
+		if err := authorize(room, actorID, domain.PermKick); err != nil {
+			return err
+		}
+		if room.RoleOf(targetID) == "" {
+			return fmt.Errorf("user is not a member of this room")
+		}
+		if !room.Outranks(actorID, targetID) {
+			return fmt.Errorf("%w: cannot kick a member of equal or higher rank", apperrors.ErrForbidden)
+		}
+		a.raise(domain.PlayerKicked{RoomMember: domain.RoomMember{RoomID: roomID, UserID: targetID}})
+		return s.removeMember(a, targetID, "kicked")
+	})
+}
 
-	room.Status = "playing"
-	room.Phase = "night"
-	room.DayCount = 1
+// UpdateSettings replaces the room settings before the game starts.
+func (s *gameService) UpdateSettings(roomID, actorID uint, settings domain.RoomSettings) (*domain.GameRoom, error) {
+	var updated *domain.GameRoom
+	err := s.exec(roomID, func(a *roomActor) error {
+		room := a.room
+		if err := authorize(room, actorID, domain.PermSettings); err != nil {
+			return err
+		}
+		if room.Status != "waiting" {
+			return fmt.Errorf("settings can only be changed before the game starts")
+		}
+		normalized, err := settings.Normalize()
+		if err != nil {
+			return err
+		}
+		scenario, err := s.scenarios.FindByID(room.ScenarioID)
+		if err != nil {
+			return fmt.Errorf("unknown scenario")
+		}
+		normalized.PhaseDurations = s.phaseDurations(room.Type, scenario).Merge(normalized.PhaseDurations)
+		room.Settings = normalized
+		if err := a.persist(); err != nil {
+			return err
+		}
+		updated = a.roomCopy()
+		return nil
+	})
+	return updated, err
+}
 
-	state, err := s.assignRoles(room)
-	if err != nil {
-		return err
-	}
+// SetMemberRole lets the host promote a member to co-host or moderator, or demote them
+// back to a player or spectator.
+func (s *gameService) SetMemberRole(roomID, actorID, targetID uint, role string) error {
+	return s.execWithRetry(roomID, func(a *roomActor) error {
+		room := a.room
+		if err := authorize(room, actorID, domain.PermRoles); err != nil {
+			return err
+		}
+		if room.Status != "waiting" {
+			return fmt.Errorf("member roles can only be changed before the game starts")
+		}
+		if targetID == room.HostID {
+			return fmt.Errorf("cannot change the host's role")
+		}
+		switch role {
+		case domain.RoomRoleCoHost, domain.RoomRoleModerator, domain.RoomRolePlayer:
+			if !room.HasPlayer(targetID) {
+				return fmt.Errorf("user must join the room first")
+			}
+		case domain.RoomRoleSpectator:
+			if room.HasPlayer(targetID) {
+				return fmt.Errorf("players must leave the room to spectate")
+			}
+		default:
+			return fmt.Errorf("unknown room role")
+		}
+		room.SetMemberRole(targetID, role)
+		return a.persist()
+	})
+}
 
-	if err := s.saveGameState(room, state); err != nil {
-		return err
-	}
-	if s.events != nil {
-		s.events.Publish(context.Background(), "game.started", room)
-	}
-	return nil
+func (s *gameService) StartGame(roomID, actorID uint) error {
+	return s.exec(roomID, func(a *roomActor) error {
+		room := a.room
+		if err := authorize(room, actorID, domain.PermStart); err != nil {
+			return err
+		}
+		if room.Status != "waiting" {
+			return fmt.Errorf("game already started")
+		}
+		if len(room.Participants()) < 6 {
+			return fmt.Errorf("not enough players")
+		}
+
+		started := *room
+		started.Status = "playing"
+		started.Phase = "night"
+		started.DayCount = 1
+		schedulePhase(&started, time.Now())
+
+		state, err := s.assignRoles(&started)
+		if err != nil {
+			return err
+		}
+		state.RecordStart()
+
+		*room = started
+		a.raise(domain.GameBegan{PhaseChange: phaseChange(room)})
+		return a.commit(state)
+	})
 }
 
+// assignRoles deals the room's scenario layout for the current player count, refusing
+// to start when the resulting distribution is not legal.
 func (s *gameService) assignRoles(room *domain.GameRoom) (*domain.GameState, error) {
+	scenario, err := s.scenarios.FindByID(room.ScenarioID)
+	if err != nil {
+		return nil, fmt.Errorf("room has no valid scenario")
+	}
 	roles, err := s.roleRepo.List()
 	if err != nil {
 		return nil, err
 	}
 
 	roleIndex := make(map[string]domain.Role)
-	var pool []string
 	for _, r := range roles {
 		roleIndex[r.Name] = r
-		count := r.MaxCount
-		if count == 0 {
-			count = 1
-		}
-		for i := 0; i < count; i++ {
-			pool = append(pool, r.Name)
-		}
 	}
 
-	if len(pool) < len(room.Players) {
-		for len(pool) < len(room.Players) {
-			pool = append(pool, "Villager")
-		}
+	players := room.Participants()
+	pool, err := scenario.RolesFor(len(players))
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateDistribution(pool, roleIndex); err != nil {
+		return nil, err
 	}
 
-	rand.Seed(time.Now().UnixNano())
 	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
 
 	state := domain.NewGameState(room.Phase, room.DayCount)
-	for idx, p := range room.Players {
-		roleName := pool[idx%len(pool)]
-		role := roleIndex[roleName]
+	state.Settings, err = room.Settings.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	for idx, p := range players {
+		roleName := pool[idx]
+		role, ok := roleIndex[roleName]
+		if !ok {
+			role.Team = "town"
+		}
 		assignment := domain.PlayerAssignment{
 			Role:          roleName,
 			Team:          role.Team,
@@ -121,27 +269,44 @@ func (s *gameService) assignRoles(room *domain.GameRoom) (*domain.GameState, err
 }
 
 func (s *gameService) Vote(roomID, userID, targetID uint) error {
-	room, err := s.roomRepo.FindByID(roomID)
-	if err != nil {
-		return err
-	}
+	return s.execWithRetry(roomID, func(a *roomActor) error {
+		room := a.room
+		if err := ensurePlaying(room); err != nil {
+			return err
+		}
 
-	if room.Phase != "day" {
-		return fmt.Errorf("votes are only allowed during the day phase")
-	}
+		state, err := a.gameState()
+		if err != nil {
+			return err
+		}
 
-	state, err := s.loadGameState(room)
-	if err != nil {
-		return err
+		vote, err := s.castVote(room, state, userID, targetID)
+		if err != nil {
+			return err
+		}
+
+		a.raise(domain.VoteCast{RoomID: roomID, Voter: userID, Target: targetID, Day: vote.Day, Round: vote.Round})
+		return a.commit(state)
+	})
+}
+
+// castVote validates and records a vote on the loaded state.
+func (s *gameService) castVote(room *domain.GameRoom, state *domain.GameState, userID, targetID uint) (domain.VoteLog, error) {
+	if room.Phase != "day" && room.Phase != "defense" {
+		return domain.VoteLog{}, fmt.Errorf("votes are only allowed during the day phase")
 	}
 
 	voter, ok := state.Assignments[userID]
 	if !ok || !voter.Alive {
-		return fmt.Errorf("voter is not active in this game")
+		return domain.VoteLog{}, fmt.Errorf("voter is not active in this game")
 	}
 
-	if _, ok := state.Assignments[targetID]; !ok {
-		return fmt.Errorf("invalid vote target")
+	if state.HasEffect(userID, domain.EffectSilenced) {
+		return domain.VoteLog{}, fmt.Errorf("voter is silenced for the day")
+	}
+
+	if target, ok := state.Assignments[targetID]; !ok || !target.Alive {
+		return domain.VoteLog{}, fmt.Errorf("invalid vote target")
 	}
 
 	vote := domain.VoteLog{
@@ -149,43 +314,54 @@ func (s *gameService) Vote(roomID, userID, targetID uint) error {
 		Target:    targetID,
 		Phase:     room.Phase,
 		Day:       room.DayCount,
+		Round:     state.Ballot.Round,
 		Timestamp: time.Now(),
 	}
-	state.Votes = append(state.Votes, vote)
-
-	return s.saveGameState(room, state)
+	return vote, state.CastVote(vote)
 }
 
-func (s *gameService) UseAbility(roomID, userID uint, ability string, targetID uint) error {
-	room, err := s.roomRepo.FindByID(roomID)
-	if err != nil {
-		return err
-	}
+func (s *gameService) UseAbility(roomID, userID uint, req domain.AbilityRequest) error {
+	return s.execWithRetry(roomID, func(a *roomActor) error {
+		room := a.room
+		if err := ensurePlaying(room); err != nil {
+			return err
+		}
 
-	if room.Status != "playing" {
-		return fmt.Errorf("game has not started")
-	}
+		state, err := a.gameState()
+		if err != nil {
+			return err
+		}
 
-	state, err := s.loadGameState(room)
-	if err != nil {
-		return err
-	}
+		if err := s.useAbility(room, state, userID, req); err != nil {
+			return err
+		}
 
+		return a.commit(state)
+	})
+}
+
+// useAbility validates and records an ability use on the loaded state.
+func (s *gameService) useAbility(room *domain.GameRoom, state *domain.GameState, userID uint, req domain.AbilityRequest) error {
 	player, ok := state.Assignments[userID]
 	if !ok || !player.Alive {
 		return fmt.Errorf("player not active in this room")
 	}
 
-	definition, ok := s.abilities[ability]
+	definition, ok := s.abilities[req.Ability]
 	if !ok {
 		return fmt.Errorf("unknown ability")
 	}
 
-	if len(player.Abilities) > 0 && !containsAbility(player.Abilities, ability) {
+	if !containsAbility(player.Abilities, req.Ability) {
 		return fmt.Errorf("ability not available to this role")
 	}
 
-	if definition.Phase != "both" && definition.Phase != room.Phase {
+	if state.HasEffect(userID, domain.EffectDisabled) {
+		return fmt.Errorf("abilities are disabled for this player")
+	}
+
+	phase := abilityPhase(room.Phase)
+	if definition.Phase != "both" && definition.Phase != phase {
 		return fmt.Errorf("ability can only be used during %s", definition.Phase)
 	}
 
@@ -193,63 +369,591 @@ func (s *gameService) UseAbility(roomID, userID uint, ability string, targetID u
 		return fmt.Errorf("ability side does not match player team")
 	}
 
-	if targetID != 0 {
-		if target, ok := state.Assignments[targetID]; !ok || !target.Alive {
+	effect, _ := domain.LookupAbilityEffect(req.Ability)
+	if req.TargetID != 0 {
+		if target, ok := state.Assignments[req.TargetID]; !ok || target.Alive == effect.TargetDead {
 			return fmt.Errorf("invalid target")
 		}
 	}
 
-	if player.UsedAbilities == nil {
-		player.UsedAbilities = map[string]domain.AbilityUsage{}
+	if usage, ok := player.UsedAbilities[req.Ability]; ok && usage.Day == state.DayCount && usage.Phase == phase {
+		return fmt.Errorf("ability already used this %s", phase)
 	}
-	if usage, ok := player.UsedAbilities[ability]; ok && usage.Day == state.DayCount && usage.Phase == room.Phase {
-		return fmt.Errorf("ability already used this %s", room.Phase)
-	}
-
-	player.UsedAbilities[ability] = domain.AbilityUsage{Day: state.DayCount, Phase: room.Phase}
-	state.Assignments[userID] = player
 
-	log := domain.AbilityAction{
+	state.RecordAbility(domain.AbilityAction{
 		UserID:    userID,
-		Ability:   ability,
-		TargetID:  targetID,
-		Phase:     room.Phase,
+		Ability:   req.Ability,
+		TargetID:  req.TargetID,
+		Guess:     req.Guess,
+		Phase:     phase,
 		Day:       room.DayCount,
 		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// abilityPhase maps a room phase to the phase abilities are declared for. Defense
+// speeches are part of the day.
+func abilityPhase(phase string) string {
+	if phase == "defense" {
+		return "day"
 	}
-	state.Abilities = append(state.Abilities, log)
+	return phase
+}
+
+func (s *gameService) Disarm(roomID, userID uint, req domain.DisarmRequest) (bool, error) {
+	var disarmed bool
+	err := s.execWithRetry(roomID, func(a *roomActor) error {
+		room := a.room
+		if err := ensurePlaying(room); err != nil {
+			return err
+		}
+
+		if room.Phase != "day" && room.Phase != "defense" {
+			return fmt.Errorf("explosives can only be disarmed during the day")
+		}
+
+		state, err := a.gameState()
+		if err != nil {
+			return err
+		}
+
+		before := mark(state)
+		if disarmed, err = state.Disarm(userID, req.TargetID, req.Guess); err != nil {
+			return err
+		}
 
-	return s.saveGameState(room, state)
+		a.announce(state, before)
+		if result := s.checkWinner(room, state); result != nil {
+			a.raise(domain.GameFinished{RoomID: room.ID, Result: result})
+		}
+		return a.commit(state)
+	})
+	return disarmed, err
 }
 
-func (s *gameService) AdvancePhase(roomID uint) (*domain.GameRoom, error) {
-	room, err := s.roomRepo.FindByID(roomID)
+// VoiceRules derives who may hear whom in the room from its members and game.
+func (s *gameService) VoiceRules(roomID uint) (*domain.VoiceRules, error) {
+	var rules domain.VoiceRules
+	err := s.read(roomID, func(room *domain.GameRoom, state *domain.GameState) error {
+		rules = domain.VoiceRulesFor(room, state)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &rules, nil
+}
 
-	state, err := s.loadGameState(room)
+// ChatRules derives who may post to and read each chat channel of the room.
+func (s *gameService) ChatRules(roomID uint) (*domain.ChatRules, error) {
+	var rules domain.ChatRules
+	err := s.read(roomID, func(room *domain.GameRoom, state *domain.GameState) error {
+		rules = domain.ChatRulesFor(room, state)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &rules, nil
+}
 
-	if room.Phase == "night" {
-		room.Phase = "day"
-	} else {
-		room.Phase = "night"
-		room.DayCount++
+// View returns what the user may see of the room's game. Moderators and everyone after
+// the game has finished get the full state.
+func (s *gameService) View(roomID, userID uint) (*domain.PlayerView, error) {
+	var view domain.PlayerView
+	err := s.read(roomID, func(room *domain.GameRoom, state *domain.GameState) error {
+		if room.RoleOf(userID) == "" {
+			return fmt.Errorf("%w: not a member of this room", apperrors.ErrForbidden)
+		}
+		if state == nil {
+			return fmt.Errorf("game has not started")
+		}
+		snapshot, err := state.Clone()
+		if err != nil {
+			return err
+		}
+		full := room.Status == "finished" || room.RoleOf(userID) == domain.RoomRoleModerator
+		view = snapshot.ViewFor(userID, full)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &view, nil
+}
 
-	state.Phase = room.Phase
-	state.DayCount = room.DayCount
+// MemberRole returns the user's role in the room, failing for non-members.
+func (s *gameService) MemberRole(roomID, userID uint) (string, error) {
+	var role string
+	err := s.read(roomID, func(room *domain.GameRoom, _ *domain.GameState) error {
+		if role = room.RoleOf(userID); role == "" {
+			return fmt.Errorf("%w: not a member of this room", apperrors.ErrForbidden)
+		}
+		return nil
+	})
+	return role, err
+}
 
-	if err := s.saveGameState(room, state); err != nil {
+// Replay rebuilds a finished game from its event log.
+func (s *gameService) Replay(roomID uint) (*domain.Replay, error) {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
 		return nil, err
 	}
-	if s.events != nil {
-		s.events.Publish(context.Background(), "game.phase_changed", room)
+	if room.Status != "finished" {
+		return nil, fmt.Errorf("replays are only available for finished games")
 	}
-	return room, nil
+	events, err := s.eventLog.ListByRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no event log recorded for this game")
+	}
+	state, err := domain.ReplayGameState(events)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Replay{RoomID: roomID, Timeline: domain.Timeline(events), State: state}, nil
+}
+
+// GameState returns the raw state of a room for administrators.
+func (s *gameService) GameState(roomID uint) (*domain.GameState, error) {
+	var snapshot *domain.GameState
+	err := s.read(roomID, func(_ *domain.GameRoom, state *domain.GameState) error {
+		if state == nil {
+			return fmt.Errorf("game has not started")
+		}
+		var err error
+		snapshot, err = state.Clone()
+		return err
+	})
+	return snapshot, err
+}
+
+func (s *gameService) AdvancePhase(roomID, actorID uint) (*domain.GameRoom, error) {
+	var room *domain.GameRoom
+	err := s.exec(roomID, func(a *roomActor) error {
+		if err := authorize(a.room, actorID, domain.PermAdvance); err != nil {
+			return err
+		}
+		if err := s.advance(a); err != nil {
+			return err
+		}
+		room = a.roomCopy()
+		return nil
+	})
+	return room, err
+}
+
+// AdvanceExpiredPhases advances every playing room whose phase deadline has passed.
+func (s *gameService) AdvanceExpiredPhases(now time.Time) (int, error) {
+	rooms, err := s.roomRepo.ListExpired(now)
+	if err != nil {
+		return 0, err
+	}
+	advanced := 0
+	var errs []error
+	for _, expired := range rooms {
+		skipped := false
+		err := s.exec(expired.ID, func(a *roomActor) error {
+			// The phase may have been advanced by hand since the rooms were listed.
+			if a.room.PhaseEndsAt == nil || a.room.PhaseEndsAt.After(now) {
+				skipped = true
+				return nil
+			}
+			// Challenges and narrator turns can outgrow the deadline the phase opened
+			// with; the phase waits for the last speech.
+			state, err := a.gameState()
+			if err != nil {
+				return err
+			}
+			if extendForSpeeches(a.room, state, now) {
+				skipped = true
+				a.raise(domain.PhaseChanged{PhaseChange: phaseChange(a.room)})
+				return a.persist()
+			}
+			return s.advance(a)
+		})
+		var owned *domain.RoomOwnedError
+		if errors.As(err, &owned) {
+			// The replica serving the room advances it.
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %d: %w", expired.ID, err))
+			continue
+		}
+		if !skipped {
+			advanced++
+		}
+	}
+	return advanced, errors.Join(errs...)
+}
+
+func (s *gameService) advance(a *roomActor) error {
+	room := a.room
+	if err := ensurePlaying(room); err != nil {
+		return err
+	}
+
+	state, err := a.gameState()
+	if err != nil {
+		return err
+	}
+	before := mark(state)
+
+	state.AdvancePhase(rand.Intn)
+	room.Phase = state.Phase
+	room.DayCount = state.DayCount
+
+	result := s.checkWinner(room, state)
+	if result == nil {
+		s.playBots(room, state)
+		state.OpenDiscussion(time.Now())
+	}
+	schedulePhase(room, time.Now())
+	extendForSpeeches(room, state, time.Now())
+
+	a.announce(state, before)
+	if result == nil {
+		a.raise(domain.PhaseChanged{PhaseChange: phaseChange(room)})
+		a.raise(turnChange(room.ID, state))
+	} else {
+		a.raise(domain.GameFinished{RoomID: room.ID, Result: result})
+	}
+	return a.commit(state)
+}
+
+// Connect marks a member as connected and gives a returning player their seat back.
+// When another replica serves the room, it hands the seat back once it sees the player
+// online.
+func (s *gameService) Connect(roomID, userID uint) error {
+	err := s.execWithRetry(roomID, func(a *roomActor) error {
+		room := a.room
+		if room.RoleOf(userID) == "" {
+			return fmt.Errorf("%w: not a member of this room", apperrors.ErrForbidden)
+		}
+		if s.presence != nil {
+			if err := s.presence.Online(roomID, userID, time.Now()); err != nil {
+				return err
+			}
+		}
+		a.raise(domain.PlayerConnected{RoomMember: domain.RoomMember{RoomID: roomID, UserID: userID}})
+
+		if room.Status == "playing" {
+			state, err := a.gameState()
+			if err != nil {
+				return err
+			}
+			if state.SetController(userID, "") {
+				a.raise(domain.PlayerReturned{RoomMember: domain.RoomMember{RoomID: roomID, UserID: userID}})
+				return a.commit(state)
+			}
+		}
+		return a.snapshot()
+	})
+	var owned *domain.RoomOwnedError
+	if !errors.As(err, &owned) {
+		return err
+	}
+	if _, err := s.MemberRole(roomID, userID); err != nil {
+		return err
+	}
+	if s.presence != nil {
+		if err := s.presence.Online(roomID, userID, time.Now()); err != nil {
+			return err
+		}
+	}
+	return s.raise(domain.PlayerConnected{RoomMember: domain.RoomMember{RoomID: roomID, UserID: userID}})
+}
+
+// Disconnect starts the grace window of a member whose connection dropped.
+func (s *gameService) Disconnect(roomID, userID uint) error {
+	if s.presence != nil {
+		if err := s.presence.Offline(roomID, userID, time.Now()); err != nil {
+			return err
+		}
+	}
+	return s.raise(domain.PlayerDisconnected{RoomMember: domain.RoomMember{RoomID: roomID, UserID: userID}})
+}
+
+// raise stores an event about a change kept outside the room, such as presence, in the
+// outbox.
+func (s *gameService) raise(event domain.Event) error {
+	return s.tx.Transact(func(tx ports.Repositories) error {
+		return tx.Outbox.Add(event)
+	})
+}
+
+// ExpireAbsences applies the absence policy to players whose grace window has passed.
+func (s *gameService) ExpireAbsences(now time.Time) (int, error) {
+	if s.presence == nil {
+		return 0, nil
+	}
+	expired, err := s.presence.Expired(now.Add(-s.options.DisconnectGrace))
+	if err != nil {
+		return 0, err
+	}
+	policy := s.options.AbsencePolicy
+	if !domain.ValidAbsencePolicy(policy) {
+		policy = domain.AbsenceSkip
+	}
+
+	handled := 0
+	errs := []error{s.seatReturned()}
+	for _, absence := range expired {
+		err := s.applyAbsence(absence, policy)
+		var owned *domain.RoomOwnedError
+		if errors.As(err, &owned) {
+			// Left for the replica serving the room.
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %d user %d: %w", absence.RoomID, absence.UserID, err))
+			continue
+		}
+		if err := s.presence.MarkHandled(absence.RoomID, absence.UserID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		handled++
+	}
+	return handled, errors.Join(errs...)
+}
+
+func (s *gameService) applyAbsence(absence domain.Presence, policy string) error {
+	return s.execWithRetry(absence.RoomID, func(a *roomActor) error {
+		room := a.room
+		if room.Status != "playing" {
+			return nil
+		}
+		state, err := a.gameState()
+		if err != nil {
+			return err
+		}
+		player, ok := state.Assignments[absence.UserID]
+		if !ok || !player.Alive || player.Controller != "" {
+			return nil
+		}
+		state.SetController(absence.UserID, policy)
+		if policy == domain.AbsenceBot {
+			s.playBot(room, state, absence.UserID)
+		}
+		a.raise(domain.PlayerAbsent{RoomID: room.ID, UserID: absence.UserID, Policy: policy})
+		return a.commit(state)
+	})
+}
+
+// seatReturned hands their seats back to players of the rooms live on this replica who
+// came back online through another replica.
+func (s *gameService) seatReturned() error {
+	var errs []error
+	for _, roomID := range s.actors.rooms() {
+		err := s.send(roomID, false, func(a *roomActor) error {
+			if a.room == nil || a.room.Status != "playing" || a.state == nil {
+				return nil
+			}
+			returned := false
+			for _, id := range a.state.PlayerIDs() {
+				if player := a.state.Assignments[id]; !player.Alive || player.Controller == "" {
+					continue
+				}
+				presence, ok, err := s.presence.Get(roomID, id)
+				if err != nil {
+					return err
+				}
+				if ok && presence.Status == domain.PresenceOnline && a.state.SetController(id, "") {
+					a.raise(domain.PlayerReturned{RoomMember: domain.RoomMember{RoomID: roomID, UserID: id}})
+					returned = true
+				}
+			}
+			if !returned {
+				return nil
+			}
+			return a.commit(a.state)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %d: %w", roomID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// playBots acts for every living player handed over to a bot.
+func (s *gameService) playBots(room *domain.GameRoom, state *domain.GameState) {
+	for _, id := range state.PlayerIDs() {
+		if player := state.Assignments[id]; player.Alive && player.Controller == domain.AbsenceBot {
+			s.playBot(room, state, id)
+		}
+	}
+}
+
+// playBot votes for a random player during the day and uses the first ability that
+// fits the night on a random target. Moves the rules reject are simply skipped.
+func (s *gameService) playBot(room *domain.GameRoom, state *domain.GameState, userID uint) {
+	if room.Phase == "day" || room.Phase == "defense" {
+		targets := state.Ballot.Candidates
+		if len(targets) == 0 {
+			targets = botTargets(state, userID, false)
+		}
+		if len(targets) > 0 {
+			s.castVote(room, state, userID, targets[rand.Intn(len(targets))])
+		}
+		return
+	}
+	for _, ability := range state.Assignments[userID].Abilities {
+		effect, ok := domain.LookupAbilityEffect(ability)
+		if !ok {
+			continue
+		}
+		targets := botTargets(state, userID, effect.TargetDead)
+		if len(targets) == 0 {
+			continue
+		}
+		req := domain.AbilityRequest{Ability: ability, TargetID: targets[rand.Intn(len(targets))]}
+		if s.useAbility(room, state, userID, req) == nil {
+			return
+		}
+	}
+}
+
+// botTargets lists the other players a bot may pick, leaving out living teammates.
+func botTargets(state *domain.GameState, userID uint, dead bool) []uint {
+	self := state.Assignments[userID]
+	var targets []uint
+	for _, id := range state.PlayerIDs() {
+		player := state.Assignments[id]
+		if id == userID || player.Alive == dead {
+			continue
+		}
+		if !dead && self.Team == "mafia" && player.Team == "mafia" {
+			continue
+		}
+		targets = append(targets, id)
+	}
+	return targets
+}
+
+// progress marks how far the state's logs had grown before a change.
+type progress struct {
+	deaths         int
+	investigations int
+}
+
+func mark(state *domain.GameState) progress {
+	return progress{deaths: len(state.Deaths), investigations: len(state.Investigations)}
+}
+
+// announce raises the investigation results and deaths recorded since the mark, so
+// they go out with the snapshot that records them.
+func (a *roomActor) announce(state *domain.GameState, since progress) {
+	for _, investigation := range state.Investigations[since.investigations:] {
+		a.raise(domain.InvestigationReport{RoomID: a.room.ID, InvestigationResult: investigation})
+	}
+	for _, death := range state.Deaths[since.deaths:] {
+		a.raise(domain.PlayerDied{
+			RoomID:      a.room.ID,
+			PublicDeath: domain.PublicDeath{UserID: death.UserID, Day: death.Day, Phase: death.Phase, Cause: death.Cause},
+		})
+	}
+}
+
+func phaseChange(room *domain.GameRoom) domain.PhaseChange {
+	return domain.PhaseChange{RoomID: room.ID, Phase: room.Phase, DayCount: room.DayCount, PhaseEndsAt: room.PhaseEndsAt}
+}
+
+// removeMember drops the user from the room and its staff lists, passing the host role
+// on when needed. A living player leaving a running game is recorded as a death.
+func (s *gameService) removeMember(a *roomActor, userID uint, cause string) error {
+	room := a.room
+	if room.HasPlayer(userID) {
+		a.seat(userID, false)
+	}
+	room.RemoveMember(userID)
+	room.DropPlayer(userID)
+	if room.HostID == userID {
+		room.HostID = room.NextHost()
+		room.RemoveMember(room.HostID)
+		a.raise(domain.HostChanged{RoomID: room.ID, HostID: room.HostID})
+	}
+
+	if room.Status == "playing" {
+		state, err := a.gameState()
+		if err != nil {
+			return err
+		}
+		before := mark(state)
+		if state.RemovePlayer(userID, cause) {
+			a.announce(state, before)
+			if result := s.checkWinner(room, state); result != nil {
+				schedulePhase(room, time.Now())
+				a.raise(domain.GameFinished{RoomID: room.ID, Result: result})
+			}
+		}
+	}
+	a.raise(domain.PlayerLeft{RoomMember: domain.RoomMember{RoomID: room.ID, UserID: userID}})
+	if err := a.persist(); err != nil {
+		return err
+	}
+	if s.presence != nil {
+		return s.presence.Remove(room.ID, userID)
+	}
+	return nil
+}
+
+// authorize rejects actors whose room role lacks the permission.
+func authorize(room *domain.GameRoom, actorID uint, permission string) error {
+	if !room.Can(actorID, permission) {
+		return fmt.Errorf("%w: missing %s permission for this room", apperrors.ErrForbidden, permission)
+	}
+	return nil
+}
+
+// checkWinner evaluates the win conditions and marks the room finished once decided.
+func (s *gameService) checkWinner(room *domain.GameRoom, state *domain.GameState) *domain.GameResult {
+	result := state.EvaluateWinner()
+	if result != nil {
+		room.Status = "finished"
+		room.Winner = result.Winner
+	}
+	return result
+}
+
+// phaseDurations returns the phase lengths a room's own settings are laid over: the
+// scenario's take precedence over the room type's, which take precedence over the
+// defaults.
+func (s *gameService) phaseDurations(roomType string, scenario *domain.Scenario) domain.PhaseDurations {
+	durations := s.options.PhaseDurations["default"].Merge(s.options.PhaseDurations[roomType])
+	return durations.Merge(scenario.PhaseDurations)
+}
+
+// schedulePhase sets the deadline of the room's current phase, clearing it for
+// untimed phases and rooms that are no longer playing.
+func schedulePhase(room *domain.GameRoom, now time.Time) {
+	room.PhaseEndsAt = nil
+	if room.Status != "playing" {
+		return
+	}
+	if d := room.Settings.PhaseDurations.Duration(room.Phase); d > 0 {
+		deadline := now.Add(d)
+		room.PhaseEndsAt = &deadline
+	}
+}
+
+// extendForSpeeches moves the phase deadline back so it does not fall before the
+// speaking queue is through. It reports whether the deadline moved.
+func extendForSpeeches(room *domain.GameRoom, state *domain.GameState, now time.Time) bool {
+	if room.PhaseEndsAt == nil {
+		return false
+	}
+	end := now.Add(state.SpeakingLeft(now))
+	if !end.After(*room.PhaseEndsAt) {
+		return false
+	}
+	room.PhaseEndsAt = &end
+	return true
 }
 
 func (s *gameService) loadGameState(room *domain.GameRoom) (*domain.GameState, error) {
@@ -266,16 +970,27 @@ func (s *gameService) loadGameState(room *domain.GameRoom) (*domain.GameState, e
 	return state, nil
 }
 
-func (s *gameService) saveGameState(room *domain.GameRoom, state *domain.GameState) error {
-	state.Phase = room.Phase
-	state.DayCount = room.DayCount
+func ensurePlaying(room *domain.GameRoom) error {
+	switch room.Status {
+	case "playing":
+		return nil
+	case "finished":
+		return fmt.Errorf("game is finished")
+	default:
+		return fmt.Errorf("game has not started")
+	}
+}
 
-	serialized, err := state.Serialize()
-	if err != nil {
-		return err
+// retryOnConflict re-runs an action whose snapshot lost a race with a concurrent update
+// of the same room. Only actions that are safe to repeat on fresh state use it.
+func retryOnConflict(action func() error) error {
+	var err error
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		if err = action(); !errors.Is(err, apperrors.ErrConflict) {
+			return err
+		}
 	}
-	room.Results = serialized
-	return s.roomRepo.Update(room)
+	return err
 }
 
 func randString(n int) string {
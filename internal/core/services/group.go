@@ -1,50 +1,55 @@
 package services
 
 import (
-	"context"
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
 )
 
 type groupService struct {
-	groupRepo ports.GroupRepository
-	userRepo  ports.UserRepository
-	events    ports.EventBus
+	groupRepo  ports.GroupRepository
+	userRepo   ports.UserRepository
+	tx         ports.Transactor
+	moderation ports.ModerationService
 }
 
-func NewGroupService(groupRepo ports.GroupRepository, userRepo ports.UserRepository, events ports.EventBus) ports.GroupService {
-	return &groupService{groupRepo: groupRepo, userRepo: userRepo, events: events}
+func NewGroupService(groupRepo ports.GroupRepository, userRepo ports.UserRepository, tx ports.Transactor, moderation ports.ModerationService) ports.GroupService {
+	return &groupService{groupRepo: groupRepo, userRepo: userRepo, tx: tx, moderation: moderation}
 }
 
 func (s *groupService) CreateGroup(ownerID uint, name string) (*domain.Group, error) {
-	group := &domain.Group{Name: name, OwnerID: ownerID}
-	if err := s.groupRepo.Create(group); err != nil {
+	name, err := s.moderation.Screen(domain.ContentSubject{Kind: domain.ContentGroup, UserID: ownerID}, name)
+	if err != nil {
 		return nil, err
 	}
-	if s.events != nil {
-		s.events.Publish(context.Background(), "group.created", group)
+	group := &domain.Group{Name: name, OwnerID: ownerID}
+	err = s.tx.Transact(func(tx ports.Repositories) error {
+		if err := tx.Group.Create(group); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(domain.GroupCreated{GroupID: group.ID, Name: group.Name, OwnerID: group.OwnerID})
+	})
+	if err != nil {
+		return nil, err
 	}
 	return group, nil
 }
 
 func (s *groupService) Invite(groupID, userID uint) error {
-	if err := s.groupRepo.AddMember(groupID, userID); err != nil {
-		return err
-	}
-	if s.events != nil {
-		s.events.Publish(context.Background(), "group.member_added", map[string]uint{"group_id": groupID, "user_id": userID})
-	}
-	return nil
+	return s.tx.Transact(func(tx ports.Repositories) error {
+		if err := tx.Group.AddMember(groupID, userID); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(domain.GroupMemberAdded{GroupMember: domain.GroupMember{GroupID: groupID, UserID: userID}})
+	})
 }
 
 func (s *groupService) Kick(groupID, userID uint) error {
-	if err := s.groupRepo.RemoveMember(groupID, userID); err != nil {
-		return err
-	}
-	if s.events != nil {
-		s.events.Publish(context.Background(), "group.member_removed", map[string]uint{"group_id": groupID, "user_id": userID})
-	}
-	return nil
+	return s.tx.Transact(func(tx ports.Repositories) error {
+		if err := tx.Group.RemoveMember(groupID, userID); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(domain.GroupMemberRemoved{GroupMember: domain.GroupMember{GroupID: groupID, UserID: userID}})
+	})
 }
 
 func (s *groupService) GetStats(groupID uint) (map[string]interface{}, error) {
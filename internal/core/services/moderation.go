@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
+	"mafia/pkg/moderation"
+	"mafia/pkg/utils"
+	"mafia/pkg/window"
+	"strings"
+	"time"
+)
+
+type moderationService struct {
+	moderator *moderation.Moderator
+	reports   ports.ReportRepository
+	actions   map[string]string
+	strikes   *window.Counter
+	threshold int
+}
+
+func NewModerationService(reports ports.ReportRepository, options ports.ModerationOptions) ports.ModerationService {
+	words := append(moderation.DefaultWords(), options.Words...)
+	return &moderationService{
+		moderator: moderation.NewModerator(moderation.NewWordList(words)),
+		reports:   reports,
+		actions:   options.Actions,
+		strikes:   window.NewCounter(options.ReportWindow),
+		threshold: options.ReportThreshold,
+	}
+}
+
+// Screen applies the action configured for the kind of text when the filter catches
+// it. Masked and rejected text counts against its author, who is reported once caught
+// often enough; flagged text is reported right away. Strikes are counted per replica.
+func (s *moderationService) Screen(subject domain.ContentSubject, text string) (string, error) {
+	result := s.moderator.Check(text)
+	if result.Clean() {
+		return text, nil
+	}
+	terms := strings.Join(result.Terms(), ", ")
+	switch s.actions[subject.Kind] {
+	case domain.ModerationFlag:
+		reason := fmt.Sprintf("content filter flagged %s %q for: %s", subject.Kind, text, terms)
+		if err := s.report(subject, reason); err != nil {
+			return "", err
+		}
+		return text, nil
+	case domain.ModerationReject:
+		if err := s.strike(subject, terms); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %s contains blocked words", apperrors.ErrInvalid, contentLabel(subject.Kind))
+	default:
+		if err := s.strike(subject, terms); err != nil {
+			return "", err
+		}
+		return result.Masked, nil
+	}
+}
+
+// strike counts a catch against the author and reports them once they reach the
+// threshold.
+func (s *moderationService) strike(subject domain.ContentSubject, terms string) error {
+	if s.threshold <= 0 {
+		return nil
+	}
+	key := utils.Key(subject.UserID)
+	count := s.strikes.Add(key, time.Now())
+	if count < s.threshold {
+		return nil
+	}
+	s.strikes.Reset(key)
+	reason := fmt.Sprintf("content filter caught this user %d times, most recently in a %s, for: %s", count, subject.Kind, terms)
+	return s.report(subject, reason)
+}
+
+// report files a Report against the author on the system's behalf.
+func (s *moderationService) report(subject domain.ContentSubject, reason string) error {
+	return s.reports.Create(&domain.Report{TargetID: subject.UserID, RoomID: subject.RoomID, Reason: reason})
+}
+
+func contentLabel(kind string) string {
+	switch kind {
+	case domain.ContentName:
+		return "name"
+	case domain.ContentGroup:
+		return "group name"
+	}
+	return "message"
+}
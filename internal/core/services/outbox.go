@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"mafia/pkg/events"
+	"time"
+)
+
+// outboxBatch bounds how many events one relay pass delivers.
+const outboxBatch = 100
+
+// outboxRetry is how long an event that could not be decoded is set aside before a
+// relay tries it again.
+const outboxRetry = time.Minute
+
+type outboxService struct {
+	tx       ports.Transactor
+	events   ports.EventBus
+	registry *events.Registry
+}
+
+// NewOutboxService relays stored events to the bus. Delivery is at least once: an event
+// published right before a crash is published again, with the same envelope ID.
+func NewOutboxService(tx ports.Transactor, bus ports.EventBus) ports.OutboxService {
+	registry := events.NewRegistry()
+	for _, event := range domain.EventCatalog {
+		registry.Register(event)
+	}
+	return &outboxService{tx: tx, events: bus, registry: registry}
+}
+
+// Relay publishes the oldest pending events and deletes them in the transaction that
+// claimed them. Events this build cannot decode, such as newer schema versions written
+// during a rolling update, are set aside for a while so a replica that can decode them
+// picks them up, without holding up the events behind them.
+func (s *outboxService) Relay() (int, error) {
+	if s.events == nil {
+		return 0, nil
+	}
+	delivered := 0
+	var errs []error
+	err := s.tx.Transact(func(tx ports.Repositories) error {
+		pending, err := tx.Outbox.Pending(outboxBatch)
+		if err != nil {
+			return err
+		}
+		ids := make([]uint, 0, len(pending))
+		for _, msg := range pending {
+			env := events.Envelope{ID: msg.EventID, Topic: msg.Topic, Version: msg.Version, OccurredAt: msg.OccurredAt, Payload: []byte(msg.Payload)}
+			event, err := s.registry.Decode(env)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("outbox event %s: %w", msg.EventID, err))
+				if err := tx.Outbox.Defer(msg.ID, time.Now().Add(outboxRetry), err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			s.events.Publish(events.WithEnvelope(context.Background(), env), env.Topic, event)
+			ids = append(ids, msg.ID)
+		}
+		if err := tx.Outbox.Delete(ids); err != nil {
+			return err
+		}
+		delivered = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return delivered, errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	"mafia/pkg/events"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryOutbox keeps outbox messages in memory, honouring RetryAt like the Postgres
+// repository.
+type memoryOutbox struct {
+	ports.OutboxRepository
+	messages []domain.OutboxMessage
+}
+
+func (o *memoryOutbox) add(t *testing.T, event events.Event) {
+	t.Helper()
+	env, err := events.Seal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.messages = append(o.messages, domain.OutboxMessage{
+		ID: uint(len(o.messages) + 1), EventID: env.ID, Topic: env.Topic, Version: env.Version,
+		Payload: string(env.Payload), OccurredAt: env.OccurredAt,
+	})
+}
+
+func (o *memoryOutbox) Pending(limit int) ([]domain.OutboxMessage, error) {
+	var pending []domain.OutboxMessage
+	for _, msg := range o.messages {
+		if len(pending) == limit {
+			break
+		}
+		if msg.RetryAt == nil || !msg.RetryAt.After(time.Now()) {
+			pending = append(pending, msg)
+		}
+	}
+	return pending, nil
+}
+
+func (o *memoryOutbox) Defer(id uint, until time.Time, reason string) error {
+	for i := range o.messages {
+		if o.messages[i].ID == id {
+			o.messages[i].Attempts++
+			o.messages[i].RetryAt = &until
+			o.messages[i].LastError = reason
+		}
+	}
+	return nil
+}
+
+func (o *memoryOutbox) Delete(ids []uint) error {
+	deleted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	kept := o.messages[:0]
+	for _, msg := range o.messages {
+		if !deleted[msg.ID] {
+			kept = append(kept, msg)
+		}
+	}
+	o.messages = kept
+	return nil
+}
+
+type outboxTx struct{ outbox *memoryOutbox }
+
+func (t outboxTx) Transact(fn func(tx ports.Repositories) error) error {
+	return fn(ports.Repositories{Outbox: t.outbox})
+}
+
+type recordingBus struct {
+	mu     sync.Mutex
+	topics []string
+}
+
+func (b *recordingBus) Publish(_ context.Context, topic string, _ interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.topics = append(b.topics, topic)
+}
+
+func (b *recordingBus) Subscribe(string, func(context.Context, interface{})) {}
+
+func TestRelaySetsUndecodableEventsAside(t *testing.T) {
+	outbox := &memoryOutbox{}
+	outbox.add(t, domain.PlayerDied{RoomID: 1})
+	outbox.messages[0].Version = 99
+	outbox.add(t, domain.GameFinished{RoomID: 1})
+	bus := &recordingBus{}
+	relay := NewOutboxService(outboxTx{outbox}, bus)
+
+	delivered, err := relay.Relay()
+	if err == nil {
+		t.Fatal("want the undecodable event reported")
+	}
+	if delivered != 1 || len(bus.topics) != 1 || bus.topics[0] != (domain.GameFinished{}).EventTopic() {
+		t.Fatalf("delivered %d %v, want only the event behind the undecodable one", delivered, bus.topics)
+	}
+	if len(outbox.messages) != 1 {
+		t.Fatalf("%d messages left, want the undecodable one kept", len(outbox.messages))
+	}
+	left := outbox.messages[0]
+	if left.Attempts != 1 || left.RetryAt == nil || left.LastError == "" {
+		t.Fatalf("left %+v, want it set aside with the reason", left)
+	}
+
+	if delivered, err := relay.Relay(); err != nil || delivered != 0 {
+		t.Fatalf("second pass delivered %d, %v; want the set-aside event skipped", delivered, err)
+	}
+}
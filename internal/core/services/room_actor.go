@@ -0,0 +1,484 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
+	"mafia/pkg/events"
+	"sync"
+	"time"
+)
+
+// actorIdleTimeout is how long the actor of a room that is not being played stays
+// resident after its last command.
+const actorIdleTimeout = 5 * time.Minute
+
+// errActorsStopped is returned for commands sent after the service has shut down.
+var errActorsStopped = errors.New("game service is shutting down")
+
+// roomCommand runs on a room's actor with exclusive access to the live room and game
+// state. Commands must not send further commands to the same room.
+type roomCommand func(a *roomActor) error
+
+// roomActor owns the live copy of one room and its game state. Its commands run one at
+// a time on a dedicated goroutine, so they never race each other. Game state changes
+// are kept in memory and snapshotted to the room repository on phase transitions, on
+// room changes and periodically in between.
+type roomActor struct {
+	id      uint
+	service *gameService
+	mailbox chan func()
+
+	// Guarded by actorRegistry.mu.
+	pending  int
+	lastUsed time.Time
+
+	// Owned by the actor goroutine.
+	room       *domain.GameRoom
+	state      *domain.GameState
+	dirty      bool
+	savedAt    time.Time
+	saved      roomMark
+	leaseUntil time.Time
+	outbox     []domain.Event
+	// seats are the player seats taken (true) or given up (false) since the last
+	// snapshot, by user.
+	seats map[uint]bool
+	// events are the game events taken from the state that still await a snapshot.
+	events []domain.GameEvent
+}
+
+// roomMark captures the room fields whose change forces an immediate snapshot.
+type roomMark struct {
+	status string
+	phase  string
+	day    int
+}
+
+func markRoom(room *domain.GameRoom) roomMark {
+	return roomMark{status: room.Status, phase: room.Phase, day: room.DayCount}
+}
+
+func (a *roomActor) loop() {
+	for task := range a.mailbox {
+		task()
+	}
+}
+
+// do runs the task on the actor goroutine and waits for its result.
+func (a *roomActor) do(task func() error) error {
+	done := make(chan error, 1)
+	a.mailbox <- func() { done <- task() }
+	return <-done
+}
+
+// load rehydrates the room from its latest snapshot unless it is already live. The
+// replica has to hold the room's lease to run it.
+func (a *roomActor) load() error {
+	if a.room != nil {
+		return nil
+	}
+	if err := a.claim(); err != nil {
+		return err
+	}
+	room, err := a.service.roomRepo.FindByID(a.id)
+	if err != nil {
+		return err
+	}
+	a.room, a.state, a.dirty = room, nil, false
+	a.saved, a.savedAt = markRoom(room), time.Now()
+	return nil
+}
+
+// claim acquires or renews this replica's lease on the room.
+func (a *roomActor) claim() error {
+	s := a.service
+	if s.leases == nil {
+		return nil
+	}
+	lease, acquired, err := s.leases.Acquire(a.id, s.options.Node, s.options.LeaseTTL)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return &domain.RoomOwnedError{Lease: *lease}
+	}
+	a.leaseUntil = lease.ExpiresAt
+	return nil
+}
+
+// reset drops the live copy so the next command reloads it from the repository.
+func (a *roomActor) reset() {
+	a.room, a.state, a.dirty, a.outbox, a.seats, a.events = nil, nil, false, nil, nil, nil
+}
+
+// unsaved reports whether changes or events still await a snapshot.
+func (a *roomActor) unsaved() bool {
+	return a.dirty || len(a.outbox) > 0
+}
+
+// seat records that the user took or gave up a player seat. It is stored with the next
+// snapshot; a seat given back before then leaves nothing to store.
+func (a *roomActor) seat(userID uint, taken bool) {
+	if prior, ok := a.seats[userID]; ok && prior != taken {
+		delete(a.seats, userID)
+		return
+	}
+	if a.seats == nil {
+		a.seats = make(map[uint]bool)
+	}
+	a.seats[userID] = taken
+}
+
+// raise records an event for the outbox. It is stored with the next snapshot, so it is
+// delivered exactly when the change it reports is saved.
+func (a *roomActor) raise(event domain.Event) {
+	a.outbox = append(a.outbox, event)
+}
+
+// gameState returns the live game state, decoding it from the room on first use.
+func (a *roomActor) gameState() (*domain.GameState, error) {
+	if a.state == nil {
+		state, err := a.service.loadGameState(a.room)
+		if err != nil {
+			return nil, err
+		}
+		a.state = state
+	}
+	return a.state, nil
+}
+
+// commit makes the state live. It is snapshotted right away when the room changed
+// status, phase or day or when events wait on it, and with the next periodic snapshot
+// otherwise.
+func (a *roomActor) commit(state *domain.GameState) error {
+	a.state = state
+	a.dirty = true
+	if markRoom(a.room) != a.saved || len(a.outbox) > 0 || a.service.options.SnapshotInterval <= 0 {
+		return a.snapshot()
+	}
+	return nil
+}
+
+// persist snapshots the room right away, for changes to the room itself.
+func (a *roomActor) persist() error {
+	a.dirty = true
+	return a.snapshot()
+}
+
+// snapshot flushes on behalf of a command. A change whose snapshot failed but is still
+// live stands: it stays pending and the next flush retries it, so only losing the live
+// copy fails the command.
+func (a *roomActor) snapshot() error {
+	err := a.flush()
+	if err != nil && a.room != nil {
+		return nil
+	}
+	return err
+}
+
+// flush writes pending changes to the room repository, the event log and the outbox in
+// one transaction; events raised without a change to the room go to the outbox alone. When the snapshot fails the changes stay pending for the next flush,
+// except after losing a race with another writer: the live copy is stale then and is
+// dropped, so the room is reloaded from the latest snapshot.
+func (a *roomActor) flush() error {
+	if !a.unsaved() || a.room == nil {
+		return nil
+	}
+	if a.dirty && a.state != nil {
+		a.state.Phase = a.room.Phase
+		a.state.DayCount = a.room.DayCount
+		serialized, err := a.state.Serialize()
+		if err != nil {
+			a.reset()
+			return err
+		}
+		a.room.Results = serialized
+		a.events = append(a.events, a.state.TakeEvents()...)
+	}
+	version := a.room.Version
+	err := a.service.tx.Transact(func(tx ports.Repositories) error {
+		if a.dirty {
+			if err := tx.Room.Update(a.room); err != nil {
+				return err
+			}
+		}
+		for userID, taken := range a.seats {
+			seat := tx.Room.RemovePlayer
+			if taken {
+				seat = tx.Room.AddPlayer
+			}
+			if err := seat(a.room.ID, userID); err != nil {
+				return err
+			}
+		}
+		if len(a.events) > 0 {
+			if err := tx.GameEvent.Append(a.room.ID, a.events); err != nil {
+				return err
+			}
+		}
+		return tx.Outbox.Add(a.outbox...)
+	})
+	if errors.Is(err, apperrors.ErrConflict) {
+		a.reset()
+		return err
+	}
+	if err != nil {
+		// The transaction rolled back, so the version bump did too.
+		a.room.Version = version
+		return err
+	}
+	a.dirty, a.outbox, a.seats, a.events = false, nil, nil, nil
+	a.saved, a.savedAt = markRoom(a.room), time.Now()
+	return nil
+}
+
+// roomCopy returns a copy of the live room that is safe to hand outside the actor.
+func (a *roomActor) roomCopy() *domain.GameRoom {
+	room := *a.room
+	return &room
+}
+
+// actorRegistry starts room actors on demand and retires them once idle.
+type actorRegistry struct {
+	mu     sync.Mutex
+	actors map[uint]*roomActor
+	closed bool
+}
+
+func newActorRegistry() *actorRegistry {
+	return &actorRegistry{actors: make(map[uint]*roomActor)}
+}
+
+// acquire returns the room's actor, starting it if needed, and holds it until release.
+func (r *actorRegistry) acquire(s *gameService, roomID uint, touch bool) (*roomActor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, errActorsStopped
+	}
+	a, ok := r.actors[roomID]
+	if !ok {
+		a = &roomActor{id: roomID, service: s, mailbox: make(chan func(), 64)}
+		r.actors[roomID] = a
+		go a.loop()
+	}
+	a.pending++
+	if touch || !ok {
+		a.lastUsed = time.Now()
+	}
+	return a, nil
+}
+
+func (r *actorRegistry) release(a *roomActor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a.pending--
+}
+
+// rooms lists the rooms that currently have an actor.
+func (r *actorRegistry) rooms() []uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]uint, 0, len(r.actors))
+	for id := range r.actors {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// retire stops the room's actor if nobody used it since the cutoff and reports whether
+// it did.
+func (r *actorRegistry) retire(roomID uint, cutoff time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.actors[roomID]
+	if r.closed || !ok || a.pending > 0 || a.lastUsed.After(cutoff) {
+		return false
+	}
+	delete(r.actors, roomID)
+	close(a.mailbox)
+	return true
+}
+
+// stop refuses further commands and returns the live actors.
+func (r *actorRegistry) stop() []*roomActor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	actors := make([]*roomActor, 0, len(r.actors))
+	for _, a := range r.actors {
+		actors = append(actors, a)
+	}
+	return actors
+}
+
+// exec runs the command on the room's actor after loading the room.
+func (s *gameService) exec(roomID uint, cmd roomCommand) error {
+	return s.send(roomID, true, func(a *roomActor) error {
+		if err := a.load(); err != nil {
+			return err
+		}
+		return cmd(a)
+	})
+}
+
+// send runs the task on the room's actor and waits for it. Housekeeping tasks are not
+// counted as use, so they do not keep an idle actor alive.
+func (s *gameService) send(roomID uint, use bool, task roomCommand) error {
+	a, err := s.actors.acquire(s, roomID, use)
+	if err != nil {
+		return err
+	}
+	defer s.actors.release(a)
+	return a.do(func() error { return task(a) })
+}
+
+// read runs a query against the live room, or against the latest snapshot when another
+// replica serves the room; such snapshots may lag by up to the snapshot interval. The
+// state is nil before the game starts, and queries must not keep references into it.
+func (s *gameService) read(roomID uint, query func(room *domain.GameRoom, state *domain.GameState) error) error {
+	err := s.exec(roomID, func(a *roomActor) error {
+		var state *domain.GameState
+		if a.room.Status != "waiting" {
+			var err error
+			if state, err = a.gameState(); err != nil {
+				return err
+			}
+		}
+		return query(a.room, state)
+	})
+	var owned *domain.RoomOwnedError
+	if !errors.As(err, &owned) {
+		return err
+	}
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return err
+	}
+	var state *domain.GameState
+	if room.Status != "waiting" {
+		if state, err = s.loadGameState(room); err != nil {
+			return err
+		}
+	}
+	return query(room, state)
+}
+
+// execWithRetry runs a command that is safe to repeat, retrying it on fresh state when
+// its snapshot lost a race with another writer of the room.
+func (s *gameService) execWithRetry(roomID uint, cmd roomCommand) error {
+	return retryOnConflict(func() error { return s.exec(roomID, cmd) })
+}
+
+// Rehydrate starts actors for the rooms that were being played, restoring each from
+// its latest snapshot. Rooms another replica still holds a lease on are left to it.
+func (s *gameService) Rehydrate() (int, error) {
+	rooms, err := s.roomRepo.ListActive()
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	var errs []error
+	for _, room := range rooms {
+		err := s.exec(room.ID, func(a *roomActor) error {
+			_, err := a.gameState()
+			return err
+		})
+		var owned *domain.RoomOwnedError
+		if errors.As(err, &owned) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %d: %w", room.ID, err))
+			continue
+		}
+		restored++
+	}
+	return restored, errors.Join(errs...)
+}
+
+// SnapshotRooms renews the leases of live rooms, persists those whose last snapshot is
+// older than the snapshot interval and retires the actors of idle rooms that are not
+// being played. A room whose lease was lost is dropped so its new owner serves it.
+func (s *gameService) SnapshotRooms(now time.Time) (int, error) {
+	saved := 0
+	var errs []error
+	for _, roomID := range s.actors.rooms() {
+		var flushed, idle bool
+		err := s.send(roomID, false, func(a *roomActor) error {
+			if a.room != nil && a.leaseUntil.Sub(now) < s.options.LeaseTTL/2 {
+				if err := a.claim(); err != nil {
+					a.reset()
+					return err
+				}
+			}
+			// Rooms with changes still pending stay resident until they are saved.
+			defer func() { idle = a.room == nil || (a.room.Status != "playing" && !a.unsaved()) }()
+			if !a.unsaved() || now.Sub(a.savedAt) < s.options.SnapshotInterval {
+				return nil
+			}
+			flushed = true
+			return a.flush()
+		})
+		if errors.Is(err, errActorsStopped) {
+			return saved, errors.Join(append(errs, err)...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %d: %w", roomID, err))
+			continue
+		}
+		if flushed {
+			saved++
+		}
+		if idle && s.actors.retire(roomID, now.Add(-actorIdleTimeout)) {
+			if err := s.releaseLease(roomID); err != nil {
+				errs = append(errs, fmt.Errorf("room %d: %w", roomID, err))
+			}
+			if s.events != nil {
+				events.Publish(context.Background(), s.events, domain.RoomRetired{RoomID: roomID})
+			}
+		}
+	}
+	return saved, errors.Join(errs...)
+}
+
+// Shutdown stops accepting commands, snapshots every live room and hands its lease back.
+func (s *gameService) Shutdown() error {
+	var errs []error
+	for _, a := range s.actors.stop() {
+		err := a.do(func() error {
+			if a.room == nil {
+				return nil
+			}
+			return errors.Join(a.flush(), s.releaseLease(a.id))
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("room %d: %w", a.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// releaseLease gives up the room's lease so another replica can take it over at once.
+func (s *gameService) releaseLease(roomID uint) error {
+	if s.leases == nil {
+		return nil
+	}
+	return s.leases.Release(roomID, s.options.Node.ID)
+}
+
+// RoomOwner returns the lease of the replica serving the room when that is not this one.
+func (s *gameService) RoomOwner(roomID uint) (*domain.RoomLease, error) {
+	if s.leases == nil {
+		return nil, nil
+	}
+	lease, ok, err := s.leases.Owner(roomID)
+	if err != nil || !ok || lease.Node == s.options.Node.ID {
+		return nil, err
+	}
+	return lease, nil
+}
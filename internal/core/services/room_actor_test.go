@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"mafia/internal/adapters/memory"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// roomStore is a room repository shared by the replicas of a test, standing in for the
+// database. It hands out copies and checks versions like the Postgres repository.
+type roomStore struct {
+	ports.RoomRepository
+	mu     sync.Mutex
+	rooms  map[uint]domain.GameRoom
+	seated map[domain.RoomMember]bool
+}
+
+func copyRoom(room domain.GameRoom) domain.GameRoom {
+	room.CoHostIDs = append([]uint(nil), room.CoHostIDs...)
+	room.ModeratorIDs = append([]uint(nil), room.ModeratorIDs...)
+	room.SpectatorIDs = append([]uint(nil), room.SpectatorIDs...)
+	room.Players = append([]domain.User(nil), room.Players...)
+	return room
+}
+
+func (s *roomStore) FindByID(id uint) (*domain.GameRoom, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room, ok := s.rooms[id]
+	if !ok {
+		return nil, apperrors.ErrNotFound
+	}
+	room = copyRoom(room)
+	return &room, nil
+}
+
+func (s *roomStore) Update(room *domain.GameRoom) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rooms[room.ID].Version != room.Version {
+		return &domain.VersionConflictError{RoomID: room.ID, Version: room.Version}
+	}
+	room.Version++
+	s.rooms[room.ID] = copyRoom(*room)
+	return nil
+}
+
+func (s *roomStore) AddPlayer(roomID, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seated == nil {
+		s.seated = make(map[domain.RoomMember]bool)
+	}
+	s.seated[domain.RoomMember{RoomID: roomID, UserID: userID}] = true
+	return nil
+}
+
+func (s *roomStore) RemovePlayer(roomID, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.seated, domain.RoomMember{RoomID: roomID, UserID: userID})
+	return nil
+}
+
+type discardOutbox struct{ ports.OutboxRepository }
+
+func (discardOutbox) Add(...domain.Event) error { return nil }
+
+type storeTx struct{ rooms *roomStore }
+
+func (t storeTx) Transact(fn func(tx ports.Repositories) error) error {
+	return fn(ports.Repositories{Room: t.rooms, Outbox: discardOutbox{}})
+}
+
+// newReplica starts a game service for the node on the shared room store and leases.
+func newReplica(rooms *roomStore, leases ports.LeaseRegistry, node string, ttl time.Duration) ports.GameService {
+	options := ports.GameOptions{Node: domain.Node{ID: node, Address: "http://" + node}, LeaseTTL: ttl}
+	return NewGameService(rooms, nil, nil, nil, nil, storeTx{rooms}, nil, nil, leases, options)
+}
+
+func newRoomStore() *roomStore {
+	return &roomStore{rooms: map[uint]domain.GameRoom{7: {ID: 7, HostID: 1, Status: "waiting"}}}
+}
+
+func ownedBy(t *testing.T, err error, node string) {
+	t.Helper()
+	var owned *domain.RoomOwnedError
+	if !errors.As(err, &owned) || owned.Lease.Node != node {
+		t.Fatalf("got %v, want the room to be owned by %s", err, node)
+	}
+}
+
+func TestLeaseHandoffOnShutdown(t *testing.T) {
+	rooms, leases := newRoomStore(), memory.NewLeaseRegistry()
+	a := newReplica(rooms, leases, "node-a", time.Minute)
+	b := newReplica(rooms, leases, "node-b", time.Minute)
+
+	if err := a.Spectate(7, 2); err != nil {
+		t.Fatalf("spectate on the first replica: %v", err)
+	}
+	ownedBy(t, b.Spectate(7, 3), "node-a")
+	if lease, err := b.RoomOwner(7); err != nil || lease == nil || lease.Address != "http://node-a" {
+		t.Fatalf("owner %+v, err %v; want node-a", lease, err)
+	}
+	if lease, err := a.RoomOwner(7); err != nil || lease != nil {
+		t.Fatalf("owner replica is told to relay to %+v, err %v", lease, err)
+	}
+
+	if err := a.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := b.Spectate(7, 3); err != nil {
+		t.Fatalf("spectate after the handoff: %v", err)
+	}
+	room, _ := rooms.FindByID(7)
+	if room.RoleOf(2) != domain.RoomRoleSpectator || room.RoleOf(3) != domain.RoomRoleSpectator {
+		t.Fatalf("spectators %v, want the changes of both replicas", room.SpectatorIDs)
+	}
+}
+
+func TestLeaseHandoffOnExpiry(t *testing.T) {
+	rooms, leases := newRoomStore(), memory.NewLeaseRegistry()
+	a := newReplica(rooms, leases, "node-a", 20*time.Millisecond)
+	b := newReplica(rooms, leases, "node-b", time.Minute)
+
+	if err := a.Spectate(7, 2); err != nil {
+		t.Fatalf("spectate on the first replica: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if err := b.Spectate(7, 3); err != nil {
+		t.Fatalf("take over the expired lease: %v", err)
+	}
+
+	// The first replica still has the room live. Its next write loses to the new owner's,
+	// after which it drops the stale copy and finds the room owned elsewhere.
+	ownedBy(t, a.Spectate(7, 4), "node-b")
+	room, _ := rooms.FindByID(7)
+	if room.RoleOf(4) != "" || room.RoleOf(3) != domain.RoomRoleSpectator {
+		t.Fatalf("spectators %v, want only the new owner's change", room.SpectatorIDs)
+	}
+}
+
+type userDirectory struct{ ports.UserRepository }
+
+func (userDirectory) FindByID(id uint) (*domain.User, error) {
+	return &domain.User{ID: id}, nil
+}
+
+type membershipTx struct {
+	rooms  *roomStore
+	outbox *recordingOutbox
+}
+
+func (t membershipTx) Transact(fn func(tx ports.Repositories) error) error {
+	return fn(ports.Repositories{Room: t.rooms, Outbox: t.outbox})
+}
+
+func TestMembershipIsSavedWithItsEvents(t *testing.T) {
+	rooms, outbox := newRoomStore(), &recordingOutbox{}
+	options := ports.GameOptions{Node: domain.Node{ID: "node-a"}, LeaseTTL: time.Minute}
+	s := NewGameService(rooms, nil, nil, nil, userDirectory{}, membershipTx{rooms, outbox}, nil, nil, nil, options)
+
+	for _, userID := range []uint{2, 3} {
+		if err := s.JoinRoom(7, userID); err != nil {
+			t.Fatalf("user %d joins: %v", userID, err)
+		}
+	}
+	if err := s.KickPlayer(7, 1, 3); err != nil {
+		t.Fatalf("kick: %v", err)
+	}
+	if err := s.Disconnect(7, 2); err != nil {
+		t.Fatalf("disconnect: %v", err)
+	}
+
+	if len(rooms.seated) != 1 || !rooms.seated[domain.RoomMember{RoomID: 7, UserID: 2}] {
+		t.Fatalf("seated %v, want only user 2", rooms.seated)
+	}
+	want := []domain.Event{
+		domain.PlayerJoined{RoomMember: domain.RoomMember{RoomID: 7, UserID: 2}},
+		domain.PlayerJoined{RoomMember: domain.RoomMember{RoomID: 7, UserID: 3}},
+		domain.PlayerKicked{RoomMember: domain.RoomMember{RoomID: 7, UserID: 3}},
+		domain.PlayerLeft{RoomMember: domain.RoomMember{RoomID: 7, UserID: 3}},
+		domain.PlayerDisconnected{RoomMember: domain.RoomMember{RoomID: 7, UserID: 2}},
+	}
+	if len(outbox.events) != len(want) {
+		t.Fatalf("outbox %v, want %v", outbox.events, want)
+	}
+	for i := range want {
+		if outbox.events[i] != want[i] {
+			t.Fatalf("outbox %v, want %v", outbox.events, want)
+		}
+	}
+}
+
+func TestActorRetriesAfterAnotherWriter(t *testing.T) {
+	rooms := newRoomStore()
+	s := newReplica(rooms, nil, "node-a", time.Minute)
+	if err := s.Spectate(7, 2); err != nil {
+		t.Fatalf("spectate: %v", err)
+	}
+
+	// Someone else saves the room behind the actor's back.
+	rooms.mu.Lock()
+	room := rooms.rooms[7]
+	room.SpectatorIDs = append(room.SpectatorIDs, 3)
+	room.Version++
+	rooms.rooms[7] = room
+	rooms.mu.Unlock()
+
+	if err := s.Spectate(7, 4); err != nil {
+		t.Fatalf("spectate on a stale copy: %v", err)
+	}
+	saved, _ := rooms.FindByID(7)
+	if !reflect.DeepEqual(saved.SpectatorIDs, []uint{2, 3, 4}) || saved.Version != 3 {
+		t.Fatalf("saved spectators %v at version %d, want 2, 3 and 4 at version 3", saved.SpectatorIDs, saved.Version)
+	}
+}
@@ -2,22 +2,28 @@ package services
 
 import "mafia/internal/ports"
 
-func NewServices(repos ports.Repositories, infra ports.Infrastructure, _ ports.SFU) ports.Services {
-	user := NewUserService(repos.User, repos.Wallet, infra)
+func NewServices(repos ports.Repositories, infra ports.Infrastructure, options ports.GameOptions, moderationOptions ports.ModerationOptions, _ ports.SFU) ports.Services {
+	moderation := NewModerationService(repos.Report, moderationOptions)
+	user := NewUserService(repos.User, repos.Wallet, repos.Tx, infra, moderation)
 	wallet := NewWalletService(repos.Wallet, infra.Payments)
-	challenge := NewChallengeService(repos.Challenge, repos.User, repos.Wallet, infra.Events)
-	group := NewGroupService(repos.Group, repos.User, infra.Events)
-	game := NewGameService(repos.Room, repos.Role, repos.User, infra.Events)
+	challenge := NewChallengeService(repos.Challenge, repos.User, repos.Tx)
+	group := NewGroupService(repos.Group, repos.User, repos.Tx, moderation)
+	game := NewGameService(repos.Room, repos.Role, repos.Scenario, repos.GameEvent, repos.User, repos.Tx, infra.Events, infra.Presence, infra.Leases, options)
+	chat := NewChatService(repos.Chat, repos.Block, game, moderation, infra.Events, options.Chat)
 	shop := NewShopService(repos.Shop, repos.Wallet)
 	admin := NewAdminService(repos.Role, repos.Rule, repos.Scenario)
+	outbox := NewOutboxService(repos.Tx, infra.Events)
 
 	return ports.Services{
-		User:      user,
-		Wallet:    wallet,
-		Challenge: challenge,
-		Group:     group,
-		Game:      game,
-		Shop:      shop,
-		Admin:     admin,
+		User:       user,
+		Wallet:     wallet,
+		Challenge:  challenge,
+		Group:      group,
+		Game:       game,
+		Chat:       chat,
+		Shop:       shop,
+		Admin:      admin,
+		Outbox:     outbox,
+		Moderation: moderation,
 	}
 }
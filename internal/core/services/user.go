@@ -3,9 +3,12 @@ package services
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"mafia/internal/core/domain"
 	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -18,12 +21,17 @@ type userService struct {
 	walletRepo    ports.WalletRepository
 	cache         ports.Cache
 	queue         ports.Queue
-	events        ports.EventBus
+	tx            ports.Transactor
 	notifications ports.NotificationSender
+	moderation    ports.ModerationService
 }
 
-func NewUserService(userRepo ports.UserRepository, walletRepo ports.WalletRepository, infra ports.Infrastructure) ports.UserService {
-	return &userService{userRepo: userRepo, walletRepo: walletRepo, cache: infra.Cache, queue: infra.Queue, events: infra.Events, notifications: infra.Notifications}
+func NewUserService(userRepo ports.UserRepository, walletRepo ports.WalletRepository, tx ports.Transactor, infra ports.Infrastructure, moderation ports.ModerationService) ports.UserService {
+	s := &userService{userRepo: userRepo, walletRepo: walletRepo, cache: infra.Cache, queue: infra.Queue, tx: tx, notifications: infra.Notifications, moderation: moderation}
+	if s.queue != nil {
+		s.queue.Register(domain.JobSendNotification, s.sendNotification)
+	}
+	return s
 }
 
 func (s *userService) Register(phone string) error {
@@ -37,23 +45,19 @@ func (s *userService) Register(phone string) error {
 		OTP:        otp,
 		OTPExpires: time.Now().Add(5 * time.Minute),
 	}
-	if err := s.userRepo.Create(user); err != nil {
+	err = s.tx.Transact(func(tx ports.Repositories) error {
+		if err := tx.User.Create(user); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(domain.UserRegistered{UserID: user.ID, RegisteredAt: user.CreatedAt})
+	})
+	if err != nil {
 		return err
 	}
 	if s.cache != nil {
-		s.cache.Set(context.Background(), otpCacheKey(phone), otp, 5*time.Minute)
-	}
-	if s.queue != nil {
-		_ = s.queue.Enqueue(func() {
-			if s.notifications != nil {
-				_ = s.notifications.Send(user.ID, "sms", fmt.Sprintf("Your verification code is %s", otp))
-			}
-			if s.events != nil {
-				s.events.Publish(context.Background(), "user.registered", user)
-			}
-		})
+		_ = s.cache.Set(context.Background(), otpCacheKey(phone), otp, 5*time.Minute)
 	}
-	return nil
+	return s.notify(user.ID, "sms", fmt.Sprintf("Your verification code is %s", otp))
 }
 
 func (s *userService) VerifyOTP(req domain.VerifyOTPRequest) (string, uint, error) {
@@ -63,22 +67,37 @@ func (s *userService) VerifyOTP(req domain.VerifyOTPRequest) (string, uint, erro
 	}
 	cachedOTP := user.OTP
 	if s.cache != nil {
-		if val, ok := s.cache.Get(context.Background(), otpCacheKey(req.Phone)); ok {
-			if code, ok := val.(string); ok {
-				cachedOTP = code
-			}
+		var code string
+		if ok, err := s.cache.Get(context.Background(), otpCacheKey(req.Phone), &code); err == nil && ok {
+			cachedOTP = code
 		}
 	}
 	if cachedOTP != req.OTP || time.Now().After(user.OTPExpires) {
 		return "", 0, fmt.Errorf("invalid otp")
 	}
 	user.OTP = ""
-	s.userRepo.Update(user)
-
-	s.userRepo.Update(user)
-
-	wallet := domain.Wallet{UserID: user.ID, Coins: 100, Diamonds: 10}
-	s.walletRepo.Create(&wallet)
+	err = s.tx.Transact(func(tx ports.Repositories) error {
+		if err := tx.User.Update(user); err != nil {
+			return err
+		}
+		// Returning users verify every login; only the first verification opens the
+		// wallet and is announced.
+		_, err := tx.Wallet.FindByUserID(user.ID)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			return err
+		}
+		wallet := domain.Wallet{UserID: user.ID, Coins: 100, Diamonds: 10}
+		if err := tx.Wallet.Create(&wallet); err != nil {
+			return err
+		}
+		return tx.Outbox.Add(domain.UserVerified{UserID: user.ID})
+	})
+	if err != nil {
+		return "", 0, err
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
@@ -86,16 +105,8 @@ func (s *userService) VerifyOTP(req domain.VerifyOTPRequest) (string, uint, erro
 	})
 	tokenStr, _ := token.SignedString(jwtKey)
 
-	if s.queue != nil {
-		_ = s.queue.Enqueue(func() {
-			if s.events != nil {
-				s.events.Publish(context.Background(), "user.verified", user)
-			}
-			if s.notifications != nil {
-				_ = s.notifications.Send(user.ID, "in-app", "Welcome to Mafia! Your account is verified.")
-			}
-		})
-	}
+	// The welcome note is a courtesy; the login stands without it.
+	_ = s.notify(user.ID, "in-app", "Welcome to Mafia! Your account is verified.")
 
 	return tokenStr, user.ID, nil
 }
@@ -135,7 +146,11 @@ func (s *userService) UpdateProfile(id uint, req domain.UpdateProfileRequest) (*
 	if err != nil {
 		return nil, err
 	}
-	user.Profile.Name = req.Name
+	name, err := s.moderation.Screen(domain.ContentSubject{Kind: domain.ContentName, UserID: id}, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	user.Profile.Name = name
 	user.Profile.Avatar = req.Avatar
 	s.userRepo.Update(user)
 	return &user.Profile, nil
@@ -186,6 +201,26 @@ func generateOTP() string {
 	return fmt.Sprintf("%06d", int(b[0])<<16|int(b[1])<<8|int(b[2])%1000000)
 }
 
+// notify queues a message for delivery, so it is retried when the sender fails.
+func (s *userService) notify(userID uint, channel, message string) error {
+	if s.queue == nil {
+		return nil
+	}
+	return s.queue.Enqueue(context.Background(), domain.JobSendNotification, domain.NotificationJob{UserID: userID, Channel: channel, Message: message})
+}
+
+// sendNotification handles JobSendNotification.
+func (s *userService) sendNotification(_ context.Context, payload []byte) error {
+	var job domain.NotificationJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	if s.notifications == nil {
+		return nil
+	}
+	return s.notifications.Send(job.UserID, job.Channel, job.Message)
+}
+
 func otpCacheKey(phone string) string {
 	return fmt.Sprintf("otp:%s", phone)
 }
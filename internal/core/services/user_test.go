@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mafia/internal/core/domain"
+	"mafia/internal/ports"
+	apperrors "mafia/pkg/errors"
+	"testing"
+	"time"
+)
+
+type phoneBook struct {
+	ports.UserRepository
+	user domain.User
+}
+
+func (b *phoneBook) FindByPhone(string) (*domain.User, error) {
+	user := b.user
+	return &user, nil
+}
+
+func (b *phoneBook) Update(user *domain.User) error {
+	b.user = *user
+	return nil
+}
+
+// uniqueWallets refuses a second wallet per user, like the unique user_id column.
+type uniqueWallets struct {
+	ports.WalletRepository
+	wallets map[uint]domain.Wallet
+}
+
+func (w *uniqueWallets) Create(wallet *domain.Wallet) error {
+	if _, ok := w.wallets[wallet.UserID]; ok {
+		return apperrors.ErrConflict
+	}
+	w.wallets[wallet.UserID] = *wallet
+	return nil
+}
+
+func (w *uniqueWallets) FindByUserID(id uint) (*domain.Wallet, error) {
+	wallet, ok := w.wallets[id]
+	if !ok {
+		return nil, apperrors.ErrNotFound
+	}
+	return &wallet, nil
+}
+
+type recordingOutbox struct {
+	ports.OutboxRepository
+	events []domain.Event
+}
+
+func (o *recordingOutbox) Add(events ...domain.Event) error {
+	o.events = append(o.events, events...)
+	return nil
+}
+
+type accountTx struct {
+	users   ports.UserRepository
+	wallets *uniqueWallets
+	outbox  *recordingOutbox
+}
+
+func (t accountTx) Transact(fn func(tx ports.Repositories) error) error {
+	return fn(ports.Repositories{User: t.users, Wallet: t.wallets, Outbox: t.outbox})
+}
+
+func TestVerifyOTPOpensOneWallet(t *testing.T) {
+	users := &phoneBook{user: domain.User{ID: 4, Phone: "09120000000"}}
+	wallets := &uniqueWallets{wallets: map[uint]domain.Wallet{}}
+	outbox := &recordingOutbox{}
+	s := NewUserService(users, wallets, accountTx{users, wallets, outbox}, ports.Infrastructure{}, nil)
+
+	for i := 0; i < 2; i++ {
+		users.user.OTP, users.user.OTPExpires = "123456", time.Now().Add(time.Minute)
+		if _, _, err := s.VerifyOTP(domain.VerifyOTPRequest{Phone: users.user.Phone, OTP: "123456"}); err != nil {
+			t.Fatalf("verification %d: %v", i+1, err)
+		}
+	}
+	if wallet := wallets.wallets[4]; len(wallets.wallets) != 1 || wallet.Coins != 100 || wallet.Diamonds != 10 {
+		t.Fatalf("wallets %v, want one starting wallet", wallets.wallets)
+	}
+	if len(outbox.events) != 1 || outbox.events[0] != (domain.UserVerified{UserID: 4}) {
+		t.Fatalf("events %v, want UserVerified once", outbox.events)
+	}
+}
+
+// newPhones knows no phone number yet.
+type newPhones struct{ ports.UserRepository }
+
+func (newPhones) FindByPhone(string) (*domain.User, error) { return nil, apperrors.ErrNotFound }
+
+func (newPhones) Create(user *domain.User) error {
+	user.ID = 5
+	return nil
+}
+
+var errQueueDown = errors.New("queue down")
+
+type brokenQueue struct{ ports.Queue }
+
+func (brokenQueue) Register(string, func(context.Context, []byte) error) {}
+
+func (brokenQueue) Enqueue(context.Context, string, any) error { return errQueueDown }
+
+func TestRegisterReportsUnsentCode(t *testing.T) {
+	users := newPhones{}
+	s := NewUserService(users, nil, accountTx{users: users, outbox: &recordingOutbox{}}, ports.Infrastructure{Queue: brokenQueue{}}, nil)
+
+	if err := s.Register("09120000000"); !errors.Is(err, errQueueDown) {
+		t.Fatalf("got %v, want the queue error", err)
+	}
+}
@@ -41,6 +41,8 @@ type RoomRepository interface {
 	Update(*domain.GameRoom) error
 	AddPlayer(roomID, userID uint) error
 	RemovePlayer(roomID, userID uint) error
+	ListExpired(now time.Time) ([]domain.GameRoom, error)
+	ListActive() ([]domain.GameRoom, error)
 }
 
 type RoleRepository interface {
@@ -66,21 +68,72 @@ type RuleRepository interface {
 	Delete(id uint) error
 }
 
+// GameEventRepository stores the append-only event log of each room.
+type GameEventRepository interface {
+	// Append numbers the events after the room's latest and stores them.
+	Append(roomID uint, events []domain.GameEvent) error
+	ListByRoom(roomID uint) ([]domain.GameEvent, error)
+}
+
+// OutboxRepository holds events written together with the change they report until
+// the relay delivers them.
+type OutboxRepository interface {
+	Add(events ...domain.Event) error
+	// Pending returns the oldest undelivered messages. Inside a transaction it claims
+	// them, so concurrent relays skip messages another relay is delivering.
+	Pending(limit int) ([]domain.OutboxMessage, error)
+	// Defer sets a message aside until the given time, so it stops holding up the
+	// messages behind it.
+	Defer(id uint, until time.Time, reason string) error
+	Delete(ids []uint) error
+}
+
+// Transactor runs changes atomically. The repositories handed to fn write inside one
+// transaction, which commits when fn returns nil and rolls back otherwise.
+type Transactor interface {
+	Transact(fn func(tx Repositories) error) error
+}
+
+// ChatRepository stores the chat of each room.
+type ChatRepository interface {
+	Create(*domain.ChatMessage) error
+	// List returns a page of history matching the query, newest first.
+	List(query domain.ChatQuery) ([]domain.ChatMessage, error)
+}
+
+// BlockRepository stores the users each user has blocked.
+type BlockRepository interface {
+	Block(userID, targetID uint) error
+	Unblock(userID, targetID uint) error
+	ListBlocked(userID uint) ([]uint, error)
+}
+
+type ReportRepository interface {
+	Create(*domain.Report) error
+}
+
 type ScenarioRepository interface {
 	Create(*domain.Scenario) error
+	FindByID(id uint) (*domain.Scenario, error)
 	List() ([]domain.Scenario, error)
 	Update(*domain.Scenario) error
 	Delete(id uint) error
 }
 
+// Cache stores JSON-encoded values with an expiration.
 type Cache interface {
-	Set(ctx context.Context, key string, value interface{}, ttl time.Duration)
-	Get(ctx context.Context, key string) (interface{}, bool)
-	Delete(ctx context.Context, key string)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Get decodes the cached value into dest and reports whether the key was found.
+	Get(ctx context.Context, key string, dest interface{}) (bool, error)
+	Delete(ctx context.Context, key string) error
 }
 
+// Queue runs named background jobs. Payloads are stored as JSON, so durable queues keep
+// jobs across restarts; handlers decode the payload themselves and a returned error
+// retries the job with backoff until it is dead-lettered.
 type Queue interface {
-	Enqueue(func()) error
+	Register(job string, handler func(ctx context.Context, payload []byte) error)
+	Enqueue(ctx context.Context, job string, payload interface{}) error
 	Close()
 }
 
@@ -89,6 +142,28 @@ type EventBus interface {
 	Subscribe(topic string, handler func(ctx context.Context, payload interface{}))
 }
 
+// PresenceStore tracks which room members are connected.
+type PresenceStore interface {
+	Online(roomID, userID uint, at time.Time) error
+	Offline(roomID, userID uint, at time.Time) error
+	Get(roomID, userID uint) (*domain.Presence, bool, error)
+	List(roomID uint) ([]domain.Presence, error)
+	// Expired lists members offline since before the cutoff that have not been handled.
+	Expired(cutoff time.Time) ([]domain.Presence, error)
+	MarkHandled(roomID, userID uint) error
+	Remove(roomID, userID uint) error
+}
+
+// LeaseRegistry hands out expiring ownership of rooms to API replicas.
+type LeaseRegistry interface {
+	// Acquire grants or extends the node's lease on the room. When another node holds an
+	// unexpired lease, that lease is returned and acquired is false.
+	Acquire(roomID uint, node domain.Node, ttl time.Duration) (lease *domain.RoomLease, acquired bool, err error)
+	Release(roomID uint, nodeID string) error
+	// Owner returns the unexpired lease on the room, if any.
+	Owner(roomID uint) (*domain.RoomLease, bool, error)
+}
+
 type NotificationSender interface {
 	Send(userID uint, channel, message string) error
 }
@@ -103,6 +178,43 @@ type Infrastructure struct {
 	Events        EventBus
 	Notifications NotificationSender
 	Payments      PaymentProvider
+	Presence      PresenceStore
+	Leases        LeaseRegistry
+}
+
+// GameOptions tunes the game engine for a deployment.
+type GameOptions struct {
+	// PhaseDurations are keyed by room type; a type's entry overrides the "default"
+	// entry phase by phase. Scenarios and room settings override both.
+	PhaseDurations map[string]domain.PhaseDurations
+	// DisconnectGrace is how long a player may stay disconnected before AbsencePolicy applies.
+	DisconnectGrace time.Duration
+	AbsencePolicy   string
+	// SnapshotInterval is how often live game state is written back between phase
+	// transitions. Zero writes every change right away.
+	SnapshotInterval time.Duration
+	// Node is this replica; rooms are owned by one replica at a time through leases
+	// that last LeaseTTL unless renewed.
+	Node     domain.Node
+	LeaseTTL time.Duration
+	Chat     ChatOptions
+}
+
+// ChatOptions tunes room chat. Each sender may post RateLimit messages per RateWindow
+// on every replica.
+type ChatOptions struct {
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// ModerationOptions tunes the content filter. Actions are keyed by the kind of text;
+// Words are blocked on top of the built-in lists. A user caught ReportThreshold times
+// within ReportWindow is reported automatically.
+type ModerationOptions struct {
+	Actions         map[string]string
+	Words           []string
+	ReportThreshold int
+	ReportWindow    time.Duration
 }
 
 type Repositories struct {
@@ -115,6 +227,12 @@ type Repositories struct {
 	Shop      ShopRepository
 	Rule      RuleRepository
 	Scenario  ScenarioRepository
+	GameEvent GameEventRepository
+	Outbox    OutboxRepository
+	Chat      ChatRepository
+	Block     BlockRepository
+	Report    ReportRepository
+	Tx        Transactor
 }
 
 type UserService interface {
@@ -148,14 +266,57 @@ type GroupService interface {
 }
 
 type GameService interface {
-	CreateRoom(hostID uint, roomType string) (*domain.GameRoom, error)
+	CreateRoom(hostID uint, req domain.CreateRoomRequest) (*domain.GameRoom, error)
 	ListRooms() ([]domain.GameRoom, error)
 	JoinRoom(roomID, userID uint) error
 	LeaveRoom(roomID, userID uint) error
-	StartGame(roomID uint) error
-	AdvancePhase(roomID uint) (*domain.GameRoom, error)
+	Spectate(roomID, userID uint) error
+	StartGame(roomID, actorID uint) error
+	AdvancePhase(roomID, actorID uint) (*domain.GameRoom, error)
+	KickPlayer(roomID, actorID, targetID uint) error
+	UpdateSettings(roomID, actorID uint, settings domain.RoomSettings) (*domain.GameRoom, error)
+	SetMemberRole(roomID, actorID, targetID uint, role string) error
 	Vote(roomID, userID, targetID uint) error
-	UseAbility(roomID, userID uint, ability string, targetID uint) error
+	UseAbility(roomID, userID uint, req domain.AbilityRequest) error
+	AdvanceExpiredPhases(now time.Time) (int, error)
+	Disarm(roomID, userID uint, req domain.DisarmRequest) (bool, error)
+	GiveSpeakingTurn(roomID, actorID uint, req domain.SpeakingTurnRequest) (*domain.SpeakingTurn, error)
+	EndSpeakingTurn(roomID, actorID uint) error
+	PassTurn(roomID, userID uint) error
+	RequestChallenge(roomID, userID uint) error
+	AcceptChallenge(roomID, speakerID, challengerID uint) error
+	// AdvanceSpeakingTurns moves on the rooms served here whose speaking turn ran out.
+	AdvanceSpeakingTurns(now time.Time) (int, error)
+	// VoiceRules says who may hear whom in the room's voice channel right now.
+	VoiceRules(roomID uint) (*domain.VoiceRules, error)
+	// ChatRules says who may post to and read each chat channel of the room right now.
+	ChatRules(roomID uint) (*domain.ChatRules, error)
+	View(roomID, userID uint) (*domain.PlayerView, error)
+	Replay(roomID uint) (*domain.Replay, error)
+	MemberRole(roomID, userID uint) (string, error)
+	Connect(roomID, userID uint) error
+	Disconnect(roomID, userID uint) error
+	ExpireAbsences(now time.Time) (int, error)
+	GameState(roomID uint) (*domain.GameState, error)
+	Rehydrate() (int, error)
+	SnapshotRooms(now time.Time) (int, error)
+	Shutdown() error
+	// RoomOwner returns the lease of the replica serving the room when that is not
+	// this replica.
+	RoomOwner(roomID uint) (*domain.RoomLease, error)
+}
+
+type ChatService interface {
+	Post(roomID, userID uint, req domain.ChatRequest) (*domain.ChatMessage, error)
+	// History returns a page of the channel, newest first, without the chat of users
+	// the reader blocked.
+	History(roomID, userID uint, channel string, before uint, limit int) ([]domain.ChatMessage, error)
+	// Recipients lists the members who may read the message and have not blocked its
+	// sender.
+	Recipients(msg domain.ChatMessage) ([]uint, error)
+	Block(userID, targetID uint) error
+	Unblock(userID, targetID uint) error
+	Blocked(userID uint) ([]uint, error)
 }
 
 type ShopService interface {
@@ -183,14 +344,45 @@ type AdminService interface {
 }
 
 type Services struct {
-	User      UserService
-	Wallet    WalletService
-	Challenge ChallengeService
-	Group     GroupService
-	Game      GameService
-	Shop      ShopService
-	Admin     AdminService
+	User       UserService
+	Wallet     WalletService
+	Challenge  ChallengeService
+	Group      GroupService
+	Game       GameService
+	Chat       ChatService
+	Shop       ShopService
+	Admin      AdminService
+	Outbox     OutboxService
+	Moderation ModerationService
+}
+
+// OutboxService delivers stored events to the event bus.
+type OutboxService interface {
+	// Relay publishes a batch of pending events and reports how many it delivered.
+	Relay() (int, error)
 }
 
-// SFU represents the WebRTC bridge used by websocket handlers.
-type SFU interface{}
+// ModerationService screens user-generated text before it is stored.
+type ModerationService interface {
+	// Screen returns the text to store, masked if need be, or an error when the text
+	// is rejected.
+	Screen(subject domain.ContentSubject, text string) (string, error)
+}
+
+// SFU forwards voice between the members of a room. Peers are keyed by room and user
+// and owned by the websocket client that joined them; signal delivers the server's
+// offers and ICE candidates to that client.
+type SFU interface {
+	Join(clientID string, roomID, userID uint, signal func(domain.WSMessage)) error
+	Answer(clientID string, roomID, userID uint, sdp string) error
+	Candidate(clientID string, roomID, userID uint, candidate domain.VoiceCandidate) error
+	// Route applies who may hear whom in a room.
+	Route(rules domain.VoiceRules)
+	// Serving reports whether any member of the room is connected to this SFU.
+	Serving(roomID uint) bool
+	// Leave drops the user's peer in the room, whichever client owns it.
+	Leave(roomID, userID uint)
+	// Release drops every peer owned by the client.
+	Release(clientID string)
+	Close()
+}
@@ -39,3 +39,14 @@ func (l *List) IsBlocked(userID, targetID string) bool {
 	_, ok := l.blocked[userID][targetID]
 	return ok
 }
+
+// Blocked returns the targets the caller has blocked.
+func (l *List) Blocked(userID string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	targets := make([]string, 0, len(l.blocked[userID]))
+	for targetID := range l.blocked[userID] {
+		targets = append(targets, targetID)
+	}
+	return targets
+}
@@ -2,15 +2,19 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"time"
 )
 
-// Cache defines a minimal interface for caching values with an expiration.
+// Cache defines a minimal interface for caching values with an expiration. Values are
+// stored as JSON, so Get decodes into a typed destination the same way whichever
+// backend holds the entry.
 type Cache interface {
-	Set(ctx context.Context, key string, value interface{}, ttl time.Duration)
-	Get(ctx context.Context, key string) (interface{}, bool)
-	Delete(ctx context.Context, key string)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Get decodes the cached value into dest and reports whether the key was found.
+	Get(ctx context.Context, key string, dest interface{}) (bool, error)
+	Delete(ctx context.Context, key string) error
 }
 
 // InMemoryCache is a lightweight cache with TTL support for demo purposes.
@@ -20,7 +24,7 @@ type InMemoryCache struct {
 }
 
 type cacheItem struct {
-	value     interface{}
+	value     []byte
 	expiresAt time.Time
 	hasExpiry bool
 }
@@ -31,36 +35,44 @@ func NewInMemoryCache() *InMemoryCache {
 }
 
 // Set stores a value with an optional TTL.
-func (c *InMemoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) {
+func (c *InMemoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	item := cacheItem{value: value}
+	item := cacheItem{value: data}
 	if ttl > 0 {
 		item.expiresAt = time.Now().Add(ttl)
 		item.hasExpiry = true
 	}
 	c.items[key] = item
+	return nil
 }
 
-// Get retrieves a value if it exists and has not expired.
-func (c *InMemoryCache) Get(_ context.Context, key string) (interface{}, bool) {
+// Get decodes a value into dest if it exists and has not expired.
+func (c *InMemoryCache) Get(_ context.Context, key string, dest interface{}) (bool, error) {
 	c.mu.RLock()
 	item, ok := c.items[key]
 	c.mu.RUnlock()
 	if !ok {
-		return nil, false
+		return false, nil
 	}
 	if item.hasExpiry && time.Now().After(item.expiresAt) {
-		c.Delete(context.Background(), key)
-		return nil, false
+		return false, c.Delete(context.Background(), key)
+	}
+	if err := json.Unmarshal(item.value, dest); err != nil {
+		return false, err
 	}
-	return item.value, true
+	return true, nil
 }
 
 // Delete removes a value from the cache.
-func (c *InMemoryCache) Delete(_ context.Context, key string) {
+func (c *InMemoryCache) Delete(_ context.Context, key string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.items, key)
+	return nil
 }
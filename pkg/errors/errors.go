@@ -13,4 +13,6 @@ var (
 	ErrConflict = errors.New("conflict")
 	// ErrInvalid is returned when input validation fails.
 	ErrInvalid = errors.New("invalid")
+	// ErrRateLimited is returned when a caller acts too often.
+	ErrRateLimited = errors.New("rate limited")
 )
@@ -16,13 +16,17 @@ type SimpleBus struct {
 	mu          sync.RWMutex
 	subscribers map[string][]func(context.Context, interface{})
 	dispatch    func(func()) error
+	dropped     func(topic string, err error)
 }
 
 // NewSimpleBus builds a bus that optionally dispatches handlers through a worker queue.
-func NewSimpleBus(dispatch func(func()) error) *SimpleBus {
+// Handlers run in publish order when the dispatcher runs one task at a time. dropped,
+// when set, is told about handler calls the dispatcher refused.
+func NewSimpleBus(dispatch func(func()) error, dropped func(topic string, err error)) *SimpleBus {
 	return &SimpleBus{
 		subscribers: make(map[string][]func(context.Context, interface{})),
 		dispatch:    dispatch,
+		dropped:     dropped,
 	}
 }
 
@@ -35,7 +39,9 @@ func (b *SimpleBus) Publish(ctx context.Context, topic string, payload interface
 	for _, h := range handlers {
 		handler := h
 		if b.dispatch != nil {
-			_ = b.dispatch(func() { handler(ctx, payload) })
+			if err := b.dispatch(func() { handler(ctx, payload) }); err != nil && b.dropped != nil {
+				b.dropped(topic, err)
+			}
 			continue
 		}
 		go handler(ctx, payload)
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// clusterChannel is the broker channel replicas exchange events on.
+const clusterChannel = "mafia.events"
+
+// Broker carries raw messages between processes.
+type Broker interface {
+	Publish(ctx context.Context, channel string, data []byte) error
+	Subscribe(ctx context.Context, channel string, handler func(data []byte)) error
+}
+
+// clusterEnvelope tags an event envelope with the node that published it.
+type clusterEnvelope struct {
+	Node string `json:"node"`
+	Envelope
+}
+
+// ClusterBus delivers events to local subscribers and relays registered events to the
+// other nodes through a broker, sealed in versioned envelopes. Unregistered topics
+// never leave the node.
+type ClusterBus struct {
+	local    *SimpleBus
+	broker   Broker
+	node     string
+	registry *Registry
+}
+
+// NewClusterBus wraps a local bus and starts listening for events from other nodes.
+func NewClusterBus(ctx context.Context, local *SimpleBus, broker Broker, node string) (*ClusterBus, error) {
+	b := &ClusterBus{local: local, broker: broker, node: node, registry: NewRegistry()}
+	if err := broker.Subscribe(ctx, clusterChannel, b.receive); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Register relays events of the prototype's type to other nodes. Received envelopes
+// are decoded into that type when their schema version matches.
+func (b *ClusterBus) Register(prototype Event) {
+	b.registry.Register(prototype)
+}
+
+// Publish delivers the payload locally and, for registered events, to the other nodes.
+// An envelope attached to ctx is forwarded as is, keeping its ID.
+func (b *ClusterBus) Publish(ctx context.Context, topic string, payload interface{}) {
+	b.local.Publish(ctx, topic, payload)
+
+	event, ok := payload.(Event)
+	if !ok || event.EventTopic() != topic || !b.registry.Knows(event) {
+		return
+	}
+	env, ok := EnvelopeFrom(ctx)
+	if !ok || env.Topic != topic {
+		var err error
+		if env, err = Seal(event); err != nil {
+			return
+		}
+	}
+	data, err := json.Marshal(clusterEnvelope{Node: b.node, Envelope: env})
+	if err != nil {
+		return
+	}
+	_ = b.broker.Publish(ctx, clusterChannel, data)
+}
+
+// Subscribe registers a handler for events published on any node.
+func (b *ClusterBus) Subscribe(topic string, handler func(context.Context, interface{})) {
+	b.local.Subscribe(topic, handler)
+}
+
+// receive publishes events from other nodes locally. Nodes running another schema
+// version of an event skip it rather than misread it.
+func (b *ClusterBus) receive(data []byte) {
+	var env clusterEnvelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Node == b.node {
+		return
+	}
+	event, err := b.registry.Decode(env.Envelope)
+	if err != nil {
+		return
+	}
+	b.local.Publish(WithEnvelope(context.Background(), env.Envelope), env.Topic, event)
+}
+
+// MemoryBroker connects cluster buses within one process. It stands in for a real
+// broker in single-node deployments and tests.
+type MemoryBroker struct {
+	mu       sync.RWMutex
+	handlers map[string][]func([]byte)
+}
+
+// NewMemoryBroker creates an in-process broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{handlers: make(map[string][]func([]byte))}
+}
+
+// Publish hands the message to every subscriber of the channel.
+func (m *MemoryBroker) Publish(_ context.Context, channel string, data []byte) error {
+	m.mu.RLock()
+	handlers := m.handlers[channel]
+	m.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(append([]byte(nil), data...))
+	}
+	return nil
+}
+
+// Subscribe registers a handler for the channel.
+func (m *MemoryBroker) Subscribe(_ context.Context, channel string, handler func([]byte)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[channel] = append(m.handlers[channel], handler)
+	return nil
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type roomOpened struct {
+	RoomID uint `json:"room_id"`
+}
+
+func (roomOpened) EventTopic() string { return "room.opened" }
+func (roomOpened) EventVersion() int  { return 1 }
+
+// roomOpenedV2 is a later schema of roomOpened that older nodes cannot read.
+type roomOpenedV2 struct {
+	RoomID uint   `json:"room_id"`
+	Code   string `json:"code"`
+}
+
+func (roomOpenedV2) EventTopic() string { return "room.opened" }
+func (roomOpenedV2) EventVersion() int  { return 2 }
+
+type roomClosed struct {
+	RoomID uint `json:"room_id"`
+}
+
+func (roomClosed) EventTopic() string { return "room.closed" }
+func (roomClosed) EventVersion() int  { return 1 }
+
+// newNode starts a cluster bus that delivers events synchronously, as the dedicated
+// single worker of the API does.
+func newNode(t *testing.T, broker Broker, node string, prototypes ...Event) *ClusterBus {
+	t.Helper()
+	local := NewSimpleBus(func(task func()) error { task(); return nil }, nil)
+	bus, err := NewClusterBus(context.Background(), local, broker, node)
+	if err != nil {
+		t.Fatalf("start %s: %v", node, err)
+	}
+	for _, prototype := range prototypes {
+		bus.Register(prototype)
+	}
+	return bus
+}
+
+// collect records the events of type T delivered on the bus.
+func collect[T Event](bus Bus) *[]T {
+	var got []T
+	Subscribe(bus, func(_ context.Context, event T) { got = append(got, event) })
+	return &got
+}
+
+func TestClusterBusRelaysRegisteredEvents(t *testing.T) {
+	broker := NewMemoryBroker()
+	a := newNode(t, broker, "node-a", roomOpened{})
+	b := newNode(t, broker, "node-b", roomOpened{})
+	atA, atB := collect[roomOpened](a), collect[roomOpened](b)
+
+	Publish(context.Background(), a, roomOpened{RoomID: 7})
+
+	if len(*atA) != 1 || (*atA)[0].RoomID != 7 {
+		t.Fatalf("publishing node got %v, want the event once", *atA)
+	}
+	if len(*atB) != 1 || (*atB)[0].RoomID != 7 {
+		t.Fatalf("other node got %v, want the event once", *atB)
+	}
+}
+
+func TestClusterBusKeepsUnregisteredEventsLocal(t *testing.T) {
+	broker := NewMemoryBroker()
+	a := newNode(t, broker, "node-a", roomOpened{})
+	b := newNode(t, broker, "node-b", roomOpened{}, roomClosed{})
+	atA, atB := collect[roomClosed](a), collect[roomClosed](b)
+
+	Publish(context.Background(), a, roomClosed{RoomID: 7})
+
+	if len(*atA) != 1 {
+		t.Fatalf("publishing node got %v, want the event", *atA)
+	}
+	if len(*atB) != 0 {
+		t.Fatalf("unregistered event reached another node: %v", *atB)
+	}
+}
+
+func TestClusterBusSkipsOtherSchemaVersions(t *testing.T) {
+	broker := NewMemoryBroker()
+	newer := newNode(t, broker, "node-a", roomOpenedV2{})
+	older := newNode(t, broker, "node-b", roomOpened{})
+	atOlder := collect[roomOpened](older)
+
+	Publish(context.Background(), newer, roomOpenedV2{RoomID: 7, Code: "ABCDEF"})
+
+	if len(*atOlder) != 0 {
+		t.Fatalf("node read an event of another schema version: %v", *atOlder)
+	}
+}
+
+func TestClusterBusKeepsEnvelopeIDs(t *testing.T) {
+	broker := NewMemoryBroker()
+	a := newNode(t, broker, "node-a", roomOpened{})
+	b := newNode(t, broker, "node-b", roomOpened{})
+	var ids []string
+	Subscribe(b, func(ctx context.Context, _ roomOpened) {
+		env, _ := EnvelopeFrom(ctx)
+		ids = append(ids, env.ID)
+	})
+
+	env, err := Seal(roomOpened{RoomID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A relayed event, such as one delivered from the outbox, keeps its ID so receivers
+	// can drop duplicates.
+	Publish(WithEnvelope(context.Background(), env), a, roomOpened{RoomID: 7})
+	Publish(context.Background(), a, roomOpened{RoomID: 7})
+
+	if len(ids) != 2 || ids[0] != env.ID || ids[1] == env.ID || ids[1] == "" {
+		t.Fatalf("envelope IDs %v, want %s and then a fresh one", ids, env.ID)
+	}
+}
+
+func TestClusterBusIgnoresMalformedMessages(t *testing.T) {
+	broker := NewMemoryBroker()
+	b := newNode(t, broker, "node-b", roomOpened{})
+	got := collect[roomOpened](b)
+
+	broker.Publish(context.Background(), clusterChannel, []byte("{not json"))
+	data, _ := json.Marshal(clusterEnvelope{Node: "node-a", Envelope: Envelope{Topic: "room.opened", Version: 1, Payload: json.RawMessage(`"seven"`)}})
+	broker.Publish(context.Background(), clusterChannel, data)
+
+	if len(*got) != 0 {
+		t.Fatalf("malformed messages delivered: %v", *got)
+	}
+}
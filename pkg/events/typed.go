@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Event is a typed payload with a fixed topic and schema version. Implementations must
+// use value receivers so the zero value names its topic. A change that older consumers
+// cannot decode bumps the version.
+type Event interface {
+	EventTopic() string
+	EventVersion() int
+}
+
+// Envelope carries an event between processes with the metadata needed to decode it.
+type Envelope struct {
+	ID         string          `json:"id"`
+	Topic      string          `json:"topic"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Seal wraps the event in an envelope with a fresh ID.
+func Seal(event Event) (Envelope, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{
+		ID:         hex.EncodeToString(id),
+		Topic:      event.EventTopic(),
+		Version:    event.EventVersion(),
+		OccurredAt: time.Now().UTC(),
+		Payload:    payload,
+	}, nil
+}
+
+// Open decodes the envelope's payload into T, refusing envelopes of another topic or
+// schema version.
+func Open[T Event](env Envelope) (T, error) {
+	var event T
+	if env.Topic != event.EventTopic() || env.Version != event.EventVersion() {
+		return event, fmt.Errorf("cannot decode %s v%d as %s v%d", env.Topic, env.Version, event.EventTopic(), event.EventVersion())
+	}
+	err := json.Unmarshal(env.Payload, &event)
+	return event, err
+}
+
+// Publish sends the event on its topic.
+func Publish(ctx context.Context, bus Bus, event Event) {
+	bus.Publish(ctx, event.EventTopic(), event)
+}
+
+// Subscribe registers a handler for events of type T. Payloads of any other type
+// published on the topic are ignored.
+func Subscribe[T Event](bus Bus, handler func(ctx context.Context, event T)) {
+	var zero T
+	bus.Subscribe(zero.EventTopic(), func(ctx context.Context, payload interface{}) {
+		if event, ok := payload.(T); ok {
+			handler(ctx, event)
+		}
+	})
+}
+
+// Registry maps topics to event types so envelopes can be decoded back into the typed
+// events subscribers expect.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewRegistry creates a registry of the given event types.
+func NewRegistry(prototypes ...Event) *Registry {
+	r := &Registry{types: make(map[string]reflect.Type)}
+	r.Register(prototypes...)
+	return r
+}
+
+// Register adds event types, keyed by their topic.
+func (r *Registry) Register(prototypes ...Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, prototype := range prototypes {
+		r.types[prototype.EventTopic()] = reflect.TypeOf(prototype)
+	}
+}
+
+// Knows reports whether the event's type is the one registered for its topic.
+func (r *Registry) Knows(event Event) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	typ, ok := r.types[event.EventTopic()]
+	return ok && typ == reflect.TypeOf(event)
+}
+
+// Decode rebuilds the typed event in the envelope. Unknown topics and other schema
+// versions than the registered one are refused rather than misread.
+func (r *Registry) Decode(env Envelope) (Event, error) {
+	r.mu.RLock()
+	typ, ok := r.types[env.Topic]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown event topic %q", env.Topic)
+	}
+	value := reflect.New(typ)
+	if version := value.Elem().Interface().(Event).EventVersion(); version != env.Version {
+		return nil, fmt.Errorf("cannot decode %s v%d, this node reads v%d", env.Topic, env.Version, version)
+	}
+	if err := json.Unmarshal(env.Payload, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface().(Event), nil
+}
+
+type envelopeKey struct{}
+
+// WithEnvelope attaches the envelope an event arrived in, so handlers can use its ID to
+// drop duplicate deliveries.
+func WithEnvelope(ctx context.Context, env Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, env)
+}
+
+// EnvelopeFrom returns the envelope attached to the handler's context, if any.
+func EnvelopeFrom(ctx context.Context) (Envelope, bool) {
+	env, ok := ctx.Value(envelopeKey{}).(Envelope)
+	return env, ok
+}
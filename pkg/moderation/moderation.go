@@ -0,0 +1,64 @@
+package moderation
+
+// Match is a piece of the input a filter objects to. Start and End are rune offsets
+// into the input, End exclusive.
+type Match struct {
+	Term       string
+	Start, End int
+}
+
+// Filter finds objectionable content in normalized text.
+type Filter interface {
+	Match(text *Text) []Match
+}
+
+// Result is the verdict on a piece of input.
+type Result struct {
+	// Masked is the input with every match replaced by asterisks.
+	Masked  string
+	Matches []Match
+}
+
+// Clean reports whether no filter matched.
+func (r Result) Clean() bool {
+	return len(r.Matches) == 0
+}
+
+// Terms lists the distinct terms matched.
+func (r Result) Terms() []string {
+	seen := make(map[string]bool, len(r.Matches))
+	var terms []string
+	for _, m := range r.Matches {
+		if !seen[m.Term] {
+			seen[m.Term] = true
+			terms = append(terms, m.Term)
+		}
+	}
+	return terms
+}
+
+// Moderator runs input through a chain of filters.
+type Moderator struct {
+	filters []Filter
+}
+
+// NewModerator creates a Moderator running the filters in order.
+func NewModerator(filters ...Filter) *Moderator {
+	return &Moderator{filters: filters}
+}
+
+// Check normalizes the input and collects the matches of every filter.
+func (m *Moderator) Check(input string) Result {
+	text := Normalize(input)
+	var matches []Match
+	for _, filter := range m.filters {
+		matches = append(matches, filter.Match(text)...)
+	}
+	masked := append([]rune(nil), text.original...)
+	for _, match := range matches {
+		for i := match.Start; i < match.End; i++ {
+			masked[i] = '*'
+		}
+	}
+	return Result{Masked: string(masked), Matches: matches}
+}
@@ -0,0 +1,133 @@
+package moderation
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Text is user input together with the normalized form filters match against. Every
+// normalized rune remembers the input rune it came from, so matches can be mapped back
+// onto the input.
+type Text struct {
+	original   []rune
+	normalized []rune
+	origin     []int
+}
+
+// Token is a word of the normalized text. Start and End are rune offsets into the
+// input, End exclusive.
+type Token struct {
+	Value      string
+	Start, End int
+}
+
+// invisible are format characters used to split words without showing it: zero-width
+// spaces and joiners, bidi controls, the soft hyphen and the Arabic tatweel.
+var invisible = map[rune]bool{
+	'\u00ad': true, '\u0640': true, '\u061c': true, '\u180e': true,
+	'\u200b': true, '\u200c': true, '\u200d': true, '\u200e': true, '\u200f': true,
+	'\u202a': true, '\u202b': true, '\u202c': true, '\u202d': true, '\u202e': true,
+	'\u2060': true, '\u2061': true, '\u2062': true, '\u2063': true, '\u2064': true,
+	'\ufeff': true,
+}
+
+// lookalikes folds letters that are commonly swapped for one another onto one form:
+// the Arabic variants of Persian letters, Cyrillic and Greek letters that look Latin,
+// and the digits and symbols of leetspeak.
+var lookalikes = map[rune]rune{
+	// Arabic → Persian
+	'ي': 'ی', 'ى': 'ی', 'ئ': 'ی', 'ك': 'ک', 'ة': 'ه', 'ۀ': 'ه', 'أ': 'ا', 'إ': 'ا', 'ٱ': 'ا', 'ؤ': 'و',
+	// Cyrillic → Latin
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's',
+	// Greek → Latin
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+	// Leetspeak
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's',
+	// Persian and Arabic-Indic digits, so they read as the leetspeak above.
+	'۰': 'o', '۱': 'i', '۳': 'e', '۴': 'a', '۵': 's', '۷': 't',
+	'٠': 'o', '١': 'i', '٣': 'e', '٤': 'a', '٥': 's', '٧': 't',
+}
+
+// Normalize prepares input for matching. It decomposes compatibility forms such as
+// full-width and presentation-form letters, drops accents, diacritics and invisible
+// characters, lower-cases and folds look-alike characters.
+func Normalize(input string) *Text {
+	t := &Text{original: []rune(input)}
+	for i, r := range t.original {
+		if invisible[r] {
+			continue
+		}
+		for _, d := range norm.NFKD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) || invisible[d] {
+				continue
+			}
+			d = unicode.ToLower(d)
+			if folded, ok := lookalikes[d]; ok {
+				d = folded
+			}
+			t.normalized = append(t.normalized, d)
+			t.origin = append(t.origin, i)
+		}
+	}
+	return t
+}
+
+// String returns the normalized text.
+func (t *Text) String() string {
+	return string(t.normalized)
+}
+
+// Tokens splits the normalized text into words. Letters spelled out one at a time,
+// like "b a d" or "b.a.d", are also joined into a word of their own.
+func (t *Text) Tokens() []Token {
+	var tokens []Token
+	var spelled []Token
+	flush := func() {
+		if len(spelled) > 1 {
+			joined := Token{Start: spelled[0].Start, End: spelled[len(spelled)-1].End}
+			for _, letter := range spelled {
+				joined.Value += letter.Value
+			}
+			tokens = append(tokens, joined)
+		}
+		spelled = nil
+	}
+	for i := 0; i < len(t.normalized); {
+		if !wordRune(t.normalized[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(t.normalized) && wordRune(t.normalized[j]) {
+			j++
+		}
+		token := Token{Value: string(t.normalized[i:j]), Start: t.origin[i], End: t.origin[j-1] + 1}
+		tokens = append(tokens, token)
+		if j-i == 1 {
+			spelled = append(spelled, token)
+		} else {
+			flush()
+		}
+		i = j
+	}
+	flush()
+	return tokens
+}
+
+func wordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// collapse squeezes runs of a repeated letter, so stretched words like "baaad" still
+// match.
+func collapse(word string) string {
+	var out []rune
+	for _, r := range word {
+		if len(out) == 0 || out[len(out)-1] != r {
+			out = append(out, r)
+		}
+	}
+	return string(out)
+}
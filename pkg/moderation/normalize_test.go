@@ -0,0 +1,76 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{name: "lower-cases", input: "BaD", want: "bad"},
+		{name: "drops accents", input: "bád", want: "bad"},
+		{name: "decomposes full-width letters", input: "ｂａｄ", want: "bad"},
+		{name: "drops invisible joiners", input: "b\u200bad\u00ad", want: "bad"},
+		{name: "folds Cyrillic look-alikes", input: "сос", want: "coc"},
+		{name: "folds Arabic letters onto Persian", input: "كيك", want: "کیک"},
+		{name: "drops Persian diacritics and tatweel", input: "كـــيرِ", want: "کیر"},
+		{name: "folds leetspeak", input: "5h1t @$$", want: "shit ass"},
+		{name: "folds Persian digits like leetspeak", input: "۵h۱t", want: "shit"},
+		{name: "keeps unfolded digits", input: "room 2 and 8", want: "room 2 and 8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input).String(); got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name, input string
+		want        []Token
+	}{
+		{
+			name:  "splits on punctuation and spaces",
+			input: "hi, you!",
+			want:  []Token{{Value: "hi", Start: 0, End: 2}, {Value: "you", Start: 4, End: 7}},
+		},
+		{
+			name:  "maps offsets back past invisible characters",
+			input: "a\u200bb cd",
+			want:  []Token{{Value: "ab", Start: 0, End: 3}, {Value: "cd", Start: 4, End: 6}},
+		},
+		{
+			name:  "joins letters spelled one at a time",
+			input: "b.a.d",
+			want: []Token{
+				{Value: "b", Start: 0, End: 1}, {Value: "a", Start: 2, End: 3}, {Value: "d", Start: 4, End: 5},
+				{Value: "bad", Start: 0, End: 5},
+			},
+		},
+		{
+			name:  "joins no single letter on its own",
+			input: "a cat",
+			want:  []Token{{Value: "a", Start: 0, End: 1}, {Value: "cat", Start: 2, End: 5}},
+		},
+		{
+			name:  "ends a spelled word at a longer one",
+			input: "x y zz",
+			want: []Token{
+				{Value: "x", Start: 0, End: 1}, {Value: "y", Start: 2, End: 3}, {Value: "zz", Start: 4, End: 6},
+				{Value: "xy", Start: 0, End: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input).Tokens(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Tokens(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package moderation
+
+import "strings"
+
+// English and Persian terms blocked by default. A trailing * also matches any word
+// starting with the term. Persian terms cover common Finglish spellings too.
+var (
+	englishWords = []string{
+		"fuck*", "motherfuck*", "shit*", "bullshit*", "bitch*", "cunt*", "asshole*", "dick", "dickhead*",
+		"bastard*", "whore*", "slut*", "nigger*", "nigga*", "faggot*", "retard*", "wank*", "twat*",
+		"pussy", "cock", "cocksuck*", "jackass",
+	}
+	persianWords = []string{
+		"کیر", "کیرم", "کیری", "کص", "کسکش*", "کسخل*", "کسننت", "کسکلک", "کونی", "جنده*", "جاکش*",
+		"مادرجنده*", "حرومزاده*", "حرامزاده*", "گاییدم", "بیناموس*", "پدرسگ*", "پفیوز", "لاشی", "گوه",
+		"kir", "kiri", "koskesh*", "kosse", "kosnanat", "koskhol*", "kooni", "jende*", "jakesh*",
+		"madarjende*", "haromzade*", "haramzade*", "bisharaf*", "binamoos*", "pedarsag*",
+	}
+)
+
+// cleanPrefixes start ordinary words that happen to begin with a blocked stem, like
+// "shiitake" with "shit*" or "retardant" with "retard*". Stems never match them.
+var cleanPrefixes = []string{"shiitake", "shitake", "retardant", "retardanc", "wankel"}
+
+// DefaultWords returns the built-in English and Persian terms.
+func DefaultWords() []string {
+	return append(append([]string(nil), englishWords...), persianWords...)
+}
+
+// WordList matches whole words against a list of terms after normalization, so
+// look-alike letters, invisible joiners, stretched letters and words spelled out one
+// letter at a time are caught too.
+type WordList struct {
+	words map[string]string
+	stems map[string]string
+	clean []string
+}
+
+// NewWordList creates a WordList. A term ending in * matches every word that starts
+// with it; other terms match whole words only.
+func NewWordList(terms []string) *WordList {
+	l := &WordList{words: make(map[string]string), stems: make(map[string]string)}
+	for _, term := range terms {
+		stem := strings.HasSuffix(term, "*")
+		key := Normalize(strings.TrimSuffix(term, "*")).String()
+		key = strings.Join(strings.Fields(key), "")
+		if key == "" {
+			continue
+		}
+		if stem {
+			l.stems[key] = term
+		} else {
+			l.words[key] = term
+		}
+	}
+	for _, prefix := range cleanPrefixes {
+		l.clean = append(l.clean, Normalize(prefix).String())
+	}
+	return l
+}
+
+// Match returns the words of the text that are on the list.
+func (l *WordList) Match(text *Text) []Match {
+	var matches []Match
+	for _, token := range text.Tokens() {
+		if term, ok := l.lookup(token.Value); ok {
+			matches = append(matches, Match{Term: term, Start: token.Start, End: token.End})
+		}
+	}
+	return matches
+}
+
+// lookup finds the term matching a word. Words with repeated letters are also compared
+// with their repeats squeezed, on both sides, which keeps short words like "as" from
+// matching "ass". Clean words are matched by whole terms only, never by stems.
+func (l *WordList) lookup(word string) (string, bool) {
+	squeezed := collapse(word)
+	stretched := squeezed != word
+	if term, ok := l.words[word]; ok {
+		return term, true
+	}
+	clean := l.cleanWord(word) || l.cleanWord(squeezed)
+	if !clean {
+		for stem, term := range l.stems {
+			if strings.HasPrefix(word, stem) {
+				return term, true
+			}
+		}
+	}
+	if !stretched {
+		return "", false
+	}
+	for key, term := range l.words {
+		if collapse(key) == squeezed {
+			return term, true
+		}
+	}
+	if clean {
+		return "", false
+	}
+	for stem, term := range l.stems {
+		if strings.HasPrefix(squeezed, collapse(stem)) {
+			return term, true
+		}
+	}
+	return "", false
+}
+
+// cleanWord reports whether the word is an ordinary one that only looks like it starts
+// with a stem.
+func (l *WordList) cleanWord(word string) bool {
+	for _, prefix := range l.clean {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package moderation
+
+import "testing"
+
+func TestWordList(t *testing.T) {
+	list := NewWordList(DefaultWords())
+	tests := []struct {
+		name, input string
+		want        []string
+	}{
+		{name: "matches a whole word", input: "you dick", want: []string{"dick"}},
+		{name: "matches a stem", input: "shitty move", want: []string{"shit*"}},
+		{name: "matches leetspeak", input: "5h1t", want: []string{"shit*"}},
+		{name: "matches stretched letters", input: "shiiiit", want: []string{"shit*"}},
+		{name: "matches spelled out letters", input: "f u c k", want: []string{"fuck*"}},
+		{name: "matches look-alike letters", input: "сunt", want: []string{"cunt*"}},
+		{name: "matches Persian with Arabic letters", input: "كير", want: []string{"کیر"}},
+		{name: "matches Finglish", input: "koskesh", want: []string{"koskesh*"}},
+		{name: "passes whole terms inside longer words", input: "Dickens drank a cocktail", want: nil},
+		{name: "passes words containing a stem", input: "Scunthorpe", want: nil},
+		{name: "passes a short word squeezed onto a longer term", input: "as is", want: nil},
+		{name: "passes shiitake", input: "shiitake and shitake mushrooms", want: nil},
+		{name: "passes retardant", input: "fire retardants", want: nil},
+		{name: "passes the Wankel engine", input: "a Wankel engine", want: nil},
+		{name: "passes numbers folded by leetspeak", input: "room 101, 3 votes at 7:45", want: nil},
+		{name: "passes digits spelled one at a time", input: "5 1 7", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, match := range list.Match(Normalize(tt.input)) {
+				got = append(got, match.Term)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Match(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Match(%q) = %v, want %v", tt.input, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckMasksMatches(t *testing.T) {
+	m := NewModerator(NewWordList([]string{"bad"}))
+	// The zero-width space inside the word is masked along with it.
+	result := m.Check("so b\u200bad!")
+	if result.Clean() || result.Masked != "so ****!" {
+		t.Fatalf("masked %q, clean %v", result.Masked, result.Clean())
+	}
+}
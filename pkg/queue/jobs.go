@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Handler processes the JSON payload of a job. A returned error, or a panic, schedules
+// a retry until the job runs out of attempts and is dead-lettered.
+type Handler func(ctx context.Context, payload []byte) error
+
+// RetryPolicy bounds how often and how fast failed jobs are retried.
+type RetryPolicy struct {
+	// MaxAttempts counts the first run; a job failing that many times is dead-lettered.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy retries a job four times, waiting 1s, 2s, 4s and 8s.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: time.Minute}
+
+// Backoff returns how long to wait before the given attempt, doubling the delay after
+// each failure. The first attempt runs right away.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt <= 1 {
+		return 0
+	}
+	delay := p.InitialBackoff
+	for i := 2; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Job is a named unit of work with a serialized payload.
+type Job struct {
+	Name       string          `json:"name"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int             `json:"attempt"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	LastError  string          `json:"last_error,omitempty"`
+}
+
+// Run calls the handler, turning a panic into an error so one bad job cannot take the
+// worker down.
+func Run(ctx context.Context, handler Handler, payload []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// MemoryJobQueue runs jobs in process on a task runner such as BackgroundQueue.Enqueue.
+// Jobs are lost on restart, so it only suits development and tests.
+type MemoryJobQueue struct {
+	run    func(func()) error
+	policy RetryPolicy
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	dead     []Job
+	timers   map[*time.Timer]struct{}
+	closed   bool
+}
+
+// NewMemoryJobQueue creates an in-process job queue.
+func NewMemoryJobQueue(run func(func()) error, policy RetryPolicy) *MemoryJobQueue {
+	return &MemoryJobQueue{run: run, policy: policy, handlers: make(map[string]Handler), timers: make(map[*time.Timer]struct{})}
+}
+
+// Register sets the handler for a job name.
+func (q *MemoryJobQueue) Register(name string, handler func(ctx context.Context, payload []byte) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[name] = handler
+}
+
+// Enqueue serializes the payload and schedules the job.
+func (q *MemoryJobQueue) Enqueue(_ context.Context, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	_, ok := q.handlers[name]
+	closed := q.closed
+	q.mu.Unlock()
+	if closed {
+		return ErrStopped
+	}
+	if !ok {
+		return fmt.Errorf("no handler registered for job %q", name)
+	}
+	return q.dispatch(Job{Name: name, Payload: data, Attempt: 1, EnqueuedAt: time.Now()})
+}
+
+// DeadLetters returns the jobs that ran out of attempts.
+func (q *MemoryJobQueue) DeadLetters() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Job(nil), q.dead...)
+}
+
+// Close cancels pending retries. Jobs already handed to the runner still run.
+func (q *MemoryJobQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	for timer := range q.timers {
+		timer.Stop()
+	}
+	q.timers = nil
+}
+
+func (q *MemoryJobQueue) dispatch(job Job) error {
+	return q.run(func() {
+		q.mu.Lock()
+		handler := q.handlers[job.Name]
+		q.mu.Unlock()
+		err := Run(context.Background(), handler, job.Payload)
+		if err == nil {
+			return
+		}
+		job.LastError = err.Error()
+		q.retry(job)
+	})
+}
+
+// retry schedules the next attempt after its backoff, or dead-letters the job.
+func (q *MemoryJobQueue) retry(job Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	if job.Attempt >= q.policy.MaxAttempts {
+		q.dead = append(q.dead, job)
+		return
+	}
+	job.Attempt++
+	var timer *time.Timer
+	timer = time.AfterFunc(q.policy.Backoff(job.Attempt), func() {
+		q.mu.Lock()
+		_, pending := q.timers[timer]
+		delete(q.timers, timer)
+		q.mu.Unlock()
+		if !pending {
+			return
+		}
+		if err := q.dispatch(job); err != nil {
+			q.retry(job)
+		}
+	})
+	q.timers[timer] = struct{}{}
+}
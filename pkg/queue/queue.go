@@ -1,6 +1,19 @@
 package queue
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors returned when a queue cannot accept more work.
+var (
+	ErrStopped = errors.New("queue stopped")
+	ErrFull    = errors.New("queue full")
+)
 
 // Task is a type alias for func() – we keep it only for readability/documentation
 type Task func()
@@ -11,49 +24,178 @@ type Dispatcher interface {
 	Close()
 }
 
-// BackgroundQueue executes tasks on a dedicated goroutine.
+// Options configures a BackgroundQueue.
+type Options struct {
+	// Workers is how many tasks run at once. It defaults to one.
+	Workers int
+	// Buffer is how many tasks may wait before Enqueue reports ErrFull.
+	Buffer int
+	// OnPanic is told about tasks that panicked; the worker recovers and carries on.
+	OnPanic func(recovered interface{}, stack []byte)
+}
+
+// Metrics is a snapshot of the queue's counters. Wait is the time tasks spent queued
+// before a worker picked them up, Run the time they took to execute.
+type Metrics struct {
+	Workers   int           `json:"workers"`
+	Depth     int           `json:"depth"`
+	Capacity  int           `json:"capacity"`
+	Running   int64         `json:"running"`
+	Enqueued  uint64        `json:"enqueued"`
+	Completed uint64        `json:"completed"`
+	Panicked  uint64        `json:"panicked"`
+	Rejected  uint64        `json:"rejected"`
+	AvgWait   time.Duration `json:"avg_wait_ns" swaggertype:"integer"`
+	MaxWait   time.Duration `json:"max_wait_ns" swaggertype:"integer"`
+	AvgRun    time.Duration `json:"avg_run_ns" swaggertype:"integer"`
+	MaxRun    time.Duration `json:"max_run_ns" swaggertype:"integer"`
+}
+
+type queuedTask struct {
+	run      Task
+	queuedAt time.Time
+}
+
+// BackgroundQueue executes tasks on a pool of worker goroutines.
 type BackgroundQueue struct {
-	tasks   chan Task
-	running bool
+	options Options
+	tasks   chan queuedTask
+	workers sync.WaitGroup
+	drained chan struct{}
+
+	// mu orders Enqueue against closing the channel.
+	mu      sync.RWMutex
+	stopped bool
+
+	running   atomic.Int64
+	enqueued  atomic.Uint64
+	completed atomic.Uint64
+	panicked  atomic.Uint64
+	rejected  atomic.Uint64
+	waitTotal atomic.Int64
+	waitMax   atomic.Int64
+	runTotal  atomic.Int64
+	runMax    atomic.Int64
 }
 
-// NewBackgroundQueue creates a new queue with the given buffer size.
-func NewBackgroundQueue(buffer int) *BackgroundQueue {
+// NewBackgroundQueue starts a queue with the given workers and buffer size.
+func NewBackgroundQueue(options Options) *BackgroundQueue {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
 	q := &BackgroundQueue{
-		tasks:   make(chan Task, buffer),
-		running: true,
+		options: options,
+		tasks:   make(chan queuedTask, options.Buffer),
+		drained: make(chan struct{}),
+	}
+	q.workers.Add(options.Workers)
+	for i := 0; i < options.Workers; i++ {
+		go q.worker()
 	}
-	go q.worker()
+	go func() {
+		q.workers.Wait()
+		close(q.drained)
+	}()
 	return q
 }
 
-// Enqueue – the ONLY method you need now.
-// It satisfies BOTH your old Dispatcher AND ports.Queue (which expects func()).
+// Enqueue hands the task to the workers without blocking. It reports ErrFull when the
+// buffer is full and ErrStopped after Shutdown.
 func (q *BackgroundQueue) Enqueue(task func()) error {
-	if !q.running {
-		return errors.New("queue stopped")
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.stopped {
+		q.rejected.Add(1)
+		return ErrStopped
 	}
 	select {
-	case q.tasks <- Task(task): // explicit conversion only for clarity
+	case q.tasks <- queuedTask{run: task, queuedAt: time.Now()}:
+		q.enqueued.Add(1)
 		return nil
 	default:
-		return errors.New("queue full")
+		q.rejected.Add(1)
+		return ErrFull
+	}
+}
+
+// Shutdown stops accepting tasks and waits for the queued and running ones to finish,
+// or for ctx to end, whichever comes first.
+func (q *BackgroundQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.stopped {
+		q.stopped = true
+		close(q.tasks)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// Close stops the worker and drains pending tasks.
+// Close stops the queue and waits for pending tasks.
 func (q *BackgroundQueue) Close() {
-	if !q.running {
-		return
+	_ = q.Shutdown(context.Background())
+}
+
+// Metrics returns a snapshot of the queue's counters.
+func (q *BackgroundQueue) Metrics() Metrics {
+	m := Metrics{
+		Workers:   q.options.Workers,
+		Depth:     len(q.tasks),
+		Capacity:  cap(q.tasks),
+		Running:   q.running.Load(),
+		Enqueued:  q.enqueued.Load(),
+		Completed: q.completed.Load(),
+		Panicked:  q.panicked.Load(),
+		Rejected:  q.rejected.Load(),
+		MaxWait:   time.Duration(q.waitMax.Load()),
+		MaxRun:    time.Duration(q.runMax.Load()),
+	}
+	if m.Completed > 0 {
+		m.AvgWait = time.Duration(q.waitTotal.Load() / int64(m.Completed))
+		m.AvgRun = time.Duration(q.runTotal.Load() / int64(m.Completed))
 	}
-	q.running = false
-	close(q.tasks)
+	return m
 }
 
 func (q *BackgroundQueue) worker() {
+	defer q.workers.Done()
 	for task := range q.tasks {
-		if task != nil {
-			task()
+		if task.run != nil {
+			q.execute(task)
+		}
+	}
+}
+
+// execute runs one task, recovering from a panic so the worker survives it.
+func (q *BackgroundQueue) execute(task queuedTask) {
+	started := time.Now()
+	q.running.Add(1)
+	defer func() {
+		if r := recover(); r != nil {
+			q.panicked.Add(1)
+			if q.options.OnPanic != nil {
+				q.options.OnPanic(r, debug.Stack())
+			}
+		}
+		q.running.Add(-1)
+		q.completed.Add(1)
+		record(&q.waitTotal, &q.waitMax, started.Sub(task.queuedAt))
+		record(&q.runTotal, &q.runMax, time.Since(started))
+	}()
+	task.run()
+}
+
+func record(total, max *atomic.Int64, d time.Duration) {
+	total.Add(int64(d))
+	for {
+		current := max.Load()
+		if int64(d) <= current || max.CompareAndSwap(current, int64(d)) {
+			return
 		}
 	}
 }
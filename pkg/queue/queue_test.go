@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPanicsAreRecovered(t *testing.T) {
+	var mu sync.Mutex
+	var recovered []interface{}
+	q := NewBackgroundQueue(Options{Buffer: 2, OnPanic: func(r interface{}, stack []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(stack) == 0 {
+			t.Error("panic reported without a stack")
+		}
+		recovered = append(recovered, r)
+	}})
+
+	var ran atomic.Bool
+	if err := q.Enqueue(func() { panic("boom") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(func() { ran.Store(true) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(recovered) != 1 || recovered[0] != "boom" {
+		t.Fatalf("OnPanic saw %v, want one boom", recovered)
+	}
+	if !ran.Load() {
+		t.Fatal("the worker stopped after a panic")
+	}
+	if m := q.Metrics(); m.Panicked != 1 || m.Completed != 2 {
+		t.Fatalf("metrics %+v, want one panic out of two tasks", m)
+	}
+}
+
+func TestShutdownDrainsQueuedTasks(t *testing.T) {
+	q := NewBackgroundQueue(Options{Workers: 2, Buffer: 10})
+	var done atomic.Int32
+	for i := 0; i < 10; i++ {
+		if err := q.Enqueue(func() {
+			time.Sleep(time.Millisecond)
+			done.Add(1)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if n := done.Load(); n != 10 {
+		t.Fatalf("%d of 10 tasks ran before shutdown returned", n)
+	}
+	if err := q.Enqueue(func() {}); !errors.Is(err, ErrStopped) {
+		t.Fatalf("enqueue after shutdown: %v, want ErrStopped", err)
+	}
+}
+
+func TestShutdownGivesUpAtTheDeadline(t *testing.T) {
+	q := NewBackgroundQueue(Options{Buffer: 1})
+	release := make(chan struct{})
+	if err := q.Enqueue(func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown with a stuck task: %v, want the deadline", err)
+	}
+	close(release)
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second shutdown: %v", err)
+	}
+}
+
+func TestEnqueueReportsAFullBuffer(t *testing.T) {
+	q := NewBackgroundQueue(Options{Buffer: 1})
+	defer q.Close()
+	started, release := make(chan struct{}), make(chan struct{})
+	if err := q.Enqueue(func() { close(started); <-release }); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	defer close(release)
+
+	if err := q.Enqueue(func() {}); err != nil {
+		t.Fatalf("buffered enqueue: %v", err)
+	}
+	if err := q.Enqueue(func() {}); !errors.Is(err, ErrFull) {
+		t.Fatalf("enqueue past the buffer: %v, want ErrFull", err)
+	}
+	if m := q.Metrics(); m.Rejected != 1 || m.Depth != 1 {
+		t.Fatalf("metrics %+v, want one rejected and one waiting", m)
+	}
+}
+
+// Enqueue racing Shutdown must never send on the closed channel, and every task it
+// accepted must still run.
+func TestEnqueueDuringShutdown(t *testing.T) {
+	q := NewBackgroundQueue(Options{Workers: 4, Buffer: 64})
+	var accepted, ran atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				err := q.Enqueue(func() { ran.Add(1) })
+				switch {
+				case err == nil:
+					accepted.Add(1)
+				case errors.Is(err, ErrStopped), errors.Is(err, ErrFull):
+				default:
+					t.Errorf("enqueue: %v", err)
+				}
+			}
+		}()
+	}
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	wg.Wait()
+	if ran.Load() != accepted.Load() {
+		t.Fatalf("ran %d of %d accepted tasks", ran.Load(), accepted.Load())
+	}
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ticker runs a job at a fixed interval on its own goroutine.
+type Ticker struct {
+	interval time.Duration
+	job      func(now time.Time)
+	once     sync.Once
+	started  atomic.Bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewTicker creates a ticker for the job; call Start to begin running it.
+func NewTicker(interval time.Duration, job func(now time.Time)) *Ticker {
+	return &Ticker{interval: interval, job: job, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Start launches the ticking goroutine.
+func (t *Ticker) Start() {
+	if t.started.CompareAndSwap(false, true) {
+		go t.run()
+	}
+}
+
+// Stop halts the ticker and waits for a running job to return.
+func (t *Ticker) Stop() {
+	t.once.Do(func() { close(t.stop) })
+	if t.started.Load() {
+		<-t.done
+	}
+}
+
+func (t *Ticker) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-ticker.C:
+			t.job(now)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "strconv"
+
 // Ptr returns a pointer to the provided value.
 func Ptr[T any](v T) *T {
 	return &v
@@ -13,3 +15,8 @@ func Default[T comparable](value T, fallback T) T {
 	}
 	return value
 }
+
+// Key formats an ID for use as a map, cache or hash key.
+func Key(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
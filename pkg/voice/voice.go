@@ -1,62 +1,128 @@
 package voice
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
-// Stream represents an active voice session.
-type Stream struct {
-	ID       string
-	RoomID   string
-	Speakers []string
+// Grant is what a member may do in a room's voice channel: the channels they are heard
+// on and the channels they listen to. A listener hears a speaker when they share a
+// channel.
+type Grant struct {
+	Speaks []string
+	Hears  []string
+	// Override keeps the member audible while someone else holds the floor.
+	Override bool
 }
 
-// Router manages voice streams per room.
+// Turn gives one member the floor of a channel until Ends. Meanwhile the other speakers
+// on that channel are muted, except members whose grant has Override.
+type Turn struct {
+	Channel string
+	Holder  string
+	Ends    time.Time
+}
+
+// Rules are the grants and turns in force in a room.
+type Rules struct {
+	Grants map[string]Grant
+	Turns  []Turn
+}
+
+// Router decides who hears whom in each room. Rooms without rules are open: everyone
+// hears everyone. In a room with rules, members without a grant hear nothing and are
+// heard by nobody.
 type Router struct {
-	mu      sync.RWMutex
-	streams map[string]Stream
+	mu    sync.RWMutex
+	rooms map[string]Rules
+	now   func() time.Time
 }
 
 // NewRouter creates a Router instance.
 func NewRouter() *Router {
-	return &Router{streams: make(map[string]Stream)}
+	return &Router{rooms: make(map[string]Rules), now: time.Now}
 }
 
-// Start registers a stream for a room.
-func (r *Router) Start(stream Stream) {
+// Route replaces the rules of a room.
+func (r *Router) Route(roomID string, rules Rules) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.streams[stream.RoomID] = stream
+	r.rooms[roomID] = rules
 }
 
-// AddSpeaker adds a speaker to a stream if present.
-func (r *Router) AddSpeaker(roomID, userID string) bool {
+// Stop forgets the rules of a room, opening it up again.
+func (r *Router) Stop(roomID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	stream, ok := r.streams[roomID]
+	delete(r.rooms, roomID)
+}
+
+// Rules returns the rules of a room.
+func (r *Router) Rules(roomID string) (Rules, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rules, ok := r.rooms[roomID]
+	return rules, ok
+}
+
+// CanHear reports whether the listener currently hears the speaker.
+func (r *Router) CanHear(roomID, listener, speaker string) bool {
+	if listener == speaker {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rules, ok := r.rooms[roomID]
+	if !ok {
+		return true
+	}
+	from, ok := rules.Grants[speaker]
+	if !ok {
+		return false
+	}
+	to, ok := rules.Grants[listener]
 	if !ok {
 		return false
 	}
-	for _, id := range stream.Speakers {
-		if id == userID {
-			r.streams[roomID] = stream
+	now := r.now()
+	for _, channel := range from.Speaks {
+		if contains(to.Hears, channel) && (from.Override || !rules.silenced(channel, speaker, now)) {
 			return true
 		}
 	}
-	stream.Speakers = append(stream.Speakers, userID)
-	r.streams[roomID] = stream
-	return true
+	return false
 }
 
-// Stop removes the stream for a room.
-func (r *Router) Stop(roomID string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	delete(r.streams, roomID)
-}
-
-// Stream returns the stream for a room.
-func (r *Router) Stream(roomID string) (Stream, bool) {
+// CanSpeak reports whether anyone could currently hear the member on the channel.
+func (r *Router) CanSpeak(roomID, member, channel string) bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	s, ok := r.streams[roomID]
-	return s, ok
+	rules, ok := r.rooms[roomID]
+	if !ok {
+		return true
+	}
+	grant, ok := rules.Grants[member]
+	if !ok || !contains(grant.Speaks, channel) {
+		return false
+	}
+	return grant.Override || !rules.silenced(channel, member, r.now())
+}
+
+// silenced reports whether another member holds the floor of the channel.
+func (rules Rules) silenced(channel, speaker string, now time.Time) bool {
+	for _, turn := range rules.Turns {
+		if turn.Channel == channel && turn.Holder != speaker && now.Before(turn.Ends) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
 }
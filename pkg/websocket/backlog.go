@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"sort"
+	"sync"
+)
+
+// Entry is a payload kept in the backlog with its sequence number.
+type Entry struct {
+	Seq     uint64
+	Payload []byte
+}
+
+// Backlog numbers published payloads and keeps the most recent ones per topic so that
+// reconnecting clients can replay what they missed.
+type Backlog struct {
+	mu     sync.Mutex
+	size   int
+	seq    uint64
+	topics map[string][]Entry
+}
+
+// NewBacklog keeps up to size entries per topic.
+func NewBacklog(size int) *Backlog {
+	return &Backlog{size: size, topics: make(map[string][]Entry)}
+}
+
+// Record assigns the next sequence number, encodes the payload with it and keeps the
+// result for the topic.
+func (b *Backlog) Record(topic string, encode func(seq uint64) ([]byte, error)) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	payload, err := encode(b.seq + 1)
+	if err != nil {
+		return nil, err
+	}
+	b.seq++
+	entries := append(b.topics[topic], Entry{Seq: b.seq, Payload: payload})
+	if len(entries) > b.size {
+		entries = entries[len(entries)-b.size:]
+	}
+	b.topics[topic] = entries
+	return payload, nil
+}
+
+// Seq returns the last sequence number handed out.
+func (b *Backlog) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Since returns the entries of the topics newer than seq, oldest first.
+func (b *Backlog) Since(seq uint64, topics ...string) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var entries []Entry
+	for _, topic := range topics {
+		for _, entry := range b.topics[topic] {
+			if entry.Seq > seq {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	return entries
+}
+
+// Forget drops the entries kept for a topic.
+func (b *Backlog) Forget(topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.topics, topic)
+}
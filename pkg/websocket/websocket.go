@@ -1,22 +1,40 @@
 package websocket
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
-// Client represents a connected websocket consumer.
+// Client represents a connected websocket consumer. Send is a bounded queue; a client
+// that lets it fill up is disconnected.
 type Client struct {
 	ID   string
 	Send chan []byte
 }
 
-// Hub manages websocket clients and broadcasts.
+// Metrics is a snapshot of the hub's counters.
+type Metrics struct {
+	Clients      int    `json:"clients"`
+	Topics       int    `json:"topics"`
+	Delivered    uint64 `json:"delivered"`
+	Dropped      uint64 `json:"dropped"`
+	Disconnected uint64 `json:"disconnected"`
+}
+
+// Hub manages websocket clients and their topic subscriptions.
 type Hub struct {
 	mu      sync.RWMutex
 	clients map[string]*Client
+	topics  map[string]map[string]*Client
+
+	delivered    atomic.Uint64
+	dropped      atomic.Uint64
+	disconnected atomic.Uint64
 }
 
 // NewHub creates a websocket hub.
 func NewHub() *Hub {
-	return &Hub{clients: make(map[string]*Client)}
+	return &Hub{clients: make(map[string]*Client), topics: make(map[string]map[string]*Client)}
 }
 
 // Register adds a client to the hub.
@@ -26,25 +44,135 @@ func (h *Hub) Register(c *Client) {
 	h.clients[c.ID] = c
 }
 
-// Unregister removes a client and closes its channel.
+// Unregister removes a client from the hub and every topic, and closes its channel.
 func (h *Hub) Unregister(id string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if c, ok := h.clients[id]; ok {
-		close(c.Send)
-		delete(h.clients, id)
+	h.unregister(id)
+}
+
+// Subscribe adds a registered client to a topic.
+func (h *Hub) Subscribe(clientID, topic string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c, ok := h.clients[clientID]
+	if !ok {
+		return false
+	}
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[string]*Client)
 	}
+	h.topics[topic][clientID] = c
+	return true
+}
+
+// Unsubscribe removes a client from a topic.
+func (h *Hub) Unsubscribe(clientID, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribe(clientID, topic)
+}
+
+// Subscribers lists the IDs of the clients subscribed to a topic.
+func (h *Hub) Subscribers(topic string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]string, 0, len(h.topics[topic]))
+	for id := range h.topics[topic] {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 // Broadcast sends the payload to all registered clients.
 func (h *Hub) Broadcast(payload []byte) {
+	h.mu.RLock()
+	slow := h.deliver(h.clients, payload)
+	h.mu.RUnlock()
+	h.disconnect(slow)
+}
+
+// Publish sends the payload to the clients subscribed to a topic.
+func (h *Hub) Publish(topic string, payload []byte) {
+	h.mu.RLock()
+	slow := h.deliver(h.topics[topic], payload)
+	h.mu.RUnlock()
+	h.disconnect(slow)
+}
+
+// Send delivers the payload to a single client.
+func (h *Hub) Send(clientID string, payload []byte) {
+	h.mu.RLock()
+	c, ok := h.clients[clientID]
+	var slow []string
+	if ok {
+		slow = h.deliver(map[string]*Client{clientID: c}, payload)
+	}
+	h.mu.RUnlock()
+	h.disconnect(slow)
+}
+
+// Metrics returns a snapshot of the hub's counters.
+func (h *Hub) Metrics() Metrics {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	for _, client := range h.clients {
+	return Metrics{
+		Clients:      len(h.clients),
+		Topics:       len(h.topics),
+		Delivered:    h.delivered.Load(),
+		Dropped:      h.dropped.Load(),
+		Disconnected: h.disconnected.Load(),
+	}
+}
+
+// deliver queues the payload for each client and returns those whose queue is full.
+// Callers must hold the read lock.
+func (h *Hub) deliver(clients map[string]*Client, payload []byte) []string {
+	var slow []string
+	for id, client := range clients {
 		select {
 		case client.Send <- payload:
+			h.delivered.Add(1)
 		default:
-			// drop message when client is busy
+			h.dropped.Add(1)
+			slow = append(slow, id)
+		}
+	}
+	return slow
+}
+
+// disconnect drops slow consumers so they cannot hold back everyone else.
+func (h *Hub) disconnect(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := h.clients[id]; ok {
+			h.unregister(id)
+			h.disconnected.Add(1)
+		}
+	}
+}
+
+func (h *Hub) unregister(id string) {
+	c, ok := h.clients[id]
+	if !ok {
+		return
+	}
+	for topic := range h.topics {
+		h.unsubscribe(id, topic)
+	}
+	close(c.Send)
+	delete(h.clients, id)
+}
+
+func (h *Hub) unsubscribe(clientID, topic string) {
+	if members, ok := h.topics[topic]; ok {
+		delete(members, clientID)
+		if len(members) == 0 {
+			delete(h.topics, topic)
 		}
 	}
 }
@@ -0,0 +1,72 @@
+package window
+
+import (
+	"sync"
+	"time"
+)
+
+// Counter counts events per key within a sliding window. Keys that have been quiet for
+// a whole window are forgotten, at most one window after they went quiet.
+type Counter struct {
+	mu    sync.Mutex
+	span  time.Duration
+	times map[string][]time.Time
+	// swept is when quiet keys were last forgotten.
+	swept time.Time
+}
+
+// NewCounter counts the events of the last span.
+func NewCounter(span time.Duration) *Counter {
+	return &Counter{span: span, times: make(map[string][]time.Time)}
+}
+
+// Add records an event for the key at now and returns how many the key has in the
+// window.
+func (c *Counter) Add(key string, now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	recent := append(c.recent(key, now), now)
+	c.times[key] = recent
+	return len(recent)
+}
+
+// Allow records an event for the key at now unless the key already has limit events in
+// the window, and reports whether it did.
+func (c *Counter) Allow(key string, now time.Time, limit int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	recent := c.recent(key, now)
+	if len(recent) >= limit {
+		c.times[key] = recent
+		return false
+	}
+	c.times[key] = append(recent, now)
+	return true
+}
+
+// Reset forgets the events of the key.
+func (c *Counter) Reset(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.times, key)
+}
+
+// recent returns the key's events still in the window. Once a window, it also forgets
+// the keys that went quiet, so sweeping costs little per event. The caller holds the
+// lock.
+func (c *Counter) recent(key string, now time.Time) []time.Time {
+	cutoff := now.Add(-c.span)
+	if !c.swept.After(cutoff) {
+		for k, times := range c.times {
+			if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+				delete(c.times, k)
+			}
+		}
+		c.swept = now
+	}
+	recent := c.times[key]
+	for len(recent) > 0 && !recent[0].After(cutoff) {
+		recent = recent[1:]
+	}
+	return recent
+}
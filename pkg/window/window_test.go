@@ -0,0 +1,64 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounter(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCounter(10 * time.Second)
+
+	if n := c.Add("a", at); n != 1 {
+		t.Fatalf("first add counted %d", n)
+	}
+	if n := c.Add("a", at.Add(5*time.Second)); n != 2 {
+		t.Fatalf("second add counted %d", n)
+	}
+	if n := c.Add("a", at.Add(12*time.Second)); n != 2 {
+		t.Fatalf("counted %d once the first event left the window, want 2", n)
+	}
+	c.Reset("a")
+	if n := c.Add("a", at.Add(13*time.Second)); n != 1 {
+		t.Fatalf("counted %d after a reset", n)
+	}
+}
+
+func TestCounterAllow(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCounter(10 * time.Second)
+
+	for i := 0; i < 2; i++ {
+		if !c.Allow("a", at, 2) {
+			t.Fatalf("event %d refused under the limit", i+1)
+		}
+	}
+	if c.Allow("a", at.Add(time.Second), 2) {
+		t.Fatal("allowed an event over the limit")
+	}
+	if !c.Allow("b", at.Add(time.Second), 2) {
+		t.Fatal("another key was held to the first key's limit")
+	}
+	if !c.Allow("a", at.Add(11*time.Second), 2) {
+		t.Fatal("refused an event after the window moved on")
+	}
+}
+
+func TestCounterForgetsQuietKeys(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCounter(10 * time.Second)
+
+	c.Add("a", at)
+	c.Add("b", at.Add(time.Second))
+	if len(c.times) != 2 {
+		t.Fatalf("tracking %d keys, want 2", len(c.times))
+	}
+	c.Add("b", at.Add(5*time.Second))
+	if len(c.times) != 2 {
+		t.Fatalf("swept %d keys within a window of the last sweep", 2-len(c.times))
+	}
+	c.Add("b", at.Add(12*time.Second))
+	if _, ok := c.times["a"]; ok || len(c.times) != 1 {
+		t.Fatalf("still tracking %v once key a went quiet for a window", c.times)
+	}
+}